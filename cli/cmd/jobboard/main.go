@@ -6,22 +6,21 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/kanaya/jobboard-cli/internal/app"
 	"github.com/kanaya/jobboard-cli/internal/config"
 	"github.com/kanaya/jobboard-cli/internal/hub"
+	"github.com/kanaya/jobboard-cli/internal/notify"
 	"github.com/kanaya/jobboard-cli/internal/runner"
-	"github.com/kanaya/jobboard-cli/internal/slack"
+	"github.com/kanaya/jobboard-cli/internal/spool"
 )
 
 func main() {
 	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
 		fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to load .env: %v\n", err)
 	}
-	config, warnings, err := config.Load(os.Args[1:])
+	cfg, warnings, err := config.Load(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[jobboard] error: %v\n", err)
 		os.Exit(1)
@@ -31,16 +30,37 @@ func main() {
 		fmt.Fprintf(os.Stdout, "[jobboard] warning: %s\n", warning)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
+	ctx := context.Background()
+
+	var sp *spool.Spool
+	if cfg.Hub.Enabled() && cfg.Hub.SpoolDir != "" {
+		sp, err = spool.Open(cfg.Hub.SpoolDir)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: %v; undelivered Hub events will be dropped\n", err)
+		}
+	}
+
+	notifiers, err := notify.New(cfg.Notify)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[jobboard] error: %v\n", err)
+		os.Exit(1)
+	}
 
 	application := app.New(
-		config,
-		hub.NewClient(config.Hub, &http.Client{Timeout: config.Hub.Timeout}),
-		slack.NewNotifier(config.Slack, &http.Client{Timeout: config.Slack.Timeout}),
+		cfg,
+		hub.NewClient(cfg.Hub, &http.Client{Timeout: cfg.Hub.Timeout}),
+		notifiers,
 		runner.New(),
+		sp,
 	)
 
+	switch cfg.Mode {
+	case config.ModeFlush:
+		os.Exit(application.Flush(ctx))
+	case config.ModeAgent:
+		os.Exit(application.Agent(ctx))
+	}
+
 	exitCode := application.Run(ctx)
 	os.Exit(exitCode)
 }
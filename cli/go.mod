@@ -2,4 +2,10 @@ module github.com/kanaya/jobboard-cli
 
 go 1.25.2
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/kanaya/jobboard-smtpmail v0.0.0
+)
+
+// Hub と共有するメール送信。リポジトリ内のディレクトリを使う
+replace github.com/kanaya/jobboard-smtpmail => ../smtpmail
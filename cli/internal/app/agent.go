@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+// Agent は Hub のキューからジョブを借りて実行し続ける。
+// SIGINT / SIGTERM / SIGHUP を受け取ると新しいジョブを借りるのをやめ、
+// 実行中のジョブ（runner がシグナルを転送する）の終了を待ってから戻る。
+func (app *App) Agent(ctx context.Context) int {
+	app.flushPending()
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			fmt.Fprintf(os.Stdout, "[jobboard] agent stopping; waiting for running jobs to finish\n")
+		case <-ctx.Done():
+		}
+		close(stop)
+	}()
+
+	fmt.Fprintf(os.Stdout, "[jobboard] agent started with %d worker(s); polling Hub every %s\n",
+		app.config.Agent.Concurrency, app.config.Agent.PollInterval)
+
+	var wg sync.WaitGroup
+	for range app.config.Agent.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.agentWorker(stop)
+		}()
+	}
+	wg.Wait()
+	return 0
+}
+
+func (app *App) agentWorker(stop <-chan struct{}) {
+	failing := false
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		ctx := context.Background()
+		cancel := context.CancelFunc(func() {})
+		if timeout := app.config.Hub.Timeout; timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		leased, err := app.hub.Lease(ctx)
+		cancel()
+
+		if err != nil && !failing {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to lease a queued job from Hub: %v\n", err)
+		}
+		failing = err != nil
+		if leased == nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(app.config.Agent.PollInterval):
+			}
+			continue
+		}
+
+		// 停止中に借りたジョブは実行せず、リースの期限切れでキューに戻す
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		app.runLeased(leased)
+	}
+}
+
+// runLeased は借りたジョブのコマンド・タグ・ラベルで設定を差し替えて実行する。
+func (app *App) runLeased(leased *hub.LeasedJob) {
+	cfg := *app.config
+	cfg.Execution.Command = leased.Command
+	cfg.Hub.Tag = leased.Tag
+	cfg.Hub.Labels = leased.Labels
+
+	job := *app
+	job.config = &cfg
+
+	fmt.Fprintf(os.Stdout, "[jobboard] running queued job %d (attempt %d): %s\n",
+		leased.QueueID, leased.Attempt, strings.Join(leased.Command, " "))
+	exitCode := job.run(context.Background(), leased, nil)
+	fmt.Fprintf(os.Stdout, "[jobboard] queued job %d exited with code %d\n", leased.QueueID, exitCode)
+}
@@ -4,51 +4,192 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/kanaya/jobboard-cli/internal/config"
 	"github.com/kanaya/jobboard-cli/internal/hub"
+	"github.com/kanaya/jobboard-cli/internal/logship"
+	"github.com/kanaya/jobboard-cli/internal/metrics"
+	"github.com/kanaya/jobboard-cli/internal/notify"
+	"github.com/kanaya/jobboard-cli/internal/pathglob"
+	"github.com/kanaya/jobboard-cli/internal/progress"
 	"github.com/kanaya/jobboard-cli/internal/runner"
-	"github.com/kanaya/jobboard-cli/internal/slack"
+	"github.com/kanaya/jobboard-cli/internal/spool"
 )
 
 const (
 	statusCompleted = "completed"
 	statusFailed    = "failed"
+	statusTimedOut  = "timed_out"
+	statusCancelled = "cancelled"
+
+	// timeout(1) と同じく、時間切れで止めた場合は 124 で終了する
+	exitCodeTimedOut = 124
 )
 
 type App struct {
-	config *config.Config
-	hub    *hub.Client
-	slack  *slack.Notifier
-	runner *runner.Runner
+	config    *config.Config
+	hub       *hub.Client
+	notifiers *notify.Set
+	runner    *runner.Runner
+	spool     *spool.Spool
 }
 
-func New(config *config.Config, hub *hub.Client, slack *slack.Notifier, runner *runner.Runner) *App {
+// spool は nil でもよい。その場合 Hub に届かなかったイベントは破棄する。
+func New(config *config.Config, hub *hub.Client, notifiers *notify.Set, runner *runner.Runner, spool *spool.Spool) *App {
 	return &App{
-		config: config,
-		hub:    hub,
-		slack:  slack,
-		runner: runner,
+		config:    config,
+		hub:       hub,
+		notifiers: notifiers,
+		runner:    runner,
+		spool:     spool,
+	}
+}
+
+// Flush はスプールに残っている Hub へのイベントを再送する。
+func (app *App) Flush(ctx context.Context) int {
+	if app.spool == nil {
+		fmt.Fprintf(os.Stderr, "[jobboard] error: spool directory is not configured\n")
+		return 1
+	}
+
+	delivered, err := app.spool.Flush(ctx, app.hub)
+	fmt.Fprintf(os.Stdout, "[jobboard] delivered %d spooled events to Hub\n", delivered)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[jobboard] error: failed to flush spool: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// flushPending は前回までの実行で残ったイベントを、新しいジョブより先に送る。
+func (app *App) flushPending() {
+	if app.spool == nil {
+		return
+	}
+
+	ctx := context.Background()
+	if timeout := app.config.Hub.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	delivered, err := app.spool.Flush(ctx, app.hub)
+	if delivered > 0 {
+		fmt.Fprintf(os.Stdout, "[jobboard] delivered %d spooled events to Hub\n", delivered)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to flush spooled events: %v\n", err)
+	}
+}
+
+// Run はコマンドを 1 回実行する（flush / agent 以外のモード）。受け取ったシグナルを子プロセスへ転送し、
+// 子プロセスを起動する前に届いていれば起動せず、cancelled として報告する。
+func (app *App) Run(ctx context.Context) int {
+	// SIGINT / SIGTERM / SIGHUP は終了するまで受け取り続ける。子プロセスの実行中は runner がプロセスグループへ転送し、
+	// それ以外のときに届いた場合も Hub への finish を済ませてから終了する
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, runner.ForwardedSignals()...)
+	defer signal.Stop(signals)
+
+	if app.config.Hub.Enabled() {
+		app.flushPending()
 	}
+	return app.run(ctx, nil, signals)
 }
 
-func (app *App) Run(ctx context.Context) (exitCode int) {
+// run は 1 つのコマンドを実行して結果を報告する。leased が nil でなければ、
+// キューから借りたジョブとして queue_id を付けて start し、指定の作業ディレクトリで実行する。
+// signals が nil なら、runner が実行中だけシグナルを受け取る。
+func (app *App) run(ctx context.Context, leased *hub.LeasedJob, signals <-chan os.Signal) (exitCode int) {
 	loc := app.config.Time.Location
 	if loc == nil {
 		loc = time.Local
 	}
 
+	workingDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to resolve working directory: %v\n", err)
+	}
+	var queueID int64
+	if leased != nil {
+		queueID = leased.QueueID
+		if leased.WorkingDir != "" {
+			workingDir = leased.WorkingDir
+		}
+	}
+
 	startedRaw := time.Now()
 	startedAt := startedRaw.In(loc)
 	var (
 		hubStarted bool
+		reporter   = newReporter(app.hub, app.spool, app.config.Hub.Timeout)
+		shipper    *logship.Shipper
+		collector  *metrics.Collector
+		watcher    *metrics.Watcher
 		result     *runner.Result
 		status     = statusCompleted
 		errorText  string
+		signalName string
+		// cancelledBy は Hub から取り消しを要求したユーザー。要求がなければ nil
+		cancelledBy atomic.Pointer[string]
 	)
 
+	notifyPayload := notify.Payload{
+		Command:   strings.Join(app.config.Execution.Command, " "),
+		Tag:       app.config.Hub.Tag,
+		Labels:    app.config.Hub.Labels,
+		StartedAt: startedAt,
+	}
+	rules := notify.NewRules(app.config.Notify.Rules, loc, func(ctx context.Context, tag string) (string, bool, error) {
+		if timeout := app.config.Hub.Timeout; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		previous, err := app.hub.PreviousRun(ctx, tag, reporter.jobID)
+		if err != nil || previous == nil {
+			return "", false, err
+		}
+		return previous.Status, true, nil
+	})
+
+	if app.config.Hub.Enabled() {
+		hubStarted = reporter.start(hub.StartParams{
+			Tag:        app.config.Hub.Tag,
+			Labels:     app.config.Hub.Labels,
+			StartedAt:  startedAt,
+			Command:    app.config.Execution.Command,
+			WorkingDir: workingDir,
+			QueueID:    queueID,
+		})
+	}
+
+	// 借りたジョブは Hub が start を受け付けた場合だけ実行する。リースを失った、スロットが埋まっている、
+	// Hub に届かないといった場合は何も報告せずに戻り、リースの期限切れでキューに戻す
+	if leased != nil && !hubStarted {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: Hub did not accept the start of queued job %d; not running it\n", leased.QueueID)
+		return 1
+	}
+
+	// 開始時のメッセージを更新できる通知先（Slack の bot など）にだけ、ここで投稿する。
+	// 通知の条件があれば結果が出るまで通知するか決まらないため、終了時にだけ送る
+	running := notifyPayload
+	running.Status = notify.StatusRunning
+	session := app.notifiers.Session()
+	if !rules.Enabled() {
+		var errs []error
+		session, errs = app.notifiers.Start(context.Background(), running)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: %v\n", err)
+		}
+	}
+
 	defer func() {
 		finishedRaw := time.Now()
 		finishedAt := finishedRaw.In(loc)
@@ -60,56 +201,177 @@ func (app *App) Run(ctx context.Context) (exitCode int) {
 			hubErrorText = &trimmedError
 		}
 
-		if hubStarted {
-			finishCtx := context.Background()
-			if timeout := app.config.Hub.Timeout; timeout > 0 {
-				var cancel context.CancelFunc
-				finishCtx, cancel = context.WithTimeout(finishCtx, timeout)
-				defer cancel()
-			}
-			if err := app.hub.Finish(finishCtx, status, finishedAt, duration, hubErrorText); err != nil {
-				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to notify Hub finish: %v\n", err)
+		if shipper != nil {
+			if err := shipper.Close(context.Background()); err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to flush logs to Hub: %v\n", err)
 			}
 		}
 
-		if app.config.Slack.Enabled() {
-			slackCtx := context.Background()
-			if timeout := app.config.Slack.Timeout; timeout > 0 {
-				var cancel context.CancelFunc
-				slackCtx, cancel = context.WithTimeout(slackCtx, timeout)
-				defer cancel()
+		if watcher != nil {
+			watcher.Close()
+		}
+		if collector != nil {
+			if err := collector.Close(context.Background()); err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to flush metrics to Hub: %v\n", err)
 			}
+		}
 
-			payload := slack.Payload{
-				Command:    strings.Join(app.config.Execution.Command, " "),
-				Tag:        app.config.Hub.Tag,
-				StartedAt:  startedAt,
+		// 子プロセスが書き終えたファイルを finish より前に送り、完了時点で揃っているようにする
+		if hubStarted && reporter.jobID != 0 && len(app.config.Hub.Artifacts) > 0 {
+			app.uploadArtifacts(reporter.jobID, workingDir)
+		}
+
+		if hubStarted {
+			reporter.finish(hub.FinishParams{
+				Status:     status,
 				FinishedAt: finishedAt,
 				Duration:   duration,
-				Status:     status,
 				ExitCode:   exitCode,
-				Error:      trimmedError,
-			}
+				Signal:     signalName,
+				ErrorText:  hubErrorText,
+				Resources:  resourcesFromUsage(result),
+			})
+		}
+
+		if app.notifiers.Len() > 0 {
+			payload := notifyPayload
+			payload.FinishedAt = finishedAt
+			payload.Duration = duration
+			payload.Status = status
+			payload.ExitCode = exitCode
+			payload.Error = trimmedError
 
-			if err := app.slack.Notify(slackCtx, payload); err != nil {
-				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to send Slack notification: %v\n", err)
+			// 条件は通知先を呼ぶ前に判定する
+			notifyNow, reason, err := rules.Evaluate(context.Background(), payload)
+			if err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: %v\n", err)
+			}
+			if !notifyNow {
+				fmt.Fprintf(os.Stdout, "[jobboard] skipping notifications: %s\n", reason)
+			} else {
+				// 通知先ごとにタイムアウトを設定し、失敗しても他の通知先には影響しない
+				for _, err := range session.Notify(context.Background(), payload) {
+					fmt.Fprintf(os.Stdout, "[jobboard] warning: %v\n", err)
+				}
 			}
 		}
 
-		if status == statusFailed && exitCode == 0 {
+		if status != statusCompleted && exitCode == 0 {
 			exitCode = 1
 		}
 	}()
 
-	if app.config.Hub.Enabled() {
-		if err := app.hub.Start(ctx, startedAt); err != nil {
-			fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to notify Hub start: %v\n", err)
+	if signals != nil {
+		select {
+		case sig := <-signals:
+			status = statusCancelled
+			signalName = runner.SignalName(sig)
+			if s, ok := sig.(syscall.Signal); ok {
+				exitCode = 128 + int(s)
+			}
+			errorText = fmt.Sprintf("cancelled by %s before the command started", signalName)
+			fmt.Fprintf(os.Stdout, "[jobboard] received %s; not starting the command\n", signalName)
+			return exitCode
+		default:
+		}
+	}
+
+	runOpts := []runner.Option{
+		runner.WithTimeout(app.config.Execution.Timeout, app.config.Execution.KillGrace),
+		runner.WithSignals(signals),
+		runner.WithShutdownWindow(app.config.Execution.ShutdownWindow),
+		runner.WithUsageSampling(app.config.Execution.UsageInterval),
+	}
+	if leased != nil {
+		// agent は並行して複数のジョブを動かし、Ctrl+C も自分で受け取るので、端末はジョブに渡さない
+		runOpts = append(runOpts, runner.WithDir(leased.WorkingDir), runner.WithoutTerminal())
+	}
+	// start をスプールに回した場合は job_id が分からないため、ログは送らない
+	if hubStarted && reporter.jobID != 0 && app.config.Hub.StreamLogs {
+		shipper = logship.New(app.hub, reporter.jobID, app.config.Hub.LogFlushInterval, app.config.Hub.Timeout)
+		shipper.Start()
+		runOpts = append(runOpts, runner.WithOutput(
+			shipper.Writer(logship.StreamStdout),
+			shipper.Writer(logship.StreamStderr),
+		))
+	}
+
+	if hubStarted && reporter.jobID != 0 && app.config.Hub.MetricsInterval > 0 {
+		collector = metrics.New(app.hub, reporter.jobID, app.config.Hub.MetricsInterval, app.config.Hub.Timeout)
+		collector.Start()
+		if url, err := collector.Listen(); err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to open local metrics endpoint: %v\n", err)
 		} else {
-			hubStarted = true
+			runOpts = append(runOpts, runner.WithEnv(metrics.EnvURL+"="+url))
+		}
+
+		if sources := metricSources(app.config.Hub); len(sources) > 0 {
+			watcher = metrics.NewWatcher(collector, sources)
+			watcher.Start()
+		}
+	} else if hubStarted && len(metricSources(app.config.Hub)) > 0 {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: metrics are disabled for this run; ignoring --watch-metrics / --watch-csv\n")
+	}
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	if hubStarted {
+		stopHeartbeat := app.startHeartbeat(reporter, func(requestedBy string) {
+			cancelledBy.Store(&requestedBy)
+			fmt.Fprintf(os.Stdout, "[jobboard] cancellation requested from Hub by %s; stopping the job\n", requestedBy)
+			cancelRun()
+		})
+		defer stopHeartbeat()
+	}
+
+	// 進捗は Hub へ送るほか、実行中の通知メッセージにも表示する
+	reportProgress := hubStarted && reporter.jobID != 0 && app.config.Hub.ProgressInterval > 0
+	var tracker *progress.Tracker
+	if reportProgress || session.Live() {
+		tracker = progress.NewTracker()
+		// stdout を読むと子プロセスから端末が見えなくなるので、指定されたときだけにする
+		if app.config.Hub.ProgressStdout {
+			runOpts = append(runOpts, runner.WithOutput(tracker.MarkerWriter(), nil))
+		}
+
+		if runner.ExtraFilesSupported() {
+			progressR, progressW, err := os.Pipe()
+			if err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to open progress pipe: %v\n", err)
+			} else {
+				// ExtraFiles の先頭は子プロセスから fd 3 として見える
+				runOpts = append(runOpts,
+					runner.WithExtraFiles(progressW),
+					runner.WithEnv(progress.EnvFD+"=3"),
+				)
+				go tracker.Read(progressR)
+				defer progressR.Close()
+			}
+		}
+
+		if reportProgress {
+			stopProgress := app.startProgress(tracker, reporter.jobID)
+			defer stopProgress()
 		}
 	}
 
-	res, runErr := app.runner.Run(ctx, app.config.Execution.Command)
+	if session.Live() {
+		stopWatch := session.Watch(func() notify.Payload {
+			payload := running
+			payload.Duration = time.Since(startedRaw)
+			if latest := tracker.Latest(); latest.Version > 0 {
+				update := latest.Update
+				payload.Progress = &update
+			}
+			return payload
+		}, func(err error) {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: %v\n", err)
+		})
+		defer stopWatch()
+	}
+
+	res, runErr := app.runner.Run(runCtx, app.config.Execution.Command, runOpts...)
 	if runErr != nil && res == nil {
 		fmt.Fprintf(os.Stderr, "[jobboard] error: failed to execute command: %v\n", runErr)
 		res = &runner.Result{ExitCode: 1, Error: runErr}
@@ -120,7 +382,35 @@ func (app *App) Run(ctx context.Context) (exitCode int) {
 	result = res
 
 	exitCode = result.ExitCode
-	if result.ExitCode != 0 || result.Error != nil || ctx.Err() != nil {
+	switch {
+	case result.TimedOut:
+		status = statusTimedOut
+		exitCode = exitCodeTimedOut
+		errorText = withStderr(result.Error.Error(), result.Stderr)
+		return exitCode
+	case result.Cancelled:
+		status = statusCancelled
+		if sig, ok := result.Signal.(syscall.Signal); ok {
+			// シェルと同じく 128 + シグナル番号で終了する
+			exitCode = 128 + int(sig)
+			signalName = runner.SignalName(sig)
+		}
+		errorText = "cancelled"
+		if result.Error != nil {
+			errorText = result.Error.Error()
+		}
+		if requestedBy := cancelledBy.Load(); requestedBy != nil && result.Signal == nil {
+			// SIGTERM で終了した子プロセスの終了コードは -1 になるため、シェルと同じ値に揃える
+			if exitCode < 0 {
+				exitCode = 128 + int(syscall.SIGTERM)
+			}
+			errorText = fmt.Sprintf("cancelled from Hub by %s", *requestedBy)
+		}
+		errorText = withStderr(errorText, result.Stderr)
+		return exitCode
+	}
+
+	if result.ExitCode != 0 || result.Error != nil {
 		status = statusFailed
 		switch {
 		case result.Error != nil:
@@ -130,13 +420,205 @@ func (app *App) Run(ctx context.Context) (exitCode int) {
 		}
 	}
 
-	if ctx.Err() != nil {
-		if errorText == "" {
-			errorText = fmt.Sprintf("terminated by signal: %v", ctx.Err())
-		}
-	} else if status == statusFailed && errorText == "" {
+	if status == statusFailed && errorText == "" {
 		errorText = fmt.Sprintf("Exit code: %d", result.ExitCode)
 	}
 
 	return exitCode
 }
+
+func resourcesFromUsage(result *runner.Result) *hub.Resources {
+	if result == nil || result.Usage == nil {
+		return nil
+	}
+	usage := result.Usage
+	return &hub.Resources{
+		CPUUserSeconds:   usage.UserCPU.Seconds(),
+		CPUSystemSeconds: usage.SystemCPU.Seconds(),
+		MaxRSSBytes:      usage.MaxRSSBytes,
+		IOReadBytes:      usage.ReadBytes,
+		IOWriteBytes:     usage.WrittenBytes,
+	}
+}
+
+func metricSources(cfg config.HubConfig) []metrics.Source {
+	var sources []metrics.Source
+	for _, pattern := range cfg.WatchMetrics {
+		sources = append(sources, metrics.Source{Pattern: pattern, Format: metrics.SourceTFEvents})
+	}
+	for _, pattern := range cfg.WatchCSV {
+		sources = append(sources, metrics.Source{Pattern: pattern, Format: metrics.SourceCSV})
+	}
+	return sources
+}
+
+// uploadArtifacts は --artifact の glob に一致したファイルを Hub に送る。
+// 相対パスの glob と名前はジョブの作業ディレクトリを基準にし、外側のファイルはファイル名だけを使う。
+// --artifact-timeout を過ぎたら残りのファイルは送らず、finish に進む。
+func (app *App) uploadArtifacts(jobID int64, workingDir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.Hub.ArtifactTimeout)
+	defer cancel()
+
+	seen := map[string]bool{}
+	for _, pattern := range app.config.Hub.Artifacts {
+		glob := pattern
+		if workingDir != "" && !filepath.IsAbs(glob) {
+			glob = filepath.Join(workingDir, glob)
+		}
+		paths, err := pathglob.Expand(glob)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: invalid artifact pattern %q: %v\n", pattern, err)
+			continue
+		}
+		if len(paths) == 0 {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: no files matched artifact pattern %q\n", pattern)
+			continue
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			name := artifactName(workingDir, path)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if ctx.Err() != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: artifact upload timed out after %s; skipping %s\n", app.config.Hub.ArtifactTimeout, name)
+				continue
+			}
+			artifact, err := app.hub.UploadArtifact(ctx, jobID, name, path)
+			if err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to upload artifact %s: %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "[jobboard] uploaded artifact %s (%d bytes, sha256 %s)\n", artifact.Name, artifact.SizeBytes, artifact.SHA256)
+		}
+	}
+}
+
+func artifactName(workingDir, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	rel, err := filepath.Rel(workingDir, abs)
+	if err != nil || workingDir == "" || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func withStderr(message, stderr string) string {
+	if strings.TrimSpace(stderr) == "" {
+		return message
+	}
+	return message + "\n\n" + stderr
+}
+
+// startHeartbeat は子プロセスの実行中、一定間隔で Hub にハートビートを送る。
+// 応答で取り消しが要求されていれば、要求したユーザーを渡して onCancel を一度だけ呼ぶ。
+// 返り値の関数を呼ぶと送信を止め、ループの終了を待つ。
+func (app *App) startHeartbeat(reporter *reporter, onCancel func(requestedBy string)) func() {
+	interval := app.config.Hub.HeartbeatInterval
+	if interval <= 0 {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		failing := false
+		cancelled := false
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			result, err := reporter.heartbeat()
+			if err != nil && !failing {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to send heartbeat to Hub: %v\n", err)
+			}
+			failing = err != nil
+			if result != nil && result.CancelRequested && !cancelled {
+				cancelled = true
+				onCancel(result.CancelRequestedBy)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// startProgress は子プロセスが報告した最新の進捗を、変化があったときだけ一定間隔で Hub に送る。
+// 返り値の関数を呼ぶと、最後の進捗を送ってからループを止める。
+func (app *App) startProgress(tracker *progress.Tracker, jobID int64) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(app.config.Hub.ProgressInterval)
+		defer ticker.Stop()
+
+		var (
+			sent    int64
+			failing bool
+		)
+		send := func() {
+			latest := tracker.Latest()
+			if latest.Version == sent {
+				return
+			}
+
+			ctx := context.Background()
+			var cancel context.CancelFunc = func() {}
+			if timeout := app.config.Hub.Timeout; timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+			}
+			err := app.hub.ReportProgress(ctx, hub.ProgressParams{
+				JobID:      jobID,
+				Percent:    latest.Percent,
+				Step:       latest.Step,
+				Total:      latest.Total,
+				Phase:      latest.Phase,
+				ReportedAt: latest.ReportedAt,
+			})
+			cancel()
+
+			if err != nil && !failing {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to send progress to Hub: %v\n", err)
+			}
+			failing = err != nil
+			if err == nil {
+				sent = latest.Version
+			}
+		}
+
+		for {
+			select {
+			case <-stop:
+				send()
+				return
+			case <-ticker.C:
+				send()
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
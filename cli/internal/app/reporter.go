@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+	"github.com/kanaya/jobboard-cli/internal/spool"
+)
+
+// reporter は start / heartbeat / finish を Hub へ届ける。
+// Hub に到達できなければイベントをスプールに書き、以降の同じ実行のイベントも
+// 順序を保つためスプール経由で送る。
+type reporter struct {
+	hub     *hub.Client
+	spool   *spool.Spool
+	timeout time.Duration
+
+	runID      string
+	jobID      int64
+	active     bool
+	spooled    bool
+	heartbeats int
+}
+
+func newReporter(client *hub.Client, sp *spool.Spool, timeout time.Duration) *reporter {
+	return &reporter{
+		hub:     client,
+		spool:   sp,
+		timeout: timeout,
+		runID:   newRunID(),
+	}
+}
+
+func (r *reporter) key(suffix string) string {
+	return r.runID + ":" + suffix
+}
+
+func (r *reporter) context() (context.Context, context.CancelFunc) {
+	if r.timeout > 0 {
+		return context.WithTimeout(context.Background(), r.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// start は Hub にジョブ開始を伝える。スプールに回した場合も true を返す。
+// キューから借りたジョブ（QueueID あり）の start はスプールしない。Hub が受け付けたかを確かめてから実行するため。
+func (r *reporter) start(params hub.StartParams) bool {
+	params.IdempotencyKey = r.key(spool.KindStart)
+	params.RunID = r.runID
+
+	ctx, cancel := r.context()
+	jobID, err := r.hub.Start(ctx, params)
+	cancel()
+	if err == nil {
+		r.jobID = jobID
+		r.active = true
+		return true
+	}
+
+	if params.QueueID == 0 && r.trySpool(spool.Event{Kind: spool.KindStart, Start: &params}, err) {
+		r.active = true
+		return true
+	}
+	fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to notify Hub start: %v\n", err)
+	return false
+}
+
+// heartbeat は送信にもスプールにも失敗した場合にエラーを返す。警告の出し分けは呼び出し側で行う。
+// Hub に届いた場合は応答を返す。スプールに回した場合は nil。
+func (r *reporter) heartbeat() (*hub.HeartbeatResult, error) {
+	if !r.active {
+		return nil, nil
+	}
+	r.heartbeats++
+	params := hub.HeartbeatParams{
+		IdempotencyKey: r.key(fmt.Sprintf("%s:%d", spool.KindHeartbeat, r.heartbeats)),
+		JobID:          r.jobID,
+		SentAt:         time.Now(),
+	}
+
+	if r.spooled {
+		// Hub が戻っていれば、溜まっている分を送ってから通常の送信に戻る
+		if err := r.flush(); err != nil {
+			return nil, r.spool.Append(r.runID, r.jobID, spool.Event{Kind: spool.KindHeartbeat, Heartbeat: &params})
+		}
+		if !r.active {
+			return nil, nil
+		}
+		params.JobID = r.jobID
+	}
+
+	ctx, cancel := r.context()
+	result, err := r.hub.Heartbeat(ctx, params)
+	cancel()
+	if err != nil && r.trySpool(spool.Event{Kind: spool.KindHeartbeat, Heartbeat: &params}, err) {
+		return nil, nil
+	}
+	return result, err
+}
+
+func (r *reporter) finish(params hub.FinishParams) {
+	if !r.active {
+		return
+	}
+	params.IdempotencyKey = r.key(spool.KindFinish)
+	params.JobID = r.jobID
+
+	if r.spooled {
+		if err := r.spool.Append(r.runID, r.jobID, spool.Event{Kind: spool.KindFinish, Finish: &params}); err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to spool Hub finish: %v\n", err)
+			return
+		}
+		if err := r.flush(); err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: Hub is unreachable; events are kept in %s (run `jobboard flush` to retry): %v\n", r.spool.Dir(), err)
+		}
+		return
+	}
+
+	ctx, cancel := r.context()
+	err := r.hub.Finish(ctx, params)
+	cancel()
+	if err == nil || r.trySpool(spool.Event{Kind: spool.KindFinish, Finish: &params}, err) {
+		return
+	}
+	fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to notify Hub finish: %v\n", err)
+}
+
+// trySpool は再送で届く見込みのあるエラーならイベントをスプールに書く。
+func (r *reporter) trySpool(event spool.Event, err error) bool {
+	if r.spool == nil || !hub.IsRetryable(err) {
+		return false
+	}
+	if spoolErr := r.spool.Append(r.runID, r.jobID, event); spoolErr != nil {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to spool Hub %s: %v\n", event.Kind, spoolErr)
+		return false
+	}
+	if !r.spooled {
+		fmt.Fprintf(os.Stdout, "[jobboard] warning: Hub is unreachable (%v); spooling %s event to %s\n", err, event.Kind, r.spool.Dir())
+	}
+	r.spooled = true
+	return true
+}
+
+func (r *reporter) flush() error {
+	ctx, cancel := r.context()
+	defer cancel()
+
+	jobID, err := r.spool.FlushRun(ctx, r.hub, r.runID)
+	if jobID != 0 {
+		r.jobID = jobID
+	}
+	if err != nil {
+		return err
+	}
+	r.spooled = false
+	if r.jobID == 0 {
+		// start が Hub に拒否された。残りのイベントも送らない
+		r.active = false
+	}
+	return nil
+}
+
+// newRunID は実行ごとの UUIDv4 を返す。Hub の run_id と Idempotency-Key の接頭辞に使う。
+func newRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
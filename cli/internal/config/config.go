@@ -7,31 +7,133 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/kanaya/jobboard-smtpmail"
+)
+
+const (
+	ModeRun   = "run"
+	ModeFlush = "flush"
+	ModeAgent = "agent"
 )
 
 type Config struct {
+	Mode      string
 	Hub       HubConfig
-	Slack     SlackConfig
+	Notify    NotifyConfig
 	Execution ExecutionConfig
+	Agent     AgentConfig
 	Time      TimeConfig
 }
 
 type HubConfig struct {
-	URL       string
-	NodeToken string
-	Tag       string
-	Timeout   time.Duration
+	URL               string
+	NodeToken         string
+	Tag               string
+	Labels            map[string]string
+	Timeout           time.Duration
+	StreamLogs        bool
+	LogFlushInterval  time.Duration
+	HeartbeatInterval time.Duration
+	ProgressInterval  time.Duration
+	ProgressStdout    bool
+	MetricsInterval   time.Duration
+	WatchMetrics      []string
+	WatchCSV          []string
+	Artifacts         []string
+	ArtifactTimeout   time.Duration
+	SpoolDir          string
+}
+
+// NotifyConfig は通知先ごとの設定。設定された通知先すべてに送る。
+type NotifyConfig struct {
+	Slack   SlackConfig
+	Discord DiscordConfig
+	Teams   TeamsConfig
+	Webhook WebhookConfig
+	Email   EmailConfig
+	Rules   NotifyRules
+}
+
+// NotifyRules は通知するかどうかの条件。On / LongerThan / OnChange のどれかを指定すると、
+// いずれか 1 つを満たしたときだけ通知する。QuietHours の間に終わったジョブは通知しない。
+type NotifyRules struct {
+	// On は通知する終了ステータス。success / failure は展開済み
+	On         []string
+	LongerThan time.Duration
+	// OnChange は同じタグの前回の実行（Hub に問い合わせる）とステータスが違うときに通知する
+	OnChange   bool
+	QuietHours *QuietHours
+}
+
+// QuietHours は通知しない時間帯。Start > End なら日付をまたぐ。値は 0:00 からの分。
+type QuietHours struct {
+	Start int
+	End   int
 }
 
+// SlackConfig は BotToken があれば Web API（Block Kit）で、なければ Incoming Webhook で投稿する。
 type SlackConfig struct {
+	WebhookURL string
+	BotToken   string
+	Channel    string
+	// UpdateInterval は Web API で投稿した開始時のメッセージを実行中に更新する間隔
+	UpdateInterval time.Duration
+	APIURL         string
+	Timeout        time.Duration
+}
+
+type DiscordConfig struct {
+	WebhookURL string
+	Timeout    time.Duration
+}
+
+type TeamsConfig struct {
 	WebhookURL string
 	Timeout    time.Duration
 }
 
+type WebhookConfig struct {
+	URL string
+	// Template は text/template 形式の本文。空なら既定の JSON を送る
+	Template string
+	Timeout  time.Duration
+}
+
+const (
+	SMTPTLSStartTLS = smtpmail.TLSStartTLS
+	SMTPTLSImplicit = smtpmail.TLSImplicit
+	SMTPTLSNone     = smtpmail.TLSNone
+)
+
+type EmailConfig struct {
+	To       []string
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// TLS は starttls（既定）、tls（接続直後から TLS）、none（平文）のいずれか
+	TLS     string
+	Timeout time.Duration
+}
+
 type ExecutionConfig struct {
-	Command []string
+	Command        []string
+	Timeout        time.Duration
+	KillGrace      time.Duration
+	ShutdownWindow time.Duration
+	UsageInterval  time.Duration
+}
+
+type AgentConfig struct {
+	PollInterval time.Duration
+	Concurrency  int
 }
 
 type TimeConfig struct {
@@ -40,6 +142,12 @@ type TimeConfig struct {
 }
 
 func Load(args []string) (*Config, []string, error) {
+	mode := ModeRun
+	if len(args) > 0 && (args[0] == ModeFlush || args[0] == ModeAgent) {
+		mode = args[0]
+		args = args[1:]
+	}
+
 	fs := flag.NewFlagSet("jobboard", flag.ContinueOnError)
 	var parseErr bytes.Buffer
 	fs.SetOutput(&parseErr)
@@ -47,12 +155,62 @@ func Load(args []string) (*Config, []string, error) {
 	hubURL := fs.String("hub-url", envString("JOBBOARD_HUB_URL", "http://localhost:8080"), "Hub base URL")
 	nodeToken := fs.String("node-token", envString("JOBBOARD_NODE_TOKEN", ""), "Token for Hub node trigger API")
 	tag := fs.String("tag", "", "Optional tag forwarded to Hub")
+	labels := labelFlag{}
+	if err := labels.setList(envString("JOBBOARD_LABELS", "")); err != nil {
+		return nil, nil, fmt.Errorf("invalid JOBBOARD_LABELS: %w", err)
+	}
+	fs.Var(labels, "label", "Label attached to the job as key=value (repeatable)")
 	slackWebhook := fs.String("slack-webhook", envString("JOBBOARD_SLACK_WEBHOOK", ""), "Slack incoming webhook URL")
+	slackBotToken := fs.String("slack-bot-token", envString("JOBBOARD_SLACK_BOT_TOKEN", ""), "Slack bot token (xoxb-...); posts a Block Kit message at start and updates it in place instead of using the webhook")
+	slackChannel := fs.String("slack-channel", envString("JOBBOARD_SLACK_CHANNEL", ""), "Slack channel ID or name the bot posts to")
+	slackUpdateInterval := fs.Duration("slack-update-interval", envDuration("JOBBOARD_SLACK_UPDATE_INTERVAL", time.Minute), "Interval between updates of the running job's Slack message (0 disables updates)")
 	hubTimeout := fs.Duration("hub-timeout", envDuration("JOBBOARD_HUB_TIMEOUT", 60*time.Second), "Timeout for Hub API requests")
 	slackTimeout := fs.Duration("slack-timeout", envDuration("JOBBOARD_SLACK_TIMEOUT", 10*time.Second), "Timeout for Slack API requests")
+	discordWebhook := fs.String("discord-webhook", envString("JOBBOARD_DISCORD_WEBHOOK", ""), "Discord webhook URL")
+	discordTimeout := fs.Duration("discord-timeout", envDuration("JOBBOARD_DISCORD_TIMEOUT", 10*time.Second), "Timeout for Discord webhook requests")
+	teamsWebhook := fs.String("teams-webhook", envString("JOBBOARD_TEAMS_WEBHOOK", ""), "Microsoft Teams webhook URL (receives an Adaptive Card)")
+	teamsTimeout := fs.Duration("teams-timeout", envDuration("JOBBOARD_TEAMS_TIMEOUT", 10*time.Second), "Timeout for Teams webhook requests")
+	webhookURL := fs.String("webhook-url", envString("JOBBOARD_WEBHOOK_URL", ""), "Generic webhook URL that receives the job summary as JSON")
+	webhookTemplate := fs.String("webhook-template", envString("JOBBOARD_WEBHOOK_TEMPLATE", ""), "Go text/template for the generic webhook body; prefix with @ to read it from a file")
+	webhookTimeout := fs.Duration("webhook-timeout", envDuration("JOBBOARD_WEBHOOK_TIMEOUT", 10*time.Second), "Timeout for generic webhook requests")
+	emailTo := listFlag(envList("JOBBOARD_EMAIL_TO"))
+	fs.Var(&emailTo, "email-to", "Recipient of the job summary email (repeatable)")
+	emailFrom := fs.String("email-from", envString("JOBBOARD_EMAIL_FROM", ""), "Sender address of the job summary email")
+	emailTimeout := fs.Duration("email-timeout", envDuration("JOBBOARD_EMAIL_TIMEOUT", 30*time.Second), "Timeout for sending the job summary email")
+	smtpHost := fs.String("smtp-host", envString("JOBBOARD_SMTP_HOST", ""), "SMTP server host for email notifications")
+	smtpPort := fs.String("smtp-port", envString("JOBBOARD_SMTP_PORT", "587"), "SMTP server port for email notifications")
+	smtpUsername := fs.String("smtp-username", envString("JOBBOARD_SMTP_USERNAME", ""), "SMTP username; the password is read from JOBBOARD_SMTP_PASSWORD")
+	smtpTLS := fs.String("smtp-tls", envString("JOBBOARD_SMTP_TLS", SMTPTLSStartTLS), "SMTP transport security: starttls, tls or none")
+	notifyOn := listFlag(envList("JOBBOARD_NOTIFY_ON"))
+	fs.Var(&notifyOn, "notify-on", "Notify only for these outcomes: success, failure or a status (completed, failed, timed_out, cancelled); repeatable")
+	notifyLongerThan := fs.Duration("notify-if-longer-than", envDuration("JOBBOARD_NOTIFY_IF_LONGER_THAN", 0), "Notify when the job ran at least this long (0 disables)")
+	notifyOnChange := fs.Bool("notify-on-change", envBool("JOBBOARD_NOTIFY_ON_CHANGE", false), "Notify when the status differs from the previous run with the same tag (looked up from Hub)")
+	quietHours := fs.String("quiet-hours", envString("JOBBOARD_QUIET_HOURS", ""), "Suppress notifications for jobs finishing in this local time window, e.g. 22:00-07:00")
+	streamLogs := fs.Bool("stream-logs", envBool("JOBBOARD_STREAM_LOGS", true), "Stream stdout/stderr to Hub while the command runs")
+	logFlushInterval := fs.Duration("log-flush-interval", envDuration("JOBBOARD_LOG_FLUSH_INTERVAL", 2*time.Second), "Interval between log uploads to Hub")
+	spoolDir := fs.String("spool-dir", envString("JOBBOARD_SPOOL_DIR", defaultSpoolDir()), "Directory for Hub events that could not be delivered (empty disables spooling)")
+	progressInterval := fs.Duration("progress-interval", envDuration("JOBBOARD_PROGRESS_INTERVAL", 5*time.Second), "Minimum interval between progress updates sent to Hub (0 disables progress reporting)")
+	progressStdout := fs.Bool("progress-stdout", envBool("JOBBOARD_PROGRESS_STDOUT", false), "Also read \"##jobboard:progress\" lines from stdout (stdout is then a pipe, not the terminal)")
+	metricsInterval := fs.Duration("metrics-flush-interval", envDuration("JOBBOARD_METRICS_FLUSH_INTERVAL", 5*time.Second), "Interval between metric uploads to Hub (0 disables the local metrics endpoint)")
+	watchMetrics := listFlag(envList("JOBBOARD_WATCH_METRICS"))
+	fs.Var(&watchMetrics, "watch-metrics", "Glob of TensorBoard event files to tail for scalar metrics; ** matches any directories (repeatable)")
+	watchCSV := listFlag(envList("JOBBOARD_WATCH_CSV"))
+	fs.Var(&watchCSV, "watch-csv", "Glob of CSV metric logs with a header row to tail for metrics (repeatable)")
+	artifacts := listFlag(envList("JOBBOARD_ARTIFACTS"))
+	artifactTimeout := fs.Duration("artifact-timeout", envDuration("JOBBOARD_ARTIFACT_TIMEOUT", 10*time.Minute), "Time limit for uploading all artifacts; files not uploaded by then are skipped")
+	fs.Var(&artifacts, "artifact", "Glob of files to upload to Hub as job artifacts after the command exits; ** matches any directories (repeatable)")
+	heartbeatInterval := fs.Duration("heartbeat-interval", envDuration("JOBBOARD_HEARTBEAT_INTERVAL", 30*time.Second), "Interval between heartbeats sent to Hub while the command runs")
+
+	timeout := fs.Duration("timeout", envDuration("JOBBOARD_TIMEOUT", 0), "Wall-clock limit for the command (0 disables)")
+	killGrace := fs.Duration("kill-grace", envDuration("JOBBOARD_KILL_GRACE", 30*time.Second), "Time to wait after SIGTERM before sending SIGKILL")
+	usageInterval := fs.Duration("usage-sample-interval", envDuration("JOBBOARD_USAGE_SAMPLE_INTERVAL", 5*time.Second), "Interval for sampling CPU/memory/I/O of the process tree (0 uses exit rusage only)")
+	shutdownWindow := fs.Duration("shutdown-window", envDuration("JOBBOARD_SHUTDOWN_WINDOW", 10*time.Second), "Time to wait after forwarding SIGINT/SIGTERM/SIGHUP before sending SIGKILL")
+
+	pollInterval := fs.Duration("agent-poll-interval", envDuration("JOBBOARD_AGENT_POLL_INTERVAL", 5*time.Second), "Interval between requests for queued jobs while the agent is idle")
+	concurrency := fs.Int("agent-concurrency", envInt("JOBBOARD_AGENT_CONCURRENCY", 1), "Number of queued jobs the agent runs at the same time")
 
 	fs.Usage = func() {
-		fmt.Fprintf(fs.Output(), "Usage: jobboard [flags] -- <command> [args...]\n\nFlags:\n")
+		fmt.Fprintf(fs.Output(), "Usage: jobboard [flags] -- <command> [args...]\n       jobboard flush [flags]\n       jobboard agent [flags]\n\nFlags:\n")
 		fs.PrintDefaults()
 	}
 
@@ -68,25 +226,106 @@ func Load(args []string) (*Config, []string, error) {
 	}
 
 	command := fs.Args()
-	if len(command) == 0 {
+	switch {
+	case mode != ModeRun && len(command) > 0:
+		return nil, nil, fmt.Errorf("%s does not take a command", mode)
+	case mode == ModeRun && len(command) == 0:
 		return nil, nil, errors.New("execution command is required; pass it after `--`")
 	}
 
+	template, err := loadTemplate(*webhookTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *slackBotToken != "" && *slackChannel == "" {
+		return nil, nil, errors.New("slack bot token requires a channel")
+	}
+	if len(emailTo) > 0 {
+		switch {
+		case *smtpHost == "":
+			return nil, nil, errors.New("email notifications require an SMTP host")
+		case *emailFrom == "":
+			return nil, nil, errors.New("email notifications require a sender address")
+		}
+	}
+	switch *smtpTLS {
+	case SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	default:
+		return nil, nil, fmt.Errorf("invalid SMTP TLS mode %q; use starttls, tls or none", *smtpTLS)
+	}
+
+	rules, err := parseNotifyRules(notifyOn, *notifyLongerThan, *notifyOnChange, *quietHours)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	tzName, tzLocation := loadLocation()
 
 	cfg := &Config{
+		Mode: mode,
 		Hub: HubConfig{
-			URL:       *hubURL,
-			NodeToken: *nodeToken,
-			Tag:       *tag,
-			Timeout:   *hubTimeout,
+			URL:               *hubURL,
+			NodeToken:         *nodeToken,
+			Tag:               *tag,
+			Labels:            labels,
+			Timeout:           *hubTimeout,
+			StreamLogs:        *streamLogs,
+			LogFlushInterval:  *logFlushInterval,
+			HeartbeatInterval: *heartbeatInterval,
+			ProgressInterval:  *progressInterval,
+			ProgressStdout:    *progressStdout,
+			MetricsInterval:   *metricsInterval,
+			WatchMetrics:      watchMetrics,
+			WatchCSV:          watchCSV,
+			Artifacts:         artifacts,
+			ArtifactTimeout:   *artifactTimeout,
+			SpoolDir:          *spoolDir,
 		},
-		Slack: SlackConfig{
-			WebhookURL: *slackWebhook,
-			Timeout:    *slackTimeout,
+		Notify: NotifyConfig{
+			Slack: SlackConfig{
+				WebhookURL:     *slackWebhook,
+				BotToken:       *slackBotToken,
+				Channel:        *slackChannel,
+				UpdateInterval: *slackUpdateInterval,
+				APIURL:         envString("JOBBOARD_SLACK_API_URL", "https://slack.com/api"),
+				Timeout:        *slackTimeout,
+			},
+			Discord: DiscordConfig{
+				WebhookURL: *discordWebhook,
+				Timeout:    *discordTimeout,
+			},
+			Teams: TeamsConfig{
+				WebhookURL: *teamsWebhook,
+				Timeout:    *teamsTimeout,
+			},
+			Webhook: WebhookConfig{
+				URL:      *webhookURL,
+				Template: template,
+				Timeout:  *webhookTimeout,
+			},
+			Email: EmailConfig{
+				To:       emailTo,
+				From:     *emailFrom,
+				Host:     *smtpHost,
+				Port:     *smtpPort,
+				Username: *smtpUsername,
+				Password: os.Getenv("JOBBOARD_SMTP_PASSWORD"),
+				TLS:      *smtpTLS,
+				Timeout:  *emailTimeout,
+			},
+			Rules: rules,
 		},
 		Execution: ExecutionConfig{
-			Command: command,
+			Command:        command,
+			Timeout:        *timeout,
+			KillGrace:      *killGrace,
+			ShutdownWindow: *shutdownWindow,
+			UsageInterval:  *usageInterval,
+		},
+		Agent: AgentConfig{
+			PollInterval: *pollInterval,
+			Concurrency:  *concurrency,
 		},
 		Time: TimeConfig{
 			Name:     tzName,
@@ -94,9 +333,35 @@ func Load(args []string) (*Config, []string, error) {
 		},
 	}
 
+	if mode == ModeFlush {
+		if !cfg.Hub.Enabled() {
+			return nil, nil, errors.New("flush requires Hub node token")
+		}
+		return cfg, nil, nil
+	}
+
+	if mode == ModeAgent {
+		switch {
+		case !cfg.Hub.Enabled():
+			return nil, nil, errors.New("agent requires Hub node token")
+		case cfg.Agent.PollInterval <= 0:
+			return nil, nil, errors.New("agent poll interval must be positive")
+		case cfg.Agent.Concurrency < 1:
+			return nil, nil, errors.New("agent concurrency must be at least 1")
+		}
+	}
+
+	if len(cfg.Hub.Artifacts) > 0 && cfg.Hub.ArtifactTimeout <= 0 {
+		return nil, nil, errors.New("artifact timeout must be positive")
+	}
+
+	if cfg.Notify.Rules.OnChange && !cfg.Hub.Enabled() {
+		return nil, nil, errors.New("--notify-on-change requires Hub node token")
+	}
+
 	warnings := cfg.collectWarnings()
-	if !cfg.Hub.Enabled() && !cfg.Slack.Enabled() {
-		return nil, warnings, errors.New("either Slack webhook or Hub node token must be provided")
+	if !cfg.Hub.Enabled() && !cfg.Notify.Enabled() {
+		return nil, warnings, errors.New("either a notification target or Hub node token must be provided")
 	}
 
 	return cfg, warnings, nil
@@ -106,21 +371,183 @@ func (c HubConfig) Enabled() bool {
 	return c.NodeToken != ""
 }
 
+func (c NotifyConfig) Enabled() bool {
+	return c.Slack.Enabled() || c.Discord.Enabled() || c.Teams.Enabled() || c.Webhook.Enabled() || c.Email.Enabled()
+}
+
 func (c SlackConfig) Enabled() bool {
+	return c.WebhookURL != "" || c.BotToken != ""
+}
+
+func (c DiscordConfig) Enabled() bool {
+	return c.WebhookURL != ""
+}
+
+func (c TeamsConfig) Enabled() bool {
 	return c.WebhookURL != ""
 }
 
+func (c WebhookConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+func (c EmailConfig) Enabled() bool {
+	return len(c.To) > 0
+}
+
+// Enabled は条件が 1 つでも指定されているかを返す。
+func (r NotifyRules) Enabled() bool {
+	return len(r.On) > 0 || r.LongerThan > 0 || r.OnChange || r.QuietHours != nil
+}
+
+// Contains は t の時刻が通知しない時間帯に入るかを返す。
+func (q QuietHours) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return q.Start <= minute && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+func (q QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+func parseNotifyRules(on []string, longerThan time.Duration, onChange bool, quietHours string) (NotifyRules, error) {
+	rules := NotifyRules{
+		LongerThan: longerThan,
+		OnChange:   onChange,
+	}
+	if longerThan < 0 {
+		return rules, errors.New("--notify-if-longer-than must not be negative")
+	}
+
+	seen := map[string]bool{}
+	add := func(statuses ...string) {
+		for _, status := range statuses {
+			if !seen[status] {
+				seen[status] = true
+				rules.On = append(rules.On, status)
+			}
+		}
+	}
+	for _, value := range on {
+		for _, item := range strings.Split(value, ",") {
+			switch item = strings.TrimSpace(item); item {
+			case "success", "completed":
+				add("completed")
+			case "failure":
+				add("failed", "timed_out", "cancelled")
+			case "failed", "timed_out", "cancelled":
+				add(item)
+			default:
+				return rules, fmt.Errorf("invalid --notify-on value %q; use success, failure, completed, failed, timed_out or cancelled", item)
+			}
+		}
+	}
+
+	if quietHours != "" {
+		start, end, ok := strings.Cut(quietHours, "-")
+		if !ok {
+			return rules, fmt.Errorf("invalid --quiet-hours %q; use HH:MM-HH:MM", quietHours)
+		}
+		startMinute, err := parseClock(start)
+		if err != nil {
+			return rules, fmt.Errorf("invalid --quiet-hours %q: %w", quietHours, err)
+		}
+		endMinute, err := parseClock(end)
+		if err != nil {
+			return rules, fmt.Errorf("invalid --quiet-hours %q: %w", quietHours, err)
+		}
+		if startMinute == endMinute {
+			return rules, fmt.Errorf("invalid --quiet-hours %q: start and end must differ", quietHours)
+		}
+		rules.QuietHours = &QuietHours{Start: startMinute, End: endMinute}
+	}
+	return rules, nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
 func (c *Config) collectWarnings() []string {
 	var warnings []string
-	if !c.Slack.Enabled() {
-		warnings = append(warnings, "Slack webhook is not configured; skipping Slack notifications")
+	if !c.Notify.Enabled() {
+		warnings = append(warnings, "no notification target is configured; skipping notifications")
 	}
 	if !c.Hub.Enabled() {
 		warnings = append(warnings, "Hub node token is not configured; skipping Hub integration")
 	}
+	if c.Notify.Rules.OnChange && c.Mode == ModeRun && c.Hub.Tag == "" {
+		warnings = append(warnings, "--notify-on-change needs --tag to find the previous run; notifying every run")
+	}
 	return warnings
 }
 
+// labelFlag は --label key=value を繰り返し受け取る。同じキーは後から指定したものが優先される。
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for key, value := range l {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("label must be key=value: %q", value)
+	}
+	l[key] = val
+	return nil
+}
+
+func (l labelFlag) setList(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		if err := l.Set(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listFlag は繰り返し指定できる文字列のフラグ。環境変数で指定した値の後ろに追加される。
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("value must not be empty")
+	}
+	*l = append(*l, value)
+	return nil
+}
+
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func envString(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -137,6 +564,45 @@ func envDuration(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+func envBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// loadTemplate は @ で始まる値をファイルパスとして読み込む。
+func loadTemplate(value string) (string, error) {
+	path, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return value, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read webhook template: %w", err)
+	}
+	return string(data), nil
+}
+
+func defaultSpoolDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "jobboard", "spool")
+}
+
 func loadLocation() (string, *time.Location) {
 	tz := envString("TIMEZONE", "")
 	if tz == "" {
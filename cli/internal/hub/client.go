@@ -3,10 +3,17 @@ package hub
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,51 +36,436 @@ func (c *Client) Enabled() bool {
 	return c.config.Enabled()
 }
 
+// StartParams などの IdempotencyKey は Hub 側で重複排除に使われる。
+// スプールから再送する際も同じキーを使うことで、二重に適用されない。
+type StartParams struct {
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+	RunID          string            `json:"run_id,omitempty"`
+	Tag            string            `json:"tag,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	StartedAt      time.Time         `json:"started_at"`
+	Command        []string          `json:"command,omitempty"`
+	WorkingDir     string            `json:"working_dir,omitempty"`
+	QueueID        int64             `json:"queue_id,omitempty"`
+}
+
 type startRequest struct {
-	NodeToken string    `json:"node_token"`
-	Tag       string    `json:"tag,omitempty"`
-	StartedAt time.Time `json:"started_at"`
+	NodeToken  string            `json:"node_token"`
+	RunID      string            `json:"run_id,omitempty"`
+	Tag        string            `json:"tag,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	Command    []string          `json:"command,omitempty"`
+	WorkingDir string            `json:"working_dir,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	QueueID    int64             `json:"queue_id,omitempty"`
+}
+
+type startResponse struct {
+	Success bool  `json:"success"`
+	JobID   int64 `json:"job_id"`
 }
 
-func (c *Client) Start(ctx context.Context, startedAt time.Time) error {
+func (c *Client) Start(ctx context.Context, params StartParams) (int64, error) {
 	if !c.Enabled() {
-		return nil
+		return 0, nil
 	}
 
 	payload := startRequest{
-		NodeToken: c.config.NodeToken,
-		Tag:       c.config.Tag,
-		StartedAt: startedAt,
+		NodeToken:  c.config.NodeToken,
+		RunID:      params.RunID,
+		Tag:        params.Tag,
+		StartedAt:  params.StartedAt,
+		Command:    params.Command,
+		WorkingDir: params.WorkingDir,
+		Labels:     params.Labels,
+		QueueID:    params.QueueID,
+	}
+
+	var resp startResponse
+	if err := c.post(ctx, "/api/job-trigger/start", params.IdempotencyKey, payload, &resp); err != nil {
+		return 0, err
 	}
+	return resp.JobID, nil
+}
+
+type Resources struct {
+	CPUUserSeconds   float64 `json:"cpu_user_seconds"`
+	CPUSystemSeconds float64 `json:"cpu_system_seconds"`
+	MaxRSSBytes      int64   `json:"max_rss_bytes"`
+	IOReadBytes      int64   `json:"io_read_bytes"`
+	IOWriteBytes     int64   `json:"io_write_bytes"`
+}
 
-	return c.post(ctx, "/api/job-trigger/start", payload)
+type FinishParams struct {
+	IdempotencyKey string        `json:"idempotency_key,omitempty"`
+	JobID          int64         `json:"job_id,omitempty"`
+	Status         string        `json:"status"`
+	FinishedAt     time.Time     `json:"finished_at"`
+	Duration       time.Duration `json:"duration"`
+	ExitCode       int           `json:"exit_code"`
+	Signal         string        `json:"signal,omitempty"`
+	ErrorText      *string       `json:"error_text,omitempty"`
+	Resources      *Resources    `json:"resources,omitempty"`
 }
 
 type finishRequest struct {
-	NodeToken     string    `json:"node_token"`
-	Status        string    `json:"status"`
-	FinishedAt    time.Time `json:"finished_at"`
-	DurationHours float64   `json:"duration_hours"`
-	ErrorText     *string   `json:"error_text,omitempty"`
+	NodeToken     string     `json:"node_token"`
+	JobID         int64      `json:"job_id,omitempty"`
+	Status        string     `json:"status"`
+	FinishedAt    time.Time  `json:"finished_at"`
+	DurationHours float64    `json:"duration_hours"`
+	ExitCode      int        `json:"exit_code"`
+	Signal        string     `json:"signal,omitempty"`
+	ErrorText     *string    `json:"error_text,omitempty"`
+	Resources     *Resources `json:"resources,omitempty"`
 }
 
-func (c *Client) Finish(ctx context.Context, status string, finishedAt time.Time, duration time.Duration, errorText *string) error {
+func (c *Client) Finish(ctx context.Context, params FinishParams) error {
 	if !c.Enabled() {
 		return nil
 	}
 
 	payload := finishRequest{
 		NodeToken:     c.config.NodeToken,
-		Status:        status,
-		FinishedAt:    finishedAt,
-		DurationHours: duration.Hours(),
-		ErrorText:     errorText,
+		JobID:         params.JobID,
+		Status:        params.Status,
+		FinishedAt:    params.FinishedAt,
+		DurationHours: params.Duration.Hours(),
+		ExitCode:      params.ExitCode,
+		Signal:        params.Signal,
+		ErrorText:     params.ErrorText,
+		Resources:     params.Resources,
+	}
+
+	return c.post(ctx, "/api/job-trigger/finish", params.IdempotencyKey, payload, nil)
+}
+
+type HeartbeatParams struct {
+	IdempotencyKey string    `json:"idempotency_key,omitempty"`
+	JobID          int64     `json:"job_id,omitempty"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// HeartbeatResult はハートビートの応答。CancelRequested が true ならダッシュボード等から取り消しが要求されている。
+type HeartbeatResult struct {
+	CancelRequested   bool   `json:"cancel_requested"`
+	CancelRequestedBy string `json:"cancel_requested_by"`
+}
+
+type heartbeatRequest struct {
+	NodeToken string    `json:"node_token"`
+	JobID     int64     `json:"job_id"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+func (c *Client) Heartbeat(ctx context.Context, params HeartbeatParams) (*HeartbeatResult, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	payload := heartbeatRequest{
+		NodeToken: c.config.NodeToken,
+		JobID:     params.JobID,
+		SentAt:    params.SentAt,
+	}
+
+	var result HeartbeatResult
+	if err := c.post(ctx, "/api/job-trigger/heartbeat", params.IdempotencyKey, payload, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type ProgressParams struct {
+	JobID      int64
+	Percent    *float64
+	Step       *int64
+	Total      *int64
+	Phase      string
+	ReportedAt time.Time
+}
+
+type progressRequest struct {
+	NodeToken  string    `json:"node_token"`
+	JobID      int64     `json:"job_id"`
+	Percent    *float64  `json:"percent,omitempty"`
+	Step       *int64    `json:"step,omitempty"`
+	Total      *int64    `json:"total,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+func (c *Client) ReportProgress(ctx context.Context, params ProgressParams) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	payload := progressRequest{
+		NodeToken:  c.config.NodeToken,
+		JobID:      params.JobID,
+		Percent:    params.Percent,
+		Step:       params.Step,
+		Total:      params.Total,
+		Phase:      params.Phase,
+		ReportedAt: params.ReportedAt,
+	}
+
+	return c.post(ctx, "/api/job-trigger/progress", "", payload, nil)
+}
+
+type LogChunk struct {
+	Stream string `json:"stream"`
+	Seq    int64  `json:"seq"`
+	Offset int64  `json:"offset"`
+	Data   string `json:"data"`
+}
+
+type appendLogsRequest struct {
+	NodeToken string     `json:"node_token"`
+	JobID     int64      `json:"job_id"`
+	Chunks    []LogChunk `json:"chunks"`
+}
+
+func (c *Client) AppendLogs(ctx context.Context, jobID int64, chunks []LogChunk) error {
+	if !c.Enabled() || len(chunks) == 0 {
+		return nil
+	}
+
+	payload := appendLogsRequest{
+		NodeToken: c.config.NodeToken,
+		JobID:     jobID,
+		Chunks:    chunks,
+	}
+
+	return c.post(ctx, "/api/job-trigger/logs", "", payload, nil)
+}
+
+type MetricPoint struct {
+	Key       string    `json:"key"`
+	Step      int64     `json:"step"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type ingestMetricsRequest struct {
+	NodeToken string        `json:"node_token"`
+	JobID     int64         `json:"job_id"`
+	Points    []MetricPoint `json:"points"`
+}
+
+func (c *Client) IngestMetrics(ctx context.Context, jobID int64, points []MetricPoint) error {
+	if !c.Enabled() || len(points) == 0 {
+		return nil
+	}
+
+	payload := ingestMetricsRequest{
+		NodeToken: c.config.NodeToken,
+		JobID:     jobID,
+		Points:    points,
+	}
+
+	return c.post(ctx, "/api/job-trigger/metrics", "", payload, nil)
+}
+
+// LeasedJob は Hub のキューから借りたジョブ。queue_id を付けて Start すると実行中のジョブになる。
+type LeasedJob struct {
+	QueueID        int64             `json:"queue_id"`
+	Command        []string          `json:"command"`
+	WorkingDir     string            `json:"working_dir"`
+	Tag            string            `json:"tag"`
+	Labels         map[string]string `json:"labels"`
+	Attempt        int               `json:"attempt"`
+	LeaseExpiresAt time.Time         `json:"lease_expires_at"`
+}
+
+type leaseRequest struct {
+	NodeToken string `json:"node_token"`
+}
+
+// Lease はキューのジョブを 1 件借りる。借りられるジョブがないか、ノードのスロットが埋まっていれば nil を返す。
+func (c *Client) Lease(ctx context.Context) (*LeasedJob, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	var job LeasedJob
+	err := c.post(ctx, "/api/job-trigger/lease", "", leaseRequest{NodeToken: c.config.NodeToken}, &job)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.QueueID == 0 {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// PreviousRun はクラスター内で同じタグを持つ、最後に終了したジョブ。
+type PreviousRun struct {
+	JobID      int64     `json:"job_id"`
+	NodeID     int64     `json:"node_id"`
+	Status     string    `json:"status"`
+	ExitCode   *int      `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type previousRunRequest struct {
+	NodeToken string `json:"node_token"`
+	Tag       string `json:"tag"`
+	JobID     int64  `json:"job_id,omitempty"`
+}
+
+// PreviousRun は tag の前回の実行を返す。jobID のジョブは除く。見つからなければ nil を返す。
+func (c *Client) PreviousRun(ctx context.Context, tag string, jobID int64) (*PreviousRun, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	var run PreviousRun
+	err := c.post(ctx, "/api/job-trigger/previous-run", "", previousRunRequest{
+		NodeToken: c.config.NodeToken,
+		Tag:       tag,
+		JobID:     jobID,
+	}, &run)
+	if err != nil {
+		return nil, err
+	}
+	if run.JobID == 0 {
+		return nil, nil
 	}
+	return &run, nil
+}
 
-	return c.post(ctx, "/api/job-trigger/finish", payload)
+// Artifact は Hub に保存されたアーティファクト。
+type Artifact struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256"`
 }
 
-func (c *Client) post(ctx context.Context, path string, payload any) error {
+// UploadArtifact は path のファイルを name という名前でジョブのアーティファクトとして送る。
+// ファイルは読みながら送り、SHA-256 は送り終えた後に付けて Hub 側で照合させる。
+func (c *Client) UploadArtifact(ctx context.Context, jobID int64, name, path string) (*Artifact, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeArtifactForm(form, c.config.NodeToken, jobID, name, info.Size(), file))
+	}()
+
+	endpoint := strings.TrimRight(c.config.URL, "/") + "/api/job-trigger/artifacts"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	// 大きなファイルは --hub-timeout に収まらないため、呼び出し側が ctx に期限を付けて打ち切る
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	var artifact Artifact
+	if err := json.NewDecoder(resp.Body).Decode(&artifact); err != nil {
+		return nil, fmt.Errorf("failed to decode hub response: %w", err)
+	}
+	return &artifact, nil
+}
+
+func writeArtifactForm(form *multipart.Writer, nodeToken string, jobID int64, name string, size int64, file io.Reader) error {
+	fields := [][2]string{
+		{"node_token", nodeToken},
+		{"job_id", strconv.FormatInt(jobID, 10)},
+		{"name", name},
+		{"size", strconv.FormatInt(size, 10)},
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(part, io.TeeReader(io.LimitReader(file, size), hash))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("file changed while uploading: read %d of %d bytes", n, size)
+	}
+
+	if err := form.WriteField("sha256", hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// StatusError は Hub がエラーステータスを返したことを表す。
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("hub request failed: %s: %s", e.Status, e.Body)
+}
+
+// IsRetryable は Hub に到達できなかった、または一時的なエラーだったかを返す。
+// 4xx はリクエスト自体が受け付けられないため、再送しても結果は変わらない。
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	switch statusErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+func (c *Client) post(ctx context.Context, path, idempotencyKey string, payload any, out any) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
@@ -85,6 +477,9 @@ func (c *Client) post(ctx context.Context, path string, payload any) error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -94,7 +489,17 @@ func (c *Client) post(ctx context.Context, path string, payload any) error {
 
 	if resp.StatusCode >= http.StatusBadRequest {
 		msg, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("hub request failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode hub response: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,244 @@
+package logship
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	maxChunkSize     = 32 * 1024
+	maxChunksPerPost = 64
+	maxPendingBytes  = 8 * 1024 * 1024
+)
+
+type Sender interface {
+	AppendLogs(ctx context.Context, jobID int64, chunks []hub.LogChunk) error
+}
+
+// Shipper は子プロセスの stdout/stderr を一定間隔でチャンクに切り出して Hub へ送る。
+// 書き込み側をブロックしないよう、送信に失敗したチャンクは上限まで溜めて次回に再送する。
+type Shipper struct {
+	sender   Sender
+	jobID    int64
+	interval time.Duration
+	timeout  time.Duration
+
+	mu           sync.Mutex
+	streams      map[string]*stream
+	pending      []hub.LogChunk
+	pendingBytes int
+	dropped      int
+	failing      bool
+
+	sendMu  sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+type stream struct {
+	name   string
+	seq    int64
+	offset int64
+	buf    []byte
+}
+
+type streamWriter struct {
+	shipper *Shipper
+	stream  *stream
+}
+
+func New(sender Sender, jobID int64, interval, timeout time.Duration) *Shipper {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Shipper{
+		sender:   sender,
+		jobID:    jobID,
+		interval: interval,
+		timeout:  timeout,
+		streams: map[string]*stream{
+			StreamStdout: {name: StreamStdout},
+			StreamStderr: {name: StreamStderr},
+		},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (s *Shipper) Writer(name string) io.Writer {
+	return &streamWriter{shipper: s, stream: s.streams[name]}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	s := w.shipper
+	s.mu.Lock()
+	w.stream.buf = append(w.stream.buf, p...)
+	full := len(w.stream.buf) >= maxChunkSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (s *Shipper) Start() {
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			s.flush(context.Background(), false)
+		}
+	}()
+}
+
+// Close は送信ループを止め、残っている出力をすべて送信する。
+func (s *Shipper) Close(ctx context.Context) error {
+	close(s.stop)
+	<-s.stopped
+	if err := s.flush(ctx, true); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dropped > 0 {
+		return fmt.Errorf("%d log chunks were dropped because the Hub was unreachable", s.dropped)
+	}
+	return nil
+}
+
+func (s *Shipper) flush(ctx context.Context, final bool) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	for _, name := range []string{StreamStdout, StreamStderr} {
+		s.cut(s.streams[name], final)
+	}
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		n := min(len(s.pending), maxChunksPerPost)
+		batch := append([]hub.LogChunk(nil), s.pending[:n]...)
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := s.send(ctx, batch); err != nil {
+			s.mu.Lock()
+			if !s.failing {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to ship logs to Hub: %v\n", err)
+				s.failing = true
+			}
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		for _, chunk := range s.pending[:n] {
+			s.pendingBytes -= len(chunk.Data)
+		}
+		s.pending = s.pending[n:]
+		s.failing = false
+		s.mu.Unlock()
+	}
+}
+
+func (s *Shipper) send(ctx context.Context, chunks []hub.LogChunk) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return s.sender.AppendLogs(ctx, s.jobID, chunks)
+}
+
+// cut はバッファをチャンクに切り出して pending に積む。s.mu を保持して呼ぶこと。
+// 途中で切れた UTF-8 文字は final でない限り次回に持ち越す。
+func (s *Shipper) cut(st *stream, final bool) {
+	if len(st.buf) == 0 {
+		return
+	}
+
+	n := len(st.buf)
+	if !final {
+		n = completeUTF8Prefix(st.buf)
+	}
+	if n == 0 {
+		return
+	}
+
+	text := sanitize(st.buf[:n])
+	st.buf = append(st.buf[:0], st.buf[n:]...)
+
+	for len(text) > 0 {
+		size := min(len(text), maxChunkSize)
+		for size < len(text) && !utf8.RuneStart(text[size]) {
+			size--
+		}
+		chunk := hub.LogChunk{
+			Stream: st.name,
+			Seq:    st.seq,
+			Offset: st.offset,
+			Data:   text[:size],
+		}
+		st.seq++
+		st.offset += int64(size)
+		text = text[size:]
+
+		s.pending = append(s.pending, chunk)
+		s.pendingBytes += size
+	}
+
+	for s.pendingBytes > maxPendingBytes && len(s.pending) > 0 {
+		s.pendingBytes -= len(s.pending[0].Data)
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+}
+
+func completeUTF8Prefix(b []byte) int {
+	// 末尾から最大 utf8.UTFMax バイト遡り、未完の文字があればその手前までを返す
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if utf8.FullRune(b[i:]) {
+			return len(b)
+		}
+		return i
+	}
+	return len(b)
+}
+
+// sanitize は Postgres の TEXT に保存できない不正な UTF-8 と NUL を置き換える。
+func sanitize(b []byte) string {
+	text := strings.ToValidUTF8(string(b), string(utf8.RuneError))
+	return strings.ReplaceAll(text, "\x00", string(utf8.RuneError))
+}
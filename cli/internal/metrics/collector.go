@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+const (
+	// EnvURL は子プロセスに記録先の URL を伝える環境変数。
+	EnvURL = "JOBBOARD_METRICS_URL"
+
+	maxPointsPerPost = 1000
+	maxPendingPoints = 100000
+)
+
+type Sender interface {
+	IngestMetrics(ctx context.Context, jobID int64, points []hub.MetricPoint) error
+}
+
+// Collector は子プロセスから受け取った時系列の値を溜め、一定間隔で Hub へ送る。
+// 子プロセスはノードトークンを知らなくてよいよう、127.0.0.1 の HTTP で値を受け付ける。
+type Collector struct {
+	sender   Sender
+	jobID    int64
+	interval time.Duration
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	pending  []hub.MetricPoint
+	nextStep map[string]int64
+	dropped  int
+	failing  bool
+
+	server *http.Server
+
+	sendMu  sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func New(sender Sender, jobID int64, interval, timeout time.Duration) *Collector {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Collector{
+		sender:   sender,
+		jobID:    jobID,
+		interval: interval,
+		timeout:  timeout,
+		nextStep: map[string]int64{},
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Add は点を送信待ちに積む。Step が負の点には、その key の直前の step の次を割り当てる。
+func (c *Collector) Add(points ...hub.MetricPoint) {
+	c.mu.Lock()
+	for _, point := range points {
+		// NaN / ±Inf は JSON で送れず、長すぎる key は Hub に拒否されるため捨てる
+		if !validKey(point.Key) || math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+			continue
+		}
+		if point.Step < 0 {
+			point.Step = c.nextStep[point.Key]
+		}
+		if point.Timestamp.IsZero() {
+			point.Timestamp = time.Now()
+		}
+		c.nextStep[point.Key] = max(c.nextStep[point.Key], point.Step+1)
+		c.pending = append(c.pending, point)
+	}
+	if over := len(c.pending) - maxPendingPoints; over > 0 {
+		c.pending = c.pending[over:]
+		c.dropped += over
+	}
+	full := len(c.pending) >= maxPointsPerPost
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Listen は 127.0.0.1 の空いているポートで受付を始め、子プロセスに渡す URL を返す。
+// URL には実行ごとのランダムなパスを含め、同じホストの他のプロセスから書き込まれないようにする。
+func (c *Collector) Listen() (string, error) {
+	var secret [16]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", err
+	}
+	path := "/" + hex.EncodeToString(secret[:]) + "/metrics"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, &handler{collector: c})
+	c.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go c.server.Serve(listener)
+
+	return "http://" + listener.Addr().String() + path, nil
+}
+
+func (c *Collector) Start() {
+	go func() {
+		defer close(c.stopped)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			case <-c.wake:
+			}
+			c.flush(context.Background())
+		}
+	}()
+}
+
+// Close は受付と送信ループを止め、残っている点をすべて送信する。
+func (c *Collector) Close(ctx context.Context) error {
+	if c.server != nil {
+		c.server.Shutdown(ctx)
+	}
+	close(c.stop)
+	<-c.stopped
+	if err := c.flush(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dropped > 0 {
+		return fmt.Errorf("%d metric points were dropped because the Hub was unreachable", c.dropped)
+	}
+	return nil
+}
+
+func (c *Collector) flush(ctx context.Context) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	for {
+		c.mu.Lock()
+		n := min(len(c.pending), maxPointsPerPost)
+		batch := append([]hub.MetricPoint(nil), c.pending[:n]...)
+		dropped := c.dropped
+		c.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := c.send(ctx, batch); err != nil {
+			c.mu.Lock()
+			if !c.failing {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: failed to send metrics to Hub: %v\n", err)
+				c.failing = true
+			}
+			c.mu.Unlock()
+			return err
+		}
+
+		c.mu.Lock()
+		// 送信中に上限を超えて古い点が捨てられていれば、その分だけ先頭がずれている
+		c.pending = c.pending[max(n-(c.dropped-dropped), 0):]
+		c.failing = false
+		c.mu.Unlock()
+	}
+}
+
+func validKey(key string) bool {
+	return key != "" && len(key) <= maxKeyLength
+}
+
+func (c *Collector) send(ctx context.Context, points []hub.MetricPoint) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	return c.sender.IngestMetrics(ctx, c.jobID, points)
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+var (
+	// 先に見つかった列を step として使う
+	csvStepColumns = []string{"step", "global_step", "iteration", "iter", "epoch"}
+	// 先に見つかった列を記録時刻として使う。UNIX 秒か RFC 3339 を受け付ける
+	csvTimeColumns = []string{"timestamp", "wall_time", "time"}
+)
+
+// csvParser はヘッダ行付きの CSV を読み、数値の列をそれぞれ key として記録する。
+// PyTorch Lightning の CSVLogger のように列ごとに空欄がある行も扱える。
+// step 列がなければ行番号（0 始まり）を step にする。
+type csvParser struct {
+	header  []string
+	stepCol int
+	timeCol int
+	row     int64
+}
+
+func (p *csvParser) parse(buf []byte, final bool) ([]hub.MetricPoint, int, error) {
+	var points []hub.MetricPoint
+	consumed := 0
+	for consumed < len(buf) {
+		rest := buf[consumed:]
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 && !final {
+			break
+		}
+		line := rest
+		if i >= 0 {
+			line = rest[:i]
+			consumed += i + 1
+		} else {
+			consumed = len(buf)
+		}
+
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record, err := csv.NewReader(bytes.NewReader(line)).Read()
+		if err != nil {
+			continue
+		}
+
+		if p.header == nil {
+			p.setHeader(record)
+			continue
+		}
+		points = append(points, p.points(record)...)
+	}
+	return points, consumed, nil
+}
+
+func (p *csvParser) setHeader(record []string) {
+	p.header = make([]string, len(record))
+	for i, name := range record {
+		p.header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+	p.stepCol = findColumn(p.header, csvStepColumns)
+	p.timeCol = findColumn(p.header, csvTimeColumns)
+}
+
+func (p *csvParser) points(record []string) []hub.MetricPoint {
+	step := p.row
+	p.row++
+	if p.stepCol >= 0 && p.stepCol < len(record) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[p.stepCol]), 64)
+		if err != nil || v < 0 {
+			return nil
+		}
+		step = int64(v)
+	}
+
+	var ts time.Time
+	if p.timeCol >= 0 && p.timeCol < len(record) {
+		ts = parseCSVTime(strings.TrimSpace(record[p.timeCol]))
+	}
+
+	var points []hub.MetricPoint
+	for i, cell := range record {
+		if i == p.stepCol || i == p.timeCol || i >= len(p.header) || p.header[i] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, hub.MetricPoint{Key: p.header[i], Step: step, Value: v, Timestamp: ts})
+	}
+	return points
+}
+
+func findColumn(header, candidates []string) int {
+	for _, name := range candidates {
+		for i, column := range header {
+			if strings.EqualFold(column, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseCSVTime(value string) time.Time {
+	if sec, err := strconv.ParseFloat(value, 64); err == nil && sec > 0 {
+		whole := int64(sec)
+		return time.Unix(whole, int64((sec-float64(whole))*1e9))
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return ts
+	}
+	return time.Time{}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+const (
+	maxBodySize  = 4 * 1024 * 1024
+	maxKeyLength = 128
+)
+
+// pointInput は子プロセスから受け取る 1 件。次のどちらの形式も受け付ける。
+//
+//	{"key": "loss", "step": 10, "value": 0.42}
+//	{"step": 10, "values": {"loss": 0.42, "accuracy": 0.91}}
+//
+// step を省略すると key ごとに直前の step の次を使う。timestamp は RFC 3339 で、省略時は受信時刻。
+type pointInput struct {
+	Key       string             `json:"key"`
+	Step      *int64             `json:"step"`
+	Value     *float64           `json:"value"`
+	Values    map[string]float64 `json:"values"`
+	Timestamp *time.Time         `json:"timestamp"`
+}
+
+func (in pointInput) points() ([]hub.MetricPoint, error) {
+	step := int64(-1)
+	if in.Step != nil {
+		if *in.Step < 0 {
+			return nil, errors.New("step must not be negative")
+		}
+		step = *in.Step
+	}
+	var ts time.Time
+	if in.Timestamp != nil {
+		ts = *in.Timestamp
+	}
+
+	values := in.Values
+	if in.Key != "" {
+		if in.Value == nil {
+			return nil, fmt.Errorf("value is required for key %q", in.Key)
+		}
+		values = map[string]float64{in.Key: *in.Value}
+	}
+	if len(values) == 0 {
+		return nil, errors.New("either key/value or values is required")
+	}
+
+	points := make([]hub.MetricPoint, 0, len(values))
+	for key, value := range values {
+		if !validKey(key) {
+			return nil, fmt.Errorf("key must be 1-%d bytes: %q", maxKeyLength, key)
+		}
+		points = append(points, hub.MetricPoint{Key: key, Step: step, Value: value, Timestamp: ts})
+	}
+	return points, nil
+}
+
+type handler struct {
+	collector *Collector
+}
+
+// ServeHTTP は JSON のオブジェクト、その配列、または改行区切りの JSON を受け付ける。
+// 一部でも不正な点があればどれも記録せず 400 を返す。
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var points []hub.MetricPoint
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var inputs []pointInput
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &inputs); err != nil {
+				http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else {
+			var input pointInput
+			if err := json.Unmarshal(raw, &input); err != nil {
+				http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			inputs = append(inputs, input)
+		}
+
+		for _, input := range inputs {
+			p, err := input.points()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			points = append(points, p...)
+		}
+	}
+
+	h.collector.Add(points...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "{\"accepted\":%d}\n", len(points))
+}
@@ -0,0 +1,348 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+// TensorBoard の event ファイルは次のレコードの繰り返しになっている。
+//
+//	uint64 length / uint32 masked_crc32c(length) / byte data[length] / uint32 masked_crc32c(data)
+//
+// data は protobuf の tensorflow.Event。依存を増やさないよう、必要なフィールドだけを直接読む。
+
+const (
+	recordHeaderSize = 12
+	recordFooterSize = 4
+	maxRecordSize    = 64 * 1024 * 1024
+)
+
+var (
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("corrupt tfevents record")
+)
+
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, castagnoli)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// readRecord は buf の先頭から 1 レコードを読み、data と消費したバイト数を返す。
+// レコードが途中までしか書かれていなければ n == 0 を返す。
+// data のチェックサムが合わないレコードは読み飛ばせるよう、n とともに errCorruptRecord を返す。
+func readRecord(buf []byte) (data []byte, n int, err error) {
+	if len(buf) < recordHeaderSize {
+		return nil, 0, nil
+	}
+	if binary.LittleEndian.Uint32(buf[8:12]) != maskedCRC(buf[:8]) {
+		return nil, 0, fmt.Errorf("%w: length checksum mismatch", errCorruptRecord)
+	}
+	length := binary.LittleEndian.Uint64(buf[:8])
+	if length > maxRecordSize {
+		return nil, 0, fmt.Errorf("%w: record of %d bytes is too large", errCorruptRecord, length)
+	}
+
+	end := recordHeaderSize + int(length) + recordFooterSize
+	if len(buf) < end {
+		return nil, 0, nil
+	}
+	data = buf[recordHeaderSize : end-recordFooterSize]
+	if binary.LittleEndian.Uint32(buf[end-recordFooterSize:end]) != maskedCRC(data) {
+		return nil, end, fmt.Errorf("%w: data checksum mismatch", errCorruptRecord)
+	}
+	return data, end, nil
+}
+
+// decodeEvent は Event から数値のスカラーだけを取り出す。
+// TF1 / PyTorch の simple_value と、TF2 の要素数 1 の数値テンソルを扱う。
+func decodeEvent(data []byte) ([]hub.MetricPoint, error) {
+	var (
+		wallTime float64
+		step     int64
+		summary  []byte
+	)
+	err := eachField(data, func(f field) error {
+		switch {
+		case f.num == 1 && f.wire == wireFixed64:
+			wallTime = math.Float64frombits(f.fixed64)
+		case f.num == 2 && f.wire == wireVarint:
+			step = int64(f.varint)
+		case f.num == 5 && f.wire == wireBytes:
+			summary = f.bytes
+		}
+		return nil
+	})
+	if err != nil || summary == nil {
+		return nil, err
+	}
+
+	var ts time.Time
+	if wallTime > 0 {
+		sec, frac := math.Modf(wallTime)
+		ts = time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	var points []hub.MetricPoint
+	err = eachField(summary, func(f field) error {
+		if f.num != 1 || f.wire != wireBytes {
+			return nil
+		}
+		tag, value, ok, err := decodeSummaryValue(f.bytes)
+		if err != nil || !ok {
+			return err
+		}
+		points = append(points, hub.MetricPoint{Key: tag, Step: step, Value: value, Timestamp: ts})
+		return nil
+	})
+	return points, err
+}
+
+func decodeSummaryValue(data []byte) (tag string, value float64, ok bool, err error) {
+	var nodeName string
+	err = eachField(data, func(f field) error {
+		switch {
+		case f.num == 1 && f.wire == wireBytes:
+			tag = string(f.bytes)
+		case f.num == 7 && f.wire == wireBytes:
+			nodeName = string(f.bytes)
+		case f.num == 2 && f.wire == wireFixed32:
+			value, ok = float64(math.Float32frombits(f.fixed32)), true
+		case f.num == 8 && f.wire == wireBytes:
+			v, scalar, err := decodeScalarTensor(f.bytes)
+			if err != nil {
+				return err
+			}
+			if scalar {
+				value, ok = v, true
+			}
+		}
+		return nil
+	})
+	if tag == "" {
+		tag = nodeName
+	}
+	return tag, value, ok && tag != "", err
+}
+
+// TensorProto の dtype のうち、スカラーとして扱う数値型。
+const (
+	dtFloat    = 1
+	dtDouble   = 2
+	dtInt32    = 3
+	dtInt64    = 9
+	dtBfloat16 = 14
+	dtHalf     = 19
+)
+
+// decodeScalarTensor は要素数 1 の数値テンソルの値を返す。文字列や多次元のテンソルは対象外。
+func decodeScalarTensor(data []byte) (float64, bool, error) {
+	var (
+		dtype    uint64
+		elements int64 = 1
+		content  []byte
+		values   []float64
+	)
+	err := eachField(data, func(f field) error {
+		switch f.num {
+		case 1:
+			dtype = f.varint
+		case 2:
+			n, err := shapeElements(f.bytes)
+			if err != nil {
+				return err
+			}
+			elements = n
+		case 4:
+			content = f.bytes
+		case 5: // float_val
+			return appendPacked(f, wireFixed32, &values, func(v uint64) float64 {
+				return float64(math.Float32frombits(uint32(v)))
+			})
+		case 6: // double_val
+			return appendPacked(f, wireFixed64, &values, math.Float64frombits)
+		case 7, 10: // int_val, int64_val
+			return appendPacked(f, wireVarint, &values, func(v uint64) float64 { return float64(int64(v)) })
+		case 13: // half_val（bfloat16 もここに入る）
+			return appendPacked(f, wireVarint, &values, func(v uint64) float64 {
+				if dtype == dtBfloat16 {
+					return float64(math.Float32frombits(uint32(v) << 16))
+				}
+				return halfToFloat(uint16(v))
+			})
+		}
+		return nil
+	})
+	if err != nil || elements != 1 {
+		return 0, false, err
+	}
+
+	if len(content) > 0 {
+		switch {
+		case dtype == dtFloat && len(content) == 4:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(content))), true, nil
+		case dtype == dtDouble && len(content) == 8:
+			return math.Float64frombits(binary.LittleEndian.Uint64(content)), true, nil
+		case dtype == dtInt32 && len(content) == 4:
+			return float64(int32(binary.LittleEndian.Uint32(content))), true, nil
+		case dtype == dtInt64 && len(content) == 8:
+			return float64(int64(binary.LittleEndian.Uint64(content))), true, nil
+		case dtype == dtHalf && len(content) == 2:
+			return halfToFloat(binary.LittleEndian.Uint16(content)), true, nil
+		case dtype == dtBfloat16 && len(content) == 2:
+			return float64(math.Float32frombits(uint32(binary.LittleEndian.Uint16(content)) << 16)), true, nil
+		}
+		return 0, false, nil
+	}
+
+	switch dtype {
+	case dtFloat, dtDouble, dtInt32, dtInt64, dtHalf, dtBfloat16:
+		if len(values) == 1 {
+			return values[0], true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// shapeElements は TensorShapeProto の要素数を返す。次元がなければスカラーなので 1。
+func shapeElements(data []byte) (int64, error) {
+	elements := int64(1)
+	err := eachField(data, func(f field) error {
+		if f.num != 2 || f.wire != wireBytes {
+			return nil
+		}
+		return eachField(f.bytes, func(d field) error {
+			if d.num == 1 && d.wire == wireVarint {
+				elements *= int64(d.varint)
+			}
+			return nil
+		})
+	})
+	return elements, err
+}
+
+func halfToFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
+
+// protobuf のワイヤ形式
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type field struct {
+	num     int
+	wire    int
+	varint  uint64
+	fixed64 uint64
+	fixed32 uint32
+	bytes   []byte
+}
+
+func eachField(data []byte, fn func(field) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errCorruptRecord
+		}
+		data = data[n:]
+
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errCorruptRecord
+			}
+			f.varint, data = v, data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errCorruptRecord
+			}
+			f.fixed64, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return errCorruptRecord
+			}
+			f.bytes, data = data[n:n+int(length)], data[n+int(length):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errCorruptRecord
+			}
+			f.fixed32, data = binary.LittleEndian.Uint32(data), data[4:]
+		default:
+			return errCorruptRecord
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendPacked は repeated な数値フィールドを、packed / 非 packed のどちらでも values に追加する。
+func appendPacked(f field, wire int, values *[]float64, conv func(uint64) float64) error {
+	if f.wire != wireBytes {
+		switch f.wire {
+		case wireVarint:
+			*values = append(*values, conv(f.varint))
+		case wireFixed64:
+			*values = append(*values, conv(f.fixed64))
+		case wireFixed32:
+			*values = append(*values, conv(uint64(f.fixed32)))
+		}
+		return nil
+	}
+
+	data := f.bytes
+	for len(data) > 0 {
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errCorruptRecord
+			}
+			*values = append(*values, conv(v))
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errCorruptRecord
+			}
+			*values = append(*values, conv(binary.LittleEndian.Uint64(data)))
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errCorruptRecord
+			}
+			*values = append(*values, conv(uint64(binary.LittleEndian.Uint32(data))))
+			data = data[4:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+	"github.com/kanaya/jobboard-cli/internal/pathglob"
+)
+
+const (
+	SourceTFEvents = "tfevents"
+	SourceCSV      = "csv"
+
+	watchInterval = 2 * time.Second
+	// event ファイルの 1 レコードが収まる大きさにしておく
+	maxReadPerPoll = maxRecordSize + recordHeaderSize + recordFooterSize
+)
+
+// Source は監視するファイルの glob と形式。glob には ** を使える。
+type Source struct {
+	Pattern string
+	Format  string
+}
+
+// parser は追記されたバイト列から点を取り出し、消費したバイト数を返す。
+// 途中までしか書かれていない末尾は消費せず、次回に持ち越す。
+type parser interface {
+	parse(buf []byte, final bool) ([]hub.MetricPoint, int, error)
+}
+
+type tailedFile struct {
+	path   string
+	source Source
+	offset int64
+	parser parser
+	failed bool
+}
+
+// Watcher は学習スクリプトが書く TensorBoard の event ファイルや CSV を実行中に追いかけ、
+// 追記されたスカラーを Collector に渡す。ジョブ開始前から更新されていないファイルは
+// 以前の実行の結果とみなして読まない。
+type Watcher struct {
+	collector *Collector
+	sources   []Source
+	since     time.Time
+
+	files map[string]*tailedFile
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func NewWatcher(collector *Collector, sources []Source) *Watcher {
+	return &Watcher{
+		collector: collector,
+		sources:   sources,
+		since:     time.Now().Add(-time.Second),
+		files:     map[string]*tailedFile{},
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+func (w *Watcher) Start() {
+	go func() {
+		defer close(w.stopped)
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+			w.poll(false)
+		}
+	}()
+}
+
+// Close は監視を止め、子プロセスが最後に書いた分まで読み取る。
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.stopped
+		w.poll(true)
+	})
+}
+
+func (w *Watcher) poll(final bool) {
+	for _, source := range w.sources {
+		paths, err := pathglob.Expand(source.Pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			file, ok := w.files[path]
+			if !ok {
+				info, err := os.Stat(path)
+				if err != nil || info.IsDir() || info.ModTime().Before(w.since) {
+					continue
+				}
+				file = &tailedFile{path: path, source: source, parser: newParser(source, path)}
+				w.files[path] = file
+			}
+			if file.failed {
+				continue
+			}
+			if err := w.read(file, final); err != nil {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: stopped watching %s: %v\n", path, err)
+				file.failed = true
+			}
+		}
+	}
+}
+
+func (w *Watcher) read(file *tailedFile, final bool) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < file.offset {
+		// 切り詰められたか作り直されたので先頭から読み直す
+		file.offset = 0
+		file.parser = newParser(file.source, file.path)
+	}
+	if info.Size() == file.offset {
+		return nil
+	}
+
+	buf := make([]byte, min(info.Size()-file.offset, maxReadPerPoll))
+	n, err := f.ReadAt(buf, file.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	points, consumed, err := file.parser.parse(buf[:n], final)
+	file.offset += int64(consumed)
+	w.collector.Add(points...)
+	return err
+}
+
+func newParser(source Source, path string) parser {
+	if source.Format == SourceCSV {
+		return &csvParser{}
+	}
+	return &tfeventsParser{prefix: runName(source.Pattern, path)}
+}
+
+// runName は TensorBoard の run 名と同じく、glob の固定部分からの相対ディレクトリを返す。
+// runs/**/events.* で runs/exp1/train/events... を読むと exp1/train になる。
+func runName(pattern, path string) string {
+	base, _ := pathglob.Split(pattern)
+	rel, err := filepath.Rel(base, filepath.Dir(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+type tfeventsParser struct {
+	prefix  string
+	skipped bool
+}
+
+func (p *tfeventsParser) parse(buf []byte, final bool) ([]hub.MetricPoint, int, error) {
+	var points []hub.MetricPoint
+	consumed := 0
+	for {
+		data, n, err := readRecord(buf[consumed:])
+		if n == 0 {
+			return points, consumed, err
+		}
+		consumed += n
+		if err != nil {
+			if !p.skipped {
+				fmt.Fprintf(os.Stdout, "[jobboard] warning: skipping tfevents record: %v\n", err)
+				p.skipped = true
+			}
+			continue
+		}
+
+		decoded, err := decodeEvent(data)
+		if err != nil {
+			continue
+		}
+		for _, point := range decoded {
+			if p.prefix != "" {
+				point.Key = p.prefix + "/" + point.Key
+			}
+			points = append(points, point)
+		}
+	}
+}
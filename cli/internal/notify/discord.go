@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+func init() {
+	Register("discord", func(cfg config.NotifyConfig) (Notifier, error) {
+		if !cfg.Discord.Enabled() {
+			return nil, nil
+		}
+		return NewDiscord(cfg.Discord, &http.Client{Timeout: cfg.Discord.Timeout}), nil
+	})
+}
+
+// Discord の embed の上限。description は 4096 文字、field の値は 1024 文字まで。
+const (
+	discordErrorLimit = 3500
+	discordFieldLimit = 1024
+)
+
+// Discord は Webhook に embed 付きのメッセージを投稿する。
+type Discord struct {
+	config     config.DiscordConfig
+	httpClient *http.Client
+}
+
+type discordMessage struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+func NewDiscord(config config.DiscordConfig, httpClient *http.Client) *Discord {
+	return &Discord{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+func (n *Discord) Name() string {
+	return "Discord"
+}
+
+func (n *Discord) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *Discord) Notify(ctx context.Context, payload Payload) error {
+	color := 0x2eb67d
+	switch payload.Status {
+	case "failed":
+		color = 0xe01e5a
+	case "timed_out", "cancelled":
+		color = 0xecb22e
+	}
+
+	var description strings.Builder
+	description.WriteString("```\n" + truncateHeadTail(payload.Command, discordFieldLimit) + "\n```")
+	if trimmed := strings.TrimSpace(payload.Error); trimmed != "" {
+		description.WriteString("\n**Error:**\n```\n" + truncateHeadTail(trimmed, discordErrorLimit) + "\n```")
+	}
+
+	fields := []discordField{
+		{Name: "Status", Value: strings.ToUpper(payload.Status), Inline: true},
+		{Name: "Exit code", Value: strconv.Itoa(payload.ExitCode), Inline: true},
+		{Name: "Duration", Value: payload.Duration.Round(time.Second).String(), Inline: true},
+	}
+	if payload.Tag != "" {
+		fields = append(fields, discordField{Name: "Tag", Value: truncateHeadTail(payload.Tag, discordFieldLimit), Inline: true})
+	}
+	if len(payload.Labels) > 0 {
+		fields = append(fields, discordField{Name: "Labels", Value: truncateHeadTail(strings.Join(sortedLabels(payload.Labels), " "), discordFieldLimit)})
+	}
+	fields = append(fields,
+		discordField{Name: "Started", Value: payload.StartedAt.Format(time.RFC3339), Inline: true},
+		discordField{Name: "Finished", Value: payload.FinishedAt.Format(time.RFC3339), Inline: true},
+	)
+
+	return postJSON(ctx, n.httpClient, n.config.WebhookURL, discordMessage{
+		Username: "jobboard",
+		Embeds: []discordEmbed{{
+			Title:       "jobboard: " + strings.ToUpper(payload.Status),
+			Description: description.String(),
+			Color:       color,
+			Fields:      fields,
+			Timestamp:   payload.FinishedAt.UTC().Format(time.RFC3339),
+		}},
+	})
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+	"github.com/kanaya/jobboard-smtpmail"
+)
+
+func init() {
+	Register("email", func(cfg config.NotifyConfig) (Notifier, error) {
+		if !cfg.Email.Enabled() {
+			return nil, nil
+		}
+		return NewEmail(cfg.Email)
+	})
+}
+
+const (
+	emailErrorTailLines = 50
+	emailErrorTailBytes = 8 << 10
+)
+
+// Email は SMTP で text/plain と text/html の multipart/alternative のサマリを送る。
+type Email struct {
+	config config.EmailConfig
+	from   *mail.Address
+	to     []*mail.Address
+}
+
+func NewEmail(config config.EmailConfig) (*Email, error) {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", config.From, err)
+	}
+	to := make([]*mail.Address, 0, len(config.To))
+	for _, raw := range config.To {
+		addr, err := mail.ParseAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %w", raw, err)
+		}
+		to = append(to, addr)
+	}
+	return &Email{
+		config: config,
+		from:   from,
+		to:     to,
+	}, nil
+}
+
+func (n *Email) Name() string {
+	return "email"
+}
+
+func (n *Email) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *Email) Notify(ctx context.Context, payload Payload) error {
+	msg, err := n.message(payload, time.Now())
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]string, 0, len(n.to))
+	for _, addr := range n.to {
+		recipients = append(recipients, addr.Address)
+	}
+	return smtpmail.Send(ctx, smtpmail.Config{
+		Host:     n.config.Host,
+		Port:     n.config.Port,
+		Username: n.config.Username,
+		Password: n.config.Password,
+		TLS:      n.config.TLS,
+	}, n.from.Address, recipients, msg)
+}
+
+type emailField struct {
+	Name  string
+	Value string
+}
+
+type emailView struct {
+	Title     string
+	Command   string
+	Fields    []emailField
+	ErrorTail string
+}
+
+var emailHTML = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.Title}}</h2>
+<pre style="background: #f4f4f4; padding: 8px;">{{.Command}}</pre>
+<table cellpadding="4" style="border-collapse: collapse;">
+{{- range .Fields}}
+<tr><th align="left">{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .ErrorTail}}
+<h3>Error</h3>
+<pre style="background: #fdf2f2; padding: 8px; white-space: pre-wrap;">{{.ErrorTail}}</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+func (n *Email) message(payload Payload, now time.Time) ([]byte, error) {
+	view := emailView{
+		Title:   "jobboard: " + strings.ToUpper(payload.Status),
+		Command: payload.Command,
+		Fields: []emailField{
+			{Name: "Status", Value: strings.ToUpper(payload.Status)},
+			{Name: "Exit code", Value: strconv.Itoa(payload.ExitCode)},
+		},
+		ErrorTail: smtpmail.ErrorTail(payload.Error, emailErrorTailLines, emailErrorTailBytes),
+	}
+	if payload.Tag != "" {
+		view.Fields = append(view.Fields, emailField{Name: "Tag", Value: payload.Tag})
+	}
+	if len(payload.Labels) > 0 {
+		view.Fields = append(view.Fields, emailField{Name: "Labels", Value: strings.Join(sortedLabels(payload.Labels), " ")})
+	}
+	view.Fields = append(view.Fields,
+		emailField{Name: "Started", Value: payload.StartedAt.Format(time.RFC3339)},
+		emailField{Name: "Finished", Value: payload.FinishedAt.Format(time.RFC3339)},
+		emailField{Name: "Duration", Value: payload.Duration.Round(time.Second).String()},
+	)
+
+	var text strings.Builder
+	text.WriteString(view.Title + "\n\n")
+	text.WriteString(view.Command + "\n\n")
+	for _, field := range view.Fields {
+		text.WriteString(fmt.Sprintf("%s: %s\n", field.Name, field.Value))
+	}
+	if view.ErrorTail != "" {
+		text.WriteString(fmt.Sprintf("\nError (last %d lines):\n%s\n", emailErrorTailLines, view.ErrorTail))
+	}
+
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, view); err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("[jobboard] %s: %s", strings.ToUpper(payload.Status), truncateHeadTail(payload.Command, 80))
+	if payload.Tag != "" {
+		subject += " (" + payload.Tag + ")"
+	}
+
+	to := make([]string, 0, len(n.to))
+	for _, addr := range n.to {
+		to = append(to, addr.String())
+	}
+	return smtpmail.BuildMessage(n.from.String(), to, subject, text.String(), html.String(), now)
+}
@@ -0,0 +1,328 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+	"github.com/kanaya/jobboard-cli/internal/progress"
+)
+
+// StatusRunning は実行中の Payload の Status。
+const StatusRunning = "running"
+
+// Payload は各通知先へ渡す内容。実行中は Status が StatusRunning で、
+// FinishedAt は空、Duration はそれまでの経過時間になる。
+type Payload struct {
+	Command    string
+	Tag        string
+	Labels     map[string]string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	Status     string
+	ExitCode   int
+	Error      string
+	// Progress は子プロセスが最後に報告した進捗。報告がなければ nil
+	Progress *progress.Update
+}
+
+// Notifier は 1 つの通知先。Timeout は Set が Notify ごとに設定する上限。
+type Notifier interface {
+	Name() string
+	Timeout() time.Duration
+	Notify(ctx context.Context, payload Payload) error
+}
+
+// LiveNotifier は開始時にメッセージを送り、実行中と終了時に同じメッセージを書き換えられる通知先。
+type LiveNotifier interface {
+	Notifier
+	// Start は開始時のメッセージを送り、そのメッセージを更新するための Live を返す
+	Start(ctx context.Context, payload Payload) (Live, error)
+	// UpdateInterval は実行中にメッセージを更新する間隔。0 以下なら終了まで更新しない
+	UpdateInterval() time.Duration
+}
+
+// Live は LiveNotifier が送った 1 つのジョブのメッセージ。
+type Live interface {
+	Update(ctx context.Context, payload Payload) error
+	Finish(ctx context.Context, payload Payload) error
+}
+
+// Factory は設定から通知先を作る。設定されていなければ nil, nil を返す。
+type Factory func(cfg config.NotifyConfig) (Notifier, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Factory{}
+)
+
+// Register は通知先の種類を登録する。同じ種類を二度登録すると panic する。
+func Register(kind string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[kind]; ok {
+		panic("notify: Register called twice for " + kind)
+	}
+	registry[kind] = factory
+}
+
+// Kinds は登録済みの種類を名前順に返す。
+func Kinds() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// New は登録済みのすべての種類について設定を確認し、設定された通知先をまとめる。
+func New(cfg config.NotifyConfig) (*Set, error) {
+	set := &Set{}
+	for _, kind := range Kinds() {
+		registryMu.Lock()
+		factory := registry[kind]
+		registryMu.Unlock()
+
+		notifier, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s notifier: %w", kind, err)
+		}
+		if notifier != nil {
+			set.notifiers = append(set.notifiers, notifier)
+		}
+	}
+	return set, nil
+}
+
+// Set は複数の通知先へ同時に送る。1 つが失敗したり遅れたりしても、他の通知先には影響しない。
+type Set struct {
+	notifiers []Notifier
+}
+
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.notifiers)
+}
+
+// Notify はすべての通知先へ送り、失敗したものごとのエラーを返す。
+func (s *Set) Notify(ctx context.Context, payload Payload) []error {
+	return s.each(ctx, "send", func(ctx context.Context, _ int, notifier Notifier) error {
+		return notifier.Notify(ctx, payload)
+	})
+}
+
+// Session は開始時のメッセージを送らずに、終了時にだけ通知する Session を返す。
+func (s *Set) Session() *Session {
+	return &Session{set: s, lives: make([]Live, s.Len())}
+}
+
+// Start は LiveNotifier の通知先へ開始時のメッセージを送り、このジョブの通知をまとめた Session を返す。
+// 開始時のメッセージを送れなかった通知先には、終了時に Notify で送る。
+func (s *Set) Start(ctx context.Context, payload Payload) (*Session, []error) {
+	session := s.Session()
+	errs := s.each(ctx, "start", func(ctx context.Context, i int, notifier Notifier) error {
+		live, ok := notifier.(LiveNotifier)
+		if !ok {
+			return nil
+		}
+		started, err := live.Start(ctx, payload)
+		if err != nil {
+			return err
+		}
+		session.lives[i] = started
+		return nil
+	})
+	return session, errs
+}
+
+// each は通知先ごとに Timeout を設定して send を同時に呼び、失敗したものごとのエラーを返す。
+func (s *Set) each(ctx context.Context, action string, send func(ctx context.Context, i int, notifier Notifier) error) []error {
+	if s.Len() == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(s.notifiers))
+	var wg sync.WaitGroup
+	for i, notifier := range s.notifiers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := callWithTimeout(ctx, notifier.Timeout(), func(ctx context.Context) error {
+				return send(ctx, i, notifier)
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to %s %s notification: %w", action, notifier.Name(), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+func callWithTimeout(ctx context.Context, timeout time.Duration, call func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return call(ctx)
+}
+
+// Session は 1 つのジョブについて、開始時に送ったメッセージを通知先ごとに保持する。
+type Session struct {
+	set *Set
+	// lives は set.notifiers と同じ並び。LiveNotifier でないか Start に失敗した通知先は nil
+	lives []Live
+}
+
+// Live は開始時のメッセージを送れた LiveNotifier があるかを返す。
+func (s *Session) Live() bool {
+	for _, live := range s.lives {
+		if live != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch は LiveNotifier の UpdateInterval ごとに state の内容でメッセージを更新する。
+// 更新に失敗し始めたときだけ warn を呼ぶ。返り値の関数を呼ぶと更新を止め、ループの終了を待つ。
+func (s *Session) Watch(state func() Payload, warn func(error)) func() {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, live := range s.lives {
+		if live == nil {
+			continue
+		}
+		notifier := s.set.notifiers[i].(LiveNotifier)
+		interval := notifier.UpdateInterval()
+		if interval <= 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			failing := false
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+
+				err := callWithTimeout(context.Background(), notifier.Timeout(), func(ctx context.Context) error {
+					return live.Update(ctx, state())
+				})
+				if err != nil && !failing {
+					warn(fmt.Errorf("failed to update %s notification: %w", notifier.Name(), err))
+				}
+				failing = err != nil
+			}
+		}()
+	}
+
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// Notify は開始時のメッセージがある通知先ではそれを終了時の内容に書き換え、それ以外には新しく送る。
+func (s *Session) Notify(ctx context.Context, payload Payload) []error {
+	return s.set.each(ctx, "send", func(ctx context.Context, i int, notifier Notifier) error {
+		if live := s.lives[i]; live != nil {
+			return live.Finish(ctx, payload)
+		}
+		return notifier.Notify(ctx, payload)
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return postBody(ctx, client, url, encoded)
+}
+
+func postBody(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if trimmed := strings.TrimSpace(string(msg)); trimmed != "" {
+			return fmt.Errorf("%s: %s", resp.Status, trimmed)
+		}
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func sortedLabels(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return pairs
+}
+
+func truncateHeadTail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max <= 6 {
+		return s[:max]
+	}
+
+	head := s[:(max-3)/2]
+	tail := s[len(s)-((max-3)/2):]
+	return head + "..." + tail
+}
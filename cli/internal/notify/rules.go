@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+// PreviousStatus は --notify-on-change の判定に使う、同じタグの前回の実行のステータスを返す。
+// 前回の実行がなければ found が false になる。
+type PreviousStatus func(ctx context.Context, tag string) (status string, found bool, err error)
+
+// Rules は通知先を呼ぶ前に、このジョブを通知するかどうかを判定する。
+type Rules struct {
+	config   config.NotifyRules
+	location *time.Location
+	previous PreviousStatus
+}
+
+func NewRules(config config.NotifyRules, location *time.Location, previous PreviousStatus) *Rules {
+	if location == nil {
+		location = time.Local
+	}
+	return &Rules{
+		config:   config,
+		location: location,
+		previous: previous,
+	}
+}
+
+func (r *Rules) Enabled() bool {
+	return r.config.Enabled()
+}
+
+// Evaluate は通知するかどうかと、通知しない場合の理由を返す。
+// 前回の実行を調べられなかった場合は、通知を取りこぼさないよう通知する側に倒し、エラーも返す。
+func (r *Rules) Evaluate(ctx context.Context, payload Payload) (bool, string, error) {
+	if quiet := r.config.QuietHours; quiet != nil && quiet.Contains(payload.FinishedAt.In(r.location)) {
+		return false, fmt.Sprintf("the job finished within quiet hours %s", quiet), nil
+	}
+
+	triggered := len(r.config.On) > 0 || r.config.LongerThan > 0 || r.config.OnChange
+	if !triggered {
+		return true, "", nil
+	}
+
+	var reasons []string
+	if len(r.config.On) > 0 {
+		if slices.Contains(r.config.On, payload.Status) {
+			return true, "", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("status %s is not in --notify-on", payload.Status))
+	}
+
+	if r.config.LongerThan > 0 {
+		if payload.Duration >= r.config.LongerThan {
+			return true, "", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("the job ran for less than %s", r.config.LongerThan))
+	}
+
+	if r.config.OnChange {
+		if payload.Tag == "" || r.previous == nil {
+			return true, "", nil
+		}
+		status, found, err := r.previous(ctx, payload.Tag)
+		if err != nil {
+			return true, "", fmt.Errorf("failed to look up the previous run from Hub: %w", err)
+		}
+		if !found || status != payload.Status {
+			return true, "", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("status %s is unchanged from the previous run", payload.Status))
+	}
+
+	return false, strings.Join(reasons, "; "), nil
+}
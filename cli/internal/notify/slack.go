@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+func init() {
+	Register("slack", func(cfg config.NotifyConfig) (Notifier, error) {
+		if !cfg.Slack.Enabled() {
+			return nil, nil
+		}
+		if cfg.Slack.BotToken != "" {
+			return NewSlackBot(cfg.Slack, &http.Client{Timeout: cfg.Slack.Timeout}), nil
+		}
+		return NewSlack(cfg.Slack, &http.Client{Timeout: cfg.Slack.Timeout}), nil
+	})
+}
+
+// Slack は Incoming Webhook にテキストのサマリを投稿する。
+type Slack struct {
+	config     config.SlackConfig
+	httpClient *http.Client
+}
+
+func NewSlack(config config.SlackConfig, httpClient *http.Client) *Slack {
+	return &Slack{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+func (n *Slack) Name() string {
+	return "Slack"
+}
+
+func (n *Slack) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *Slack) Notify(ctx context.Context, payload Payload) error {
+	icon := ":white_check_mark:"
+	switch payload.Status {
+	case "failed":
+		icon = ":x:"
+	case "timed_out":
+		icon = ":hourglass:"
+	case "cancelled":
+		icon = ":no_entry_sign:"
+	}
+
+	statusLabel := strings.ToUpper(payload.Status)
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%s *jobboard* `%s`\n", icon, payload.Command))
+
+	if payload.Tag != "" {
+		text.WriteString(fmt.Sprintf("*Tag:* %s\n", payload.Tag))
+	}
+
+	if len(payload.Labels) > 0 {
+		pairs := sortedLabels(payload.Labels)
+		for i, pair := range pairs {
+			pairs[i] = "`" + pair + "`"
+		}
+		text.WriteString(fmt.Sprintf("*Labels:* %s\n", strings.Join(pairs, " ")))
+	}
+
+	text.WriteString(fmt.Sprintf("*Status:* %s (exit code %d)\n", statusLabel, payload.ExitCode))
+	text.WriteString(fmt.Sprintf("*Started:* %s\n", payload.StartedAt.Format(time.RFC3339)))
+	text.WriteString(fmt.Sprintf("*Finished:* %s\n", payload.FinishedAt.Format(time.RFC3339)))
+	text.WriteString(fmt.Sprintf("*DurationHours:* %f\n", payload.Duration.Hours()))
+
+	if trimmed := strings.TrimSpace(payload.Error); trimmed != "" {
+		text.WriteString("*Error:*\n```")
+		text.WriteString(truncateHeadTail(trimmed, 900))
+		text.WriteString("```\n")
+	}
+
+	return postJSON(ctx, n.httpClient, n.config.WebhookURL, map[string]string{
+		"text": text.String(),
+	})
+}
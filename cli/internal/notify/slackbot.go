@@ -0,0 +1,303 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+// Block Kit の上限。section の text は 3000 文字、fields の各要素は 2000 文字、header は 150 文字まで。
+// メッセージの text は 40000 文字まで。
+const (
+	slackSectionLimit = 3000
+	slackFieldLimit   = 2000
+	slackHeaderLimit  = 150
+	slackErrorLines   = 30
+	slackThreadLimit  = 35000
+)
+
+// SlackBot は Web API で Block Kit のメッセージを投稿する。開始時に投稿したメッセージを
+// 実行中は chat.update で書き換え、失敗時の詳細はスレッドに返信する。
+type SlackBot struct {
+	config     config.SlackConfig
+	httpClient *http.Client
+}
+
+type slackMessage struct {
+	Channel  string       `json:"channel"`
+	TS       string       `json:"ts,omitempty"`
+	ThreadTS string       `json:"thread_ts,omitempty"`
+	Text     string       `json:"text"`
+	Blocks   []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+func NewSlackBot(config config.SlackConfig, httpClient *http.Client) *SlackBot {
+	return &SlackBot{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+func (n *SlackBot) Name() string {
+	return "Slack"
+}
+
+func (n *SlackBot) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *SlackBot) UpdateInterval() time.Duration {
+	return n.config.UpdateInterval
+}
+
+// Notify は開始時のメッセージがない場合に、終了時のメッセージを新しく投稿する。
+func (n *SlackBot) Notify(ctx context.Context, payload Payload) error {
+	text, blocks := slackBlocks(payload)
+	resp, err := n.call(ctx, "chat.postMessage", slackMessage{
+		Channel: n.config.Channel,
+		Text:    text,
+		Blocks:  blocks,
+	})
+	if err != nil {
+		return err
+	}
+	return n.replyError(ctx, resp.Channel, resp.TS, payload)
+}
+
+func (n *SlackBot) Start(ctx context.Context, payload Payload) (Live, error) {
+	text, blocks := slackBlocks(payload)
+	resp, err := n.call(ctx, "chat.postMessage", slackMessage{
+		Channel: n.config.Channel,
+		Text:    text,
+		Blocks:  blocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// chat.update にはチャンネル名ではなく、応答の ID を渡す必要がある
+	return &slackLive{
+		bot:     n,
+		channel: resp.Channel,
+		ts:      resp.TS,
+	}, nil
+}
+
+type slackLive struct {
+	bot     *SlackBot
+	channel string
+	ts      string
+}
+
+func (l *slackLive) Update(ctx context.Context, payload Payload) error {
+	text, blocks := slackBlocks(payload)
+	_, err := l.bot.call(ctx, "chat.update", slackMessage{
+		Channel: l.channel,
+		TS:      l.ts,
+		Text:    text,
+		Blocks:  blocks,
+	})
+	return err
+}
+
+func (l *slackLive) Finish(ctx context.Context, payload Payload) error {
+	if err := l.Update(ctx, payload); err != nil {
+		return err
+	}
+	return l.bot.replyError(ctx, l.channel, l.ts, payload)
+}
+
+// replyError は失敗したジョブのエラー出力を、切り詰めずにメッセージのスレッドへ返信する。
+func (n *SlackBot) replyError(ctx context.Context, channel, ts string, payload Payload) error {
+	trimmed := strings.TrimSpace(payload.Error)
+	if payload.Status == "completed" || trimmed == "" {
+		return nil
+	}
+	_, err := n.call(ctx, "chat.postMessage", slackMessage{
+		Channel:  channel,
+		ThreadTS: ts,
+		Text:     "*Error output*\n```" + truncateHeadTail(trimmed, slackThreadLimit) + "```",
+	})
+	return err
+}
+
+func (n *SlackBot) call(ctx context.Context, method string, message slackMessage) (*slackResponse, error) {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimRight(n.config.APIURL, "/") + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+n.config.BotToken)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			return nil, fmt.Errorf("%s: %s (retry after %ss)", method, resp.Status, retryAfter)
+		}
+		return nil, fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	// Web API はエラーでも 200 を返し、本文の ok と error で結果を表す
+	var result slackResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %w", method, err)
+	}
+	if !result.OK {
+		if result.Error == "" {
+			return nil, errors.New(method + ": unknown error")
+		}
+		return nil, fmt.Errorf("%s: %s", method, result.Error)
+	}
+	return &result, nil
+}
+
+// slackBlocks は通知のプレビューに使う text と、メッセージ本体の blocks を作る。
+func slackBlocks(payload Payload) (string, []slackBlock) {
+	icon := ":white_check_mark:"
+	switch payload.Status {
+	case StatusRunning:
+		icon = ":hourglass_flowing_sand:"
+	case "failed":
+		icon = ":x:"
+	case "timed_out":
+		icon = ":hourglass:"
+	case "cancelled":
+		icon = ":no_entry_sign:"
+	}
+	status := strings.ToUpper(payload.Status)
+	text := fmt.Sprintf("%s jobboard %s: %s", icon, status, truncateHeadTail(payload.Command, 200))
+
+	fields := []slackText{
+		{Type: "mrkdwn", Text: "*Status:*\n" + status},
+	}
+	if payload.Status != StatusRunning {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Exit code:*\n" + strconv.Itoa(payload.ExitCode)})
+	}
+	if payload.Tag != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Tag:*\n" + truncateHeadTail(payload.Tag, slackFieldLimit-10)})
+	}
+	if len(payload.Labels) > 0 {
+		pairs := sortedLabels(payload.Labels)
+		for i, pair := range pairs {
+			pairs[i] = "`" + pair + "`"
+		}
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Labels:*\n" + truncateHeadTail(strings.Join(pairs, " "), slackFieldLimit-10)})
+	}
+	fields = append(fields, slackText{Type: "mrkdwn", Text: "*Started:*\n" + payload.StartedAt.Format(time.RFC3339)})
+	if payload.Status == StatusRunning {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Elapsed:*\n" + payload.Duration.Round(time.Second).String()})
+	} else {
+		fields = append(fields,
+			slackText{Type: "mrkdwn", Text: "*Finished:*\n" + payload.FinishedAt.Format(time.RFC3339)},
+			slackText{Type: "mrkdwn", Text: "*Duration:*\n" + payload.Duration.Round(time.Second).String()},
+		)
+	}
+
+	blocks := []slackBlock{
+		{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: truncateHeadTail("jobboard: "+status, slackHeaderLimit)},
+		},
+		{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: icon + " `" + truncateHeadTail(payload.Command, slackSectionLimit-100) + "`"},
+		},
+		{
+			Type:   "section",
+			Fields: fields,
+		},
+	}
+
+	if payload.Status == StatusRunning {
+		var elements []slackText
+		if p := payload.Progress; p != nil {
+			elements = append(elements, slackText{Type: "mrkdwn", Text: "*Progress:* " + progressText(p.Percent, p.Step, p.Total, p.Phase)})
+		}
+		elements = append(elements, slackText{Type: "mrkdwn", Text: "Updated " + time.Now().In(payload.StartedAt.Location()).Format(time.RFC3339)})
+		blocks = append(blocks, slackBlock{Type: "context", Elements: elements})
+	}
+
+	if trimmed := strings.TrimSpace(payload.Error); trimmed != "" && payload.Status != StatusRunning {
+		// section の長い text は Slack 側で「もっと見る」に折りたたまれる
+		tail := lastLines(trimmed, slackErrorLines)
+		tail = truncateHeadTail(tail, slackSectionLimit-100)
+		note := ""
+		if payload.Status != "completed" {
+			note = "\n_Full output in thread_"
+		}
+		blocks = append(blocks,
+			slackBlock{Type: "divider"},
+			slackBlock{
+				Type: "section",
+				Text: &slackText{Type: "mrkdwn", Text: "*Error:*\n```" + tail + "```" + note},
+			},
+		)
+	}
+
+	return text, blocks
+}
+
+func progressText(percent *float64, step, total *int64, phase string) string {
+	var parts []string
+	if percent != nil {
+		parts = append(parts, strconv.FormatFloat(*percent, 'f', 1, 64)+"%")
+	}
+	switch {
+	case step != nil && total != nil:
+		parts = append(parts, fmt.Sprintf("step %d/%d", *step, *total))
+	case step != nil:
+		parts = append(parts, fmt.Sprintf("step %d", *step))
+	}
+	if phase != "" {
+		parts = append(parts, phase)
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " · ")
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return "...\n" + strings.Join(lines[len(lines)-n:], "\n")
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+func init() {
+	Register("teams", func(cfg config.NotifyConfig) (Notifier, error) {
+		if !cfg.Teams.Enabled() {
+			return nil, nil
+		}
+		return NewTeams(cfg.Teams, &http.Client{Timeout: cfg.Teams.Timeout}), nil
+	})
+}
+
+const teamsErrorLimit = 4000
+
+// Teams は Incoming Webhook（または Workflows の Webhook）に Adaptive Card を投稿する。
+type Teams struct {
+	config     config.TeamsConfig
+	httpClient *http.Client
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []map[string]any `json:"body"`
+}
+
+func NewTeams(config config.TeamsConfig, httpClient *http.Client) *Teams {
+	return &Teams{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+func (n *Teams) Name() string {
+	return "Teams"
+}
+
+func (n *Teams) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *Teams) Notify(ctx context.Context, payload Payload) error {
+	color := "Good"
+	switch payload.Status {
+	case "failed":
+		color = "Attention"
+	case "timed_out", "cancelled":
+		color = "Warning"
+	}
+
+	facts := []map[string]string{
+		{"title": "Status", "value": strings.ToUpper(payload.Status)},
+		{"title": "Exit code", "value": strconv.Itoa(payload.ExitCode)},
+	}
+	if payload.Tag != "" {
+		facts = append(facts, map[string]string{"title": "Tag", "value": payload.Tag})
+	}
+	if len(payload.Labels) > 0 {
+		facts = append(facts, map[string]string{"title": "Labels", "value": strings.Join(sortedLabels(payload.Labels), " ")})
+	}
+	facts = append(facts,
+		map[string]string{"title": "Started", "value": payload.StartedAt.Format(time.RFC3339)},
+		map[string]string{"title": "Finished", "value": payload.FinishedAt.Format(time.RFC3339)},
+		map[string]string{"title": "Duration", "value": payload.Duration.Round(time.Second).String()},
+	)
+
+	body := []map[string]any{
+		{"type": "TextBlock", "text": "jobboard: " + strings.ToUpper(payload.Status), "weight": "Bolder", "size": "Medium", "color": color},
+		{"type": "TextBlock", "text": payload.Command, "fontType": "Monospace", "wrap": true},
+		{"type": "FactSet", "facts": facts},
+	}
+	if trimmed := strings.TrimSpace(payload.Error); trimmed != "" {
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": "Error", "weight": "Bolder", "spacing": "Medium"},
+			map[string]any{"type": "TextBlock", "text": truncateHeadTail(trimmed, teamsErrorLimit), "fontType": "Monospace", "wrap": true},
+		)
+	}
+
+	return postJSON(ctx, n.httpClient, n.config.WebhookURL, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	})
+}
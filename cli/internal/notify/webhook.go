@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/config"
+)
+
+func init() {
+	Register("webhook", func(cfg config.NotifyConfig) (Notifier, error) {
+		if !cfg.Webhook.Enabled() {
+			return nil, nil
+		}
+		return NewWebhook(cfg.Webhook, &http.Client{Timeout: cfg.Webhook.Timeout})
+	})
+}
+
+// Webhook は任意の URL に JSON を POST する。テンプレートを指定しなければ webhookBody の形で送る。
+type Webhook struct {
+	config     config.WebhookConfig
+	template   *template.Template
+	httpClient *http.Client
+}
+
+type webhookBody struct {
+	Command         string            `json:"command"`
+	Tag             string            `json:"tag,omitempty"`
+	Labels          map[string]string `json:"labels"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      time.Time         `json:"finished_at"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Status          string            `json:"status"`
+	ExitCode        int               `json:"exit_code"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// テンプレートでは Payload のフィールドに加え、値を JSON に変換する json 関数を使える。
+// 例: {"text": {{json (printf "%s: %s" .Status .Command)}}}
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+func NewWebhook(config config.WebhookConfig, httpClient *http.Client) (*Webhook, error) {
+	n := &Webhook{
+		config:     config,
+		httpClient: httpClient,
+	}
+	if config.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(webhookFuncs).Option("missingkey=error").Parse(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		n.template = tmpl
+	}
+	return n, nil
+}
+
+func (n *Webhook) Name() string {
+	return "webhook"
+}
+
+func (n *Webhook) Timeout() time.Duration {
+	return n.config.Timeout
+}
+
+func (n *Webhook) Notify(ctx context.Context, payload Payload) error {
+	if n.template == nil {
+		labels := payload.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		return postJSON(ctx, n.httpClient, n.config.URL, webhookBody{
+			Command:         payload.Command,
+			Tag:             payload.Tag,
+			Labels:          labels,
+			StartedAt:       payload.StartedAt,
+			FinishedAt:      payload.FinishedAt,
+			DurationSeconds: payload.Duration.Seconds(),
+			Status:          payload.Status,
+			ExitCode:        payload.ExitCode,
+			Error:           payload.Error,
+		})
+	}
+
+	var body bytes.Buffer
+	if err := n.template.Execute(&body, payload); err != nil {
+		return err
+	}
+	if !json.Valid(body.Bytes()) {
+		return errors.New("webhook template did not produce valid JSON")
+	}
+	return postBody(ctx, n.httpClient, n.config.URL, body.Bytes())
+}
@@ -0,0 +1,79 @@
+package pathglob
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Expand は filepath.Glob に加え、0 個以上のディレクトリに一致する ** を扱う。
+func Expand(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	base, rest := Split(pattern)
+	segments := strings.Split(rest, string(filepath.Separator))
+	var matches []string
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == base {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return nil
+		}
+		if matchSegments(segments, strings.Split(rel, string(filepath.Separator))) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return matches, err
+}
+
+// Split は pattern を、メタ文字を含まない先頭のディレクトリと残りに分ける。
+func Split(pattern string) (string, string) {
+	segments := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+	i := 0
+	for i < len(segments)-1 && !strings.ContainsAny(segments[i], "*?[") {
+		i++
+	}
+	base := strings.Join(segments[:i], string(filepath.Separator))
+	switch {
+	case base == "" && filepath.IsAbs(pattern):
+		base = string(filepath.Separator)
+	case base == "":
+		base = "."
+	}
+	return base, strings.Join(segments[i:], string(filepath.Separator))
+}
+
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, err := filepath.Match(pattern[0], path[0])
+	return err == nil && ok && matchSegments(pattern[1:], path[1:])
+}
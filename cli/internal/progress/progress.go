@@ -0,0 +1,191 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvFD は進捗を書き込む fd 番号を子プロセスに伝える環境変数。
+	EnvFD = "JOBBOARD_PROGRESS_FD"
+
+	// MarkerPrefix で始まる stdout の行も進捗として扱う。
+	MarkerPrefix = "##jobboard:progress "
+
+	maxLineSize   = 64 * 1024
+	maxPhaseChars = 256
+)
+
+// Update は子プロセスが報告した進捗 1 件。省略された項目は nil / 空文字になる。
+type Update struct {
+	Percent *float64 `json:"percent,omitempty"`
+	Step    *int64   `json:"step,omitempty"`
+	Total   *int64   `json:"total,omitempty"`
+	Phase   string   `json:"phase,omitempty"`
+}
+
+// Parse は 1 行を進捗として解釈する。次のどちらの形式も受け付ける。
+//
+//	{"percent": 42.5, "step": 3, "total": 10, "phase": "train"}
+//	percent=42.5 step=3 total=10 phase=train epoch 3
+//
+// key=value 形式の phase は空白を含められるよう、行末までを値とする。
+func Parse(line string) (Update, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Update{}, false
+	}
+
+	var update Update
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &update); err != nil {
+			return Update{}, false
+		}
+	} else {
+		fields := line
+		if i := strings.Index(" "+line, " phase="); i >= 0 {
+			fields = line[:i]
+			update.Phase = strings.TrimSpace(line[i+len("phase="):])
+		}
+		for _, field := range strings.Fields(fields) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return Update{}, false
+			}
+			switch key {
+			case "percent":
+				v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+				if err != nil {
+					return Update{}, false
+				}
+				update.Percent = &v
+			case "step":
+				v, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return Update{}, false
+				}
+				update.Step = &v
+			case "total":
+				v, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return Update{}, false
+				}
+				update.Total = &v
+			}
+		}
+	}
+
+	if update.Percent == nil && update.Step == nil && update.Total == nil && update.Phase == "" {
+		return Update{}, false
+	}
+	return update.normalize(), true
+}
+
+func (u Update) normalize() Update {
+	if u.Percent == nil && u.Step != nil && u.Total != nil && *u.Total > 0 {
+		percent := float64(*u.Step) / float64(*u.Total) * 100
+		u.Percent = &percent
+	}
+	if u.Percent != nil {
+		percent := math.Max(0, math.Min(100, *u.Percent))
+		if math.IsNaN(percent) {
+			u.Percent = nil
+		} else {
+			u.Percent = &percent
+		}
+	}
+	if runes := []rune(u.Phase); len(runes) > maxPhaseChars {
+		u.Phase = string(runes[:maxPhaseChars])
+	}
+	return u
+}
+
+// Snapshot は Tracker が保持している最新の進捗。
+type Snapshot struct {
+	Update
+	ReportedAt time.Time
+	Version    int64
+}
+
+// Tracker は最新の進捗だけを保持する。送信側は Version を見て変化があったときだけ送る。
+type Tracker struct {
+	mu     sync.Mutex
+	latest Snapshot
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+func (t *Tracker) Set(update Update) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.latest = Snapshot{
+		Update:     update,
+		ReportedAt: time.Now(),
+		Version:    t.latest.Version + 1,
+	}
+}
+
+func (t *Tracker) Latest() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latest
+}
+
+// Read は r から 1 行ずつ進捗を読み取り、EOF まで Tracker に反映する。
+func (t *Tracker) Read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		if update, ok := Parse(scanner.Text()); ok {
+			t.Set(update)
+		}
+	}
+}
+
+// MarkerWriter は stdout に流れた MarkerPrefix の行を拾う Writer を返す。
+// 出力そのものは変更せず、端末やログにはそのまま残る。
+func (t *Tracker) MarkerWriter() io.Writer {
+	return &markerWriter{tracker: t}
+}
+
+type markerWriter struct {
+	tracker *Tracker
+	buf     []byte
+}
+
+func (w *markerWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.scan(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	// 改行のない長い出力は進捗の行ではないので捨てる
+	if len(w.buf) > maxLineSize {
+		w.buf = w.buf[:0]
+	}
+	return len(p), nil
+}
+
+func (w *markerWriter) scan(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if !bytes.HasPrefix(line, []byte(MarkerPrefix)) {
+		return
+	}
+	if update, ok := Parse(string(line[len(MarkerPrefix):])); ok {
+		w.tracker.Set(update)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// プロセスグループを持たないプラットフォームでは直下の子プロセスだけを扱う。
+// シグナルも送れないため、どのシグナルでも強制終了する。
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := cmd.Process.Kill()
+	if err == os.ErrProcessDone {
+		return nil
+	}
+	return err
+}
+
+// SignalName は Hub に記録するためのシグナル名を返す。
+func SignalName(sig os.Signal) string {
+	if sig == os.Interrupt {
+		return "SIGINT"
+	}
+	return sig.String()
+}
+
+func processUsage(state *os.ProcessState) Usage {
+	return Usage{
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+	}
+}
+
+// ExtraFilesSupported は WithExtraFiles で fd を引き継げるかを返す。
+// Windows などでは exec.Cmd.ExtraFiles が使えない。
+func ExtraFilesSupported() bool {
+	return false
+}
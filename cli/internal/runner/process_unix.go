@@ -0,0 +1,80 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGTERM: "SIGTERM",
+}
+
+// setProcessGroup は子プロセスを新しいプロセスグループのリーダーとして起動させ、
+// 孫プロセスまでまとめてシグナルを届けられるようにする。
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	err := syscall.Kill(-cmd.Process.Pid, s)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
+
+// SignalName は Hub に記録するためのシグナル名（SIGINT など）を返す。
+func SignalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		if name, ok := signalNames[s]; ok {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// processUsage は wait4 で得た rusage を Usage に変換する。
+// rusage には子プロセスが wait した子孫の使用量も含まれる。
+func processUsage(state *os.ProcessState) Usage {
+	usage := Usage{
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return usage
+	}
+
+	// ru_maxrss は macOS ではバイト、それ以外では KiB 単位
+	usage.MaxRSSBytes = int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		usage.MaxRSSBytes *= 1024
+	}
+	usage.ReadBytes = int64(ru.Inblock) * 512
+	usage.WrittenBytes = int64(ru.Oublock) * 512
+	return usage
+}
+
+// ExtraFilesSupported は WithExtraFiles で fd を引き継げるかを返す。
+func ExtraFilesSupported() bool {
+	return true
+}
@@ -8,38 +8,269 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultKillGrace      = 30 * time.Second
+	defaultShutdownWindow = 10 * time.Second
 )
 
 type Runner struct{}
 
 type Result struct {
-	ExitCode int
-	Stderr   string
-	Error    error
+	ExitCode  int
+	Stderr    string
+	Error     error
+	TimedOut  bool
+	Cancelled bool
+	// Signal は jobboard が受け取り子プロセスへ転送したシグナル。転送していなければ nil。
+	Signal os.Signal
+	Usage  *Usage
+}
+
+type options struct {
+	stdout         []io.Writer
+	stderr         []io.Writer
+	timeout        time.Duration
+	killGrace      time.Duration
+	shutdownWindow time.Duration
+	usageInterval  time.Duration
+	env            []string
+	extraFiles     []*os.File
+	dir            string
+	signals        <-chan os.Signal
+	detached       bool
+}
+
+type Option func(*options)
+
+// WithOutput は子プロセスの stdout/stderr を端末に加えて指定の Writer にも複製する。
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(o *options) {
+		if stdout != nil {
+			o.stdout = append(o.stdout, stdout)
+		}
+		if stderr != nil {
+			o.stderr = append(o.stderr, stderr)
+		}
+	}
+}
+
+// WithTimeout は実行時間の上限を設定する。上限に達するとプロセスグループ全体に SIGTERM を送り、
+// killGrace 経過後も残っていれば SIGKILL で止める。
+func WithTimeout(timeout, killGrace time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+		o.killGrace = killGrace
+	}
+}
+
+// WithShutdownWindow は受け取ったシグナルを転送してから SIGKILL に切り替えるまでの猶予を設定する。
+func WithShutdownWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.shutdownWindow = window
+	}
+}
+
+// WithUsageSampling は実行中に /proc からプロセスツリーのリソース使用量を採取する間隔を設定する。
+// 0 を渡すと採取せず、終了時の rusage だけを使う。
+func WithUsageSampling(interval time.Duration) Option {
+	return func(o *options) {
+		o.usageInterval = interval
+	}
+}
+
+// WithEnv は子プロセスの環境変数に KEY=VALUE を追加する。
+func WithEnv(env ...string) Option {
+	return func(o *options) {
+		o.env = append(o.env, env...)
+	}
+}
+
+// WithDir は子プロセスの作業ディレクトリを設定する。空なら jobboard と同じディレクトリで実行する。
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithExtraFiles は子プロセスに fd 3 以降として files を引き継ぐ。
+// 子プロセスが終了したときに読み手が EOF を受け取れるよう、Run は起動後に files を閉じる。
+func WithExtraFiles(files ...*os.File) Option {
+	return func(o *options) {
+		o.extraFiles = append(o.extraFiles, files...)
+	}
+}
+
+// WithSignals は転送するシグナルを signals から受け取る。呼び出し側が ForwardedSignals を
+// signal.Notify で先に受け取っていれば、子プロセスの実行前後に届いたシグナルで jobboard が終了しない。
+// 指定しなければ Run の間だけ自分で受け取る。
+func WithSignals(signals <-chan os.Signal) Option {
+	return func(o *options) {
+		o.signals = signals
+	}
+}
+
+// WithoutTerminal は子プロセスに標準入力を渡さず（/dev/null を読ませる）、端末の前面にも置かない。
+// agent のように端末の持ち主が jobboard 自身で、複数のジョブを並行して動かす場合に使う。
+func WithoutTerminal() Option {
+	return func(o *options) {
+		o.detached = true
+	}
+}
+
+// ForwardedSignals は実行中の子プロセスへ転送するシグナル。
+func ForwardedSignals() []os.Signal {
+	return forwardedSignals
 }
 
 func New() *Runner {
 	return &Runner{}
 }
 
-func (r *Runner) Run(ctx context.Context, command []string) (*Result, error) {
+// Run はコマンドを独立したプロセスグループで実行し、終了まで待つ。標準入力が端末なら、そのグループを端末の前面に置く
+// （WithoutTerminal を指定した場合を除く）。
+// 実行中に受け取った SIGINT / SIGTERM / SIGHUP はプロセスグループへそのまま転送し、
+// ctx がキャンセルされた場合は SIGTERM を送って終了させる。
+func (r *Runner) Run(ctx context.Context, command []string, opts ...Option) (*Result, error) {
 	if len(command) == 0 {
 		return nil, errors.New("no command provided to run")
 	}
 
-	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
-	cmd.Env = os.Environ()
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	cfg := options{
+		killGrace:      defaultKillGrace,
+		shutdownWindow: defaultShutdownWindow,
+		usageInterval:  defaultUsageSampleInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), cfg.env...)
+	cmd.Dir = cfg.dir
+	cmd.ExtraFiles = cfg.extraFiles
+	var foreground bool
+	if !cfg.detached {
+		cmd.Stdin, foreground = childStdin()
+	}
+	cmd.Stdout = teeWriter(os.Stdout, cfg.stdout)
 
 	var stderrBuf bytes.Buffer
-	cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+	cmd.Stderr = io.MultiWriter(append([]io.Writer{os.Stderr, &stderrBuf}, cfg.stderr...)...)
+	setProcessGroup(cmd)
+	if foreground {
+		setForeground(cmd, os.Stdin)
+	}
 
-	err := cmd.Run()
+	signals := cfg.signals
+	if signals == nil {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, forwardedSignals...)
+		defer signal.Stop(ch)
+		signals = ch
+	}
+
+	err := cmd.Start()
+	for _, f := range cfg.extraFiles {
+		f.Close()
+	}
+	if err != nil {
+		return &Result{ExitCode: 1, Error: err}, nil
+	}
+
+	sampler := newUsageSampler()
+	sampler.start(cmd.Process.Pid, cfg.usageInterval)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeoutC <-chan time.Time
+	if cfg.timeout > 0 {
+		timer := time.NewTimer(cfg.timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	var (
+		killC     <-chan time.Time
+		ctxDone   = ctx.Done()
+		received  os.Signal
+		timedOut  bool
+		cancelled bool
+	)
+
+	// SIGTERM やシグナル転送の後は killC の期限まで子プロセスの終了を待ち、
+	// 残っていればプロセスグループごと SIGKILL する
+	escalate := func(after time.Duration) {
+		if killC == nil {
+			killC = time.After(after)
+		}
+	}
+
+wait:
+	for {
+		select {
+		case err = <-done:
+			break wait
+		case <-timeoutC:
+			timeoutC = nil
+			timedOut = true
+			sendSignal(cmd, syscall.SIGTERM)
+			escalate(cfg.killGrace)
+		case <-ctxDone:
+			ctxDone = nil
+			cancelled = true
+			sendSignal(cmd, syscall.SIGTERM)
+			escalate(cfg.killGrace)
+		case sig := <-signals:
+			if received == nil {
+				received = sig
+			}
+			sendSignal(cmd, sig)
+			escalate(cfg.shutdownWindow)
+		case <-killC:
+			killC = nil
+			sendSignal(cmd, syscall.SIGKILL)
+		}
+	}
+
+	if foreground {
+		reclaimTerminal(os.Stdin)
+	}
+
+	usage := sampler.stop()
+	if cmd.ProcessState != nil {
+		usage = usage.merge(processUsage(cmd.ProcessState))
+		// 端末から送られたシグナルは子プロセスが直接受け取るので、転送した場合と同じく扱う
+		if foreground && received == nil && !timedOut && !cancelled {
+			received = terminatedBy(cmd.ProcessState)
+		}
+	}
 
 	result := &Result{
-		ExitCode: 0,
-		Stderr:   stderrBuf.String(),
+		ExitCode:  0,
+		Stderr:    stderrBuf.String(),
+		TimedOut:  timedOut,
+		Cancelled: cancelled || received != nil,
+		Signal:    received,
+		Usage:     &usage,
+	}
+
+	switch {
+	case timedOut:
+		result.ExitCode = exitCode(err)
+		result.Error = fmt.Errorf("timed out after %s", cfg.timeout)
+		return result, nil
+	case received != nil:
+		result.ExitCode = exitCode(err)
+		result.Error = fmt.Errorf("cancelled by %s", SignalName(received))
+		return result, nil
 	}
 
 	if err != nil {
@@ -56,3 +287,45 @@ func (r *Runner) Run(ctx context.Context, command []string) (*Result, error) {
 
 	return result, nil
 }
+
+func sendSignal(cmd *exec.Cmd, sig os.Signal) {
+	if err := signalGroup(cmd, sig); err != nil {
+		fmt.Fprintf(os.Stderr, "[jobboard] warning: failed to send %s: %v\n", SignalName(sig), err)
+	}
+}
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// childStdin は子プロセスに渡す標準入力を返す。子プロセスは別のプロセスグループで動くため、
+// 端末の場合は子プロセスのグループを端末の前面に置いて渡す（foreground が true）。
+// jobboard 自身が背面で動いているときは端末から読むと SIGTTIN で停止するので、渡さず EOF を読ませる。
+func childStdin() (stdin io.Reader, foreground bool) {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, false
+	}
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return os.Stdin, false
+	}
+	if foregroundTerminal(os.Stdin) {
+		return os.Stdin, true
+	}
+	return nil, false
+}
+
+// teeWriter は複製先がなければ端末をそのまま子プロセスに渡し、isatty 判定を壊さないようにする。
+func teeWriter(base *os.File, extra []io.Writer) io.Writer {
+	if len(extra) == 0 {
+		return base
+	}
+	return io.MultiWriter(append([]io.Writer{base}, extra...)...)
+}
@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+// 端末の前面のプロセスグループを切り替えられないプラットフォームでは、端末を子プロセスに渡さない。
+func foregroundTerminal(f *os.File) bool {
+	return false
+}
+
+func setForeground(cmd *exec.Cmd, tty *os.File) {}
+
+func reclaimTerminal(tty *os.File) {}
+
+func terminatedBy(state *os.ProcessState) os.Signal {
+	return nil
+}
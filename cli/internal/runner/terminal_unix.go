@@ -0,0 +1,60 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// foregroundTerminal は標準入力が端末で、jobboard がその前面のプロセスグループにいれば true を返す。
+// 背面で実行されている（`jobboard ... &` など）ときに端末を奪わないよう確認する。
+func foregroundTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return false
+	}
+	return int(pgrp) == syscall.Getpgrp()
+}
+
+// setForeground は子プロセスのプロセスグループを端末の前面に置いて起動させる。
+// 子プロセスは端末から読めるようになり、Ctrl+C などの端末からのシグナルも直接受け取る。
+func setForeground(cmd *exec.Cmd, tty *os.File) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(tty.Fd())
+}
+
+// reclaimTerminal は子プロセスの終了後、端末の前面を jobboard のプロセスグループに戻す。
+// 背面から tcsetpgrp すると SIGTTOU で止まるため、その間だけ無視する。
+func reclaimTerminal(tty *os.File) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	pgrp := int32(syscall.Getpgrp())
+	syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+}
+
+// terminatedBy は子プロセスが forwardedSignals のいずれかで終了していれば、そのシグナルを返す。
+// 前面の子プロセスは端末からのシグナルを jobboard を経由せずに受け取るため、終了状態から判断する。
+func terminatedBy(state *os.ProcessState) os.Signal {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return nil
+	}
+	for _, sig := range forwardedSignals {
+		if sig == ws.Signal() {
+			return ws.Signal()
+		}
+	}
+	return nil
+}
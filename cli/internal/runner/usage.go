@@ -0,0 +1,33 @@
+package runner
+
+import (
+	"time"
+)
+
+const defaultUsageSampleInterval = 5 * time.Second
+
+// Usage はプロセスツリー全体のリソース使用量。
+// 終了時の rusage と実行中の /proc サンプリングのうち大きい方を採用する。
+type Usage struct {
+	UserCPU      time.Duration
+	SystemCPU    time.Duration
+	MaxRSSBytes  int64
+	ReadBytes    int64
+	WrittenBytes int64
+}
+
+func (u Usage) merge(other Usage) Usage {
+	return Usage{
+		UserCPU:      max(u.UserCPU, other.UserCPU),
+		SystemCPU:    max(u.SystemCPU, other.SystemCPU),
+		MaxRSSBytes:  max(u.MaxRSSBytes, other.MaxRSSBytes),
+		ReadBytes:    max(u.ReadBytes, other.ReadBytes),
+		WrittenBytes: max(u.WrittenBytes, other.WrittenBytes),
+	}
+}
+
+// usageSampler は実行中のプロセスグループを定期的に観測する。
+type usageSampler interface {
+	start(pgid int, interval time.Duration)
+	stop() Usage
+}
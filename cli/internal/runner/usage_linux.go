@@ -0,0 +1,174 @@
+//go:build linux
+
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks は /proc/<pid>/stat の utime/stime の単位。Linux ではほぼ常に 100。
+const clockTicks = 100
+
+type procStat struct {
+	userTicks   int64
+	systemTicks int64
+	readBytes   int64
+	writeBytes  int64
+}
+
+// procSampler は /proc からプロセスグループに属するプロセスを列挙し、
+// RSS の合計のピークと、プロセスごとの CPU 時間・I/O の最終観測値を集計する。
+// 途中で終了したプロセスも最後に観測した値で合算される。
+type procSampler struct {
+	mu      sync.Mutex
+	peakRSS int64
+	seen    map[int]procStat
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func newUsageSampler() usageSampler {
+	return &procSampler{seen: map[int]procStat{}}
+}
+
+func (s *procSampler) start(pgid int, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.quit = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.sample(pgid)
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *procSampler) stop() Usage {
+	if s.quit != nil {
+		close(s.quit)
+		<-s.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := Usage{MaxRSSBytes: s.peakRSS}
+	var userTicks, systemTicks int64
+	for _, st := range s.seen {
+		userTicks += st.userTicks
+		systemTicks += st.systemTicks
+		usage.ReadBytes += st.readBytes
+		usage.WrittenBytes += st.writeBytes
+	}
+	usage.UserCPU = ticksToDuration(userTicks)
+	usage.SystemCPU = ticksToDuration(systemTicks)
+	return usage
+}
+
+func (s *procSampler) sample(pgid int) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return
+	}
+
+	pageSize := int64(os.Getpagesize())
+	var rss int64
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+
+		pgrp, st, rssPages, ok := readProcStat(dir)
+		if !ok || pgrp != pgid {
+			continue
+		}
+		st.readBytes, st.writeBytes = readProcIO(dir)
+		rss += rssPages * pageSize
+
+		s.mu.Lock()
+		prev := s.seen[pid]
+		s.seen[pid] = procStat{
+			userTicks:   max(prev.userTicks, st.userTicks),
+			systemTicks: max(prev.systemTicks, st.systemTicks),
+			readBytes:   max(prev.readBytes, st.readBytes),
+			writeBytes:  max(prev.writeBytes, st.writeBytes),
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.peakRSS = max(s.peakRSS, rss)
+	s.mu.Unlock()
+}
+
+// readProcStat は /proc/<pid>/stat からプロセスグループ、CPU 時間、RSS（ページ数）を読む。
+func readProcStat(dir string) (int, procStat, int64, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0, procStat{}, 0, false
+	}
+	// comm に空白や括弧が含まれうるため、最後の ')' 以降をフィールドとして扱う
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, procStat{}, 0, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	// fields[0] は state (3 番目のフィールド)
+	if len(fields) < 22 {
+		return 0, procStat{}, 0, false
+	}
+	pgrp, _ := strconv.Atoi(fields[2])
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+	return pgrp, procStat{userTicks: utime, systemTicks: stime}, rss, true
+}
+
+func readProcIO(dir string) (int64, int64) {
+	f, err := os.Open(filepath.Join(dir, "io"))
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	var read, write int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "read_bytes":
+			read = n
+		case "write_bytes":
+			write = n
+		}
+	}
+	return read, write
+}
+
+func ticksToDuration(ticks int64) time.Duration {
+	return time.Duration(ticks) * time.Second / clockTicks
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package runner
+
+import "time"
+
+// /proc を持たないプラットフォームでは終了時の rusage だけを使う。
+type noopSampler struct{}
+
+func newUsageSampler() usageSampler {
+	return noopSampler{}
+}
+
+func (noopSampler) start(pgid int, interval time.Duration) {}
+
+func (noopSampler) stop() Usage {
+	return Usage{}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package spool
+
+// processAlive を確かめる手段がないため、常に再送の対象にする。
+// Idempotency-Key があるので、実行中のスプールを送っても二重には適用されない。
+func processAlive(pid int) bool {
+	return false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package spool
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive はスプールを書いたプロセスがまだ動いているかを返す。
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,284 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-cli/internal/hub"
+)
+
+const (
+	KindStart     = "start"
+	KindHeartbeat = "heartbeat"
+	KindFinish    = "finish"
+
+	fileSuffix = ".json"
+)
+
+type Sender interface {
+	Start(ctx context.Context, params hub.StartParams) (int64, error)
+	Heartbeat(ctx context.Context, params hub.HeartbeatParams) (*hub.HeartbeatResult, error)
+	Finish(ctx context.Context, params hub.FinishParams) error
+}
+
+// Event は Hub へ届けられなかったイベント 1 件。
+// 各パラメータには元の時刻と Idempotency-Key がそのまま入っている。
+type Event struct {
+	Kind      string               `json:"kind"`
+	Start     *hub.StartParams     `json:"start,omitempty"`
+	Heartbeat *hub.HeartbeatParams `json:"heartbeat,omitempty"`
+	Finish    *hub.FinishParams    `json:"finish,omitempty"`
+}
+
+// Run は 1 回の jobboard 実行ぶんのスプール。ファイル 1 つに対応する。
+// start が届いていれば JobID が入り、以降のイベントはその ID で再送する。
+type Run struct {
+	ID        string    `json:"id"`
+	PID       int       `json:"pid"`
+	CreatedAt time.Time `json:"created_at"`
+	JobID     int64     `json:"job_id,omitempty"`
+	Events    []Event   `json:"events"`
+}
+
+// Spool は Hub に届けられなかった start / heartbeat / finish をディスクに保存し、
+// 後から元の順序で再送する。
+type Spool struct {
+	dir string
+}
+
+func Open(dir string) (*Spool, error) {
+	if dir == "" {
+		return nil, errors.New("spool directory is not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	return &Spool{dir: dir}, nil
+}
+
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// Append はイベントを実行 runID のスプールに追記する。
+// ハートビートは最新の 1 件だけ残せば十分なので、古いものは置き換える。
+func (s *Spool) Append(runID string, jobID int64, event Event) error {
+	run, err := s.load(runID)
+	if errors.Is(err, os.ErrNotExist) {
+		run = &Run{
+			ID:        runID,
+			PID:       os.Getpid(),
+			CreatedAt: time.Now().UTC(),
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if jobID != 0 {
+		run.JobID = jobID
+	}
+	if event.Kind == KindHeartbeat {
+		events := run.Events[:0]
+		for _, e := range run.Events {
+			if e.Kind != KindHeartbeat {
+				events = append(events, e)
+			}
+		}
+		run.Events = events
+	}
+	run.Events = append(run.Events, event)
+
+	return s.save(run)
+}
+
+// Flush は他プロセスが実行中のものを除くすべてのスプールを古い順に再送する。
+// Hub に到達できなければその時点で止め、残りは次回に回す。
+func (s *Spool) Flush(ctx context.Context, sender Sender) (int, error) {
+	runs, err := s.runs()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, run := range runs {
+		if run.PID != os.Getpid() && processAlive(run.PID) {
+			continue
+		}
+		n, err := s.flushRun(ctx, sender, run)
+		delivered += n
+		if err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// FlushRun は実行 runID のスプールだけを再送し、判明した job_id を返す。
+func (s *Spool) FlushRun(ctx context.Context, sender Sender, runID string) (int64, error) {
+	run, err := s.load(runID)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.flushRun(ctx, sender, run)
+	return run.JobID, err
+}
+
+func (s *Spool) flushRun(ctx context.Context, sender Sender, run *Run) (int, error) {
+	delivered := 0
+	for len(run.Events) > 0 {
+		event := run.Events[0]
+		err := s.deliver(ctx, sender, run, event)
+		if hub.IsRetryable(err) {
+			if saveErr := s.save(run); saveErr != nil {
+				return delivered, saveErr
+			}
+			return delivered, err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: dropping spooled %s event of run %s: %v\n", event.Kind, run.ID, err)
+			if event.Kind == KindStart {
+				// start が拒否された実行の残りを送ると、別のジョブに適用されかねない
+				run.Events = nil
+				break
+			}
+		} else {
+			delivered++
+		}
+		run.Events = run.Events[1:]
+	}
+
+	if err := s.remove(run.ID); err != nil {
+		return delivered, err
+	}
+	return delivered, nil
+}
+
+func (s *Spool) deliver(ctx context.Context, sender Sender, run *Run, event Event) error {
+	switch event.Kind {
+	case KindStart:
+		if event.Start == nil {
+			return errors.New("start event has no payload")
+		}
+		jobID, err := sender.Start(ctx, *event.Start)
+		if err != nil {
+			return err
+		}
+		run.JobID = jobID
+		return nil
+	case KindHeartbeat:
+		if event.Heartbeat == nil {
+			return errors.New("heartbeat event has no payload")
+		}
+		if run.JobID == 0 {
+			return errors.New("job id is unknown")
+		}
+		params := *event.Heartbeat
+		params.JobID = run.JobID
+		// 再送では取り消し要求を扱わない。実行中のジョブなら次のハートビートで受け取る
+		_, err := sender.Heartbeat(ctx, params)
+		return err
+	case KindFinish:
+		if event.Finish == nil {
+			return errors.New("finish event has no payload")
+		}
+		params := *event.Finish
+		if params.JobID == 0 {
+			params.JobID = run.JobID
+		}
+		return sender.Finish(ctx, params)
+	default:
+		return fmt.Errorf("unknown event kind %q", event.Kind)
+	}
+}
+
+func (s *Spool) runs() ([]*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		run, err := s.load(strings.TrimSuffix(name, fileSuffix))
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "[jobboard] warning: skipping unreadable spool file %s: %v\n", name, err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+func (s *Spool) path(runID string) string {
+	return filepath.Join(s.dir, runID+fileSuffix)
+}
+
+func (s *Spool) load(runID string) (*Run, error) {
+	data, err := os.ReadFile(s.path(runID))
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// save は一時ファイルに書いてから rename し、書きかけのファイルを残さない。
+func (s *Spool) save(run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+run.ID+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(run.ID))
+}
+
+func (s *Spool) remove(runID string) error {
+	err := os.Remove(s.path(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -7,13 +7,20 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/kanaya/jobboard-hub/internal/anomaly"
+	"github.com/kanaya/jobboard-hub/internal/blobstore"
 	"github.com/kanaya/jobboard-hub/internal/config"
 	"github.com/kanaya/jobboard-hub/internal/database"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
+	"github.com/kanaya/jobboard-hub/internal/mailer"
+	"github.com/kanaya/jobboard-hub/internal/reaper"
 	"github.com/kanaya/jobboard-hub/internal/router"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	cfg := config.Load()
 
@@ -24,7 +31,24 @@ func main() {
 	defer db.Close()
 	log.Println("Successfully connected to database")
 
-	r := router.New(ctx, db, cfg.Server.AllowedOrigins, []byte(cfg.Auth.JWTSecret), cfg.Auth.TokenTTL)
+	store, err := blobstore.New(cfg.Blob)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+
+	queries := repo.New(db.Pool)
+
+	notifier, err := mailer.New(cfg.Mail, queries)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	detector := anomaly.New(queries, cfg.Anomaly, notifier)
+	broker := events.NewMemoryBroker(cfg.Events.BufferSize)
+
+	r := router.New(ctx, db, store, cfg.Blob.MaxArtifactSize, cfg.Queue.LeaseTTL, notifier, detector, broker, cfg.Events.KeepAlive, cfg.Server.AllowedOrigins, []byte(cfg.Auth.JWTSecret), cfg.Auth.TokenTTL)
+
+	go reaper.New(queries, cfg.Reaper, notifier, detector, broker).Run(ctx)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -32,6 +56,7 @@ func main() {
 	go func() {
 		<-quit
 		log.Println("Shutting down server...")
+		cancel()
 		db.Close()
 		os.Exit(0)
 	}()
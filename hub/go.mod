@@ -7,6 +7,7 @@ require (
 	github.com/gin-gonic/gin v1.11.0
 	github.com/golang-migrate/migrate/v4 v4.19.0
 	github.com/jackc/pgx/v5 v5.5.4
+	github.com/kanaya/jobboard-smtpmail v0.0.0
 )
 
 require (
@@ -50,3 +51,6 @@ require (
 	golang.org/x/tools v0.38.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
 )
+
+// CLI と共有するメール送信。リポジトリ内のディレクトリを使う
+replace github.com/kanaya/jobboard-smtpmail => ../smtpmail
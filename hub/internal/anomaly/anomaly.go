@@ -0,0 +1,180 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kanaya/jobboard-hub/internal/config"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/mailer"
+)
+
+const (
+	Slow = "slow"
+	Fast = "fast"
+)
+
+const (
+	// madScale は MAD を正規分布の標準偏差に揃える係数
+	madScale = 1.4826
+	// minSpreadRatio は MAD が 0 に近いとき（毎回ほぼ同じ時間で終わるタグ）に使う散らばりの下限。中央値に対する割合
+	minSpreadRatio = 0.05
+)
+
+// 所要時間を比べるステータス。cancelled と lost は途中で止まったもので、所要時間に意味がない。
+// ベースラインは completed だけから作るので、failed / timed_out は slow だけを判定する（途中で落ちたジョブは短くて当然）
+var judgedStatuses = map[string]bool{
+	"completed": true,
+	"failed":    true,
+	"timed_out": true,
+}
+
+// Detector はタグごとの所要時間のベースライン（直近の completed の中央値と MAD）とジョブを比べ、
+// 外れたジョブに slow / fast の印を付けて通知する。
+type Detector struct {
+	queries repo.Querier
+	config  config.AnomalyConfig
+	mailer  *mailer.Mailer
+}
+
+func New(queries repo.Querier, cfg config.AnomalyConfig, mailer *mailer.Mailer) *Detector {
+	return &Detector{
+		queries: queries,
+		config:  cfg,
+		mailer:  mailer,
+	}
+}
+
+// JobFinished は終了したジョブの所要時間を判定して記録し、ベースラインを更新する。
+// 判定は更新前のベースラインで行うので、ジョブ自身は比較対象に含まれない。
+// 印を付け直したジョブを返す。判定しなかった場合や失敗した場合は job をそのまま返す。
+func (d *Detector) JobFinished(ctx context.Context, job repo.Job, nodeName string) repo.Job {
+	if job.Tag == nil || !judgedStatuses[job.Status] {
+		return job
+	}
+
+	judged, err := d.judge(ctx, job)
+	if err != nil {
+		log.Printf("failed to judge duration of job %d: %v", job.ID, err)
+	}
+
+	if job.Status == "completed" {
+		if err := d.queries.RefreshJobDurationBaseline(ctx, repo.RefreshJobDurationBaselineParams{
+			ClusterID:  job.ClusterID,
+			Tag:        *job.Tag,
+			WindowSize: d.config.Window,
+		}); err != nil {
+			log.Printf("failed to refresh duration baseline for tag %q: %v", *job.Tag, err)
+		}
+	}
+
+	if judged == nil {
+		return job
+	}
+	// 実行中に slow として通知済みなら、終了時には送らない
+	notified := job.DurationAnomaly != nil && *job.DurationAnomaly == Slow
+	if judged.DurationAnomaly != nil && !(notified && *judged.DurationAnomaly == Slow) {
+		d.mailer.DurationAnomaly(*judged, nodeName)
+	}
+	return *judged
+}
+
+func (d *Detector) judge(ctx context.Context, job repo.Job) (*repo.Job, error) {
+	seconds, ok := durationSeconds(job)
+	if !ok {
+		return nil, nil
+	}
+
+	baseline, err := d.queries.GetJobDurationBaseline(ctx, repo.GetJobDurationBaselineParams{
+		ClusterID: job.ClusterID,
+		Tag:       *job.Tag,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if baseline.SampleCount < d.config.MinSamples {
+		return nil, nil
+	}
+
+	score := robustScore(seconds, baseline.MedianSeconds, baseline.MadSeconds)
+	var anomaly *string
+	switch {
+	case score >= d.config.Threshold:
+		anomaly = ptr(Slow)
+	case score <= -d.config.Threshold && job.Status == "completed":
+		anomaly = ptr(Fast)
+	}
+
+	updated, err := d.queries.SetJobDurationAnomaly(ctx, repo.SetJobDurationAnomalyParams{
+		Anomaly:         anomaly,
+		Score:           &score,
+		BaselineSeconds: baseline.MedianSeconds,
+		ID:              job.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// CheckRunning は実行中のジョブのうち、経過時間がベースラインの中央値の RunningFactor 倍を超えたものに
+// slow の印を付けて通知し、印を付けたジョブを返す。1 つのジョブにつき 1 回だけ通知する。
+func (d *Detector) CheckRunning(ctx context.Context) ([]repo.Job, error) {
+	if d.config.RunningFactor <= 0 {
+		return nil, nil
+	}
+
+	jobs, err := d.queries.FlagOverrunningJobs(ctx, repo.FlagOverrunningJobsParams{
+		MinSamples: d.config.MinSamples,
+		Factor:     d.config.RunningFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		log.Printf("job %d is running longer than %.1fx its baseline", job.ID, d.config.RunningFactor)
+
+		nodeName := fmt.Sprintf("node %d", job.NodeID)
+		if node, err := d.queries.GetNodeByCluster(ctx, repo.GetNodeByClusterParams{ID: job.NodeID, ClusterID: job.ClusterID}); err == nil {
+			nodeName = node.NodeName
+		}
+		d.mailer.DurationAnomaly(job, nodeName)
+	}
+	return jobs, nil
+}
+
+// robustScore は所要時間の robust z-score。正なら中央値より遅く、負なら速い。
+func robustScore(seconds, median, mad float64) float64 {
+	spread := math.Max(madScale*mad, minSpreadRatio*median)
+	if spread <= 0 {
+		return 0
+	}
+	return (seconds - median) / spread
+}
+
+// durationSeconds は CLI が報告した所要時間を優先し、なければ開始と終了の時刻の差を使う。
+func durationSeconds(job repo.Job) (float64, bool) {
+	if iv := job.DurationHours; iv.Valid {
+		d := time.Duration(iv.Microseconds)*time.Microsecond +
+			time.Duration(iv.Days)*24*time.Hour +
+			time.Duration(iv.Months)*30*24*time.Hour
+		return d.Seconds(), true
+	}
+	if job.StartedAt.Valid && job.FinishedAt.Valid {
+		return job.FinishedAt.Time.Sub(job.StartedAt.Time).Seconds(), true
+	}
+	return 0, false
+}
+
+func ptr(s string) *string {
+	return &s
+}
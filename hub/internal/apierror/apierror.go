@@ -85,14 +85,20 @@ func Write(c *gin.Context, desc Descriptor, opts ...Option) {
 
 const (
 	CodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	CodeInvalidCursor        ErrorCode = "INVALID_CURSOR"
 	CodeInvalidCredentials   ErrorCode = "INVALID_CREDENTIALS"
 	CodeAuthMissingToken     ErrorCode = "AUTH_MISSING_TOKEN"
 	CodeAuthInvalidToken     ErrorCode = "AUTH_INVALID_TOKEN"
 	CodeClusterAlreadyExists ErrorCode = "CLUSTER_ALREADY_EXISTS"
 	CodeNodeNotFound         ErrorCode = "NODE_NOT_FOUND"
 	CodeJobNotFound          ErrorCode = "JOB_NOT_FOUND"
-	CodeJobAlreadyRunning    ErrorCode = "JOB_ALREADY_RUNNING"
+	CodeNodeSlotsFull        ErrorCode = "NODE_SLOTS_FULL"
 	CodeJobNotRunning        ErrorCode = "JOB_NOT_RUNNING"
+	CodeArtifactNotFound     ErrorCode = "ARTIFACT_NOT_FOUND"
+	CodeArtifactTooLarge     ErrorCode = "ARTIFACT_TOO_LARGE"
+	CodeQueuedJobNotFound    ErrorCode = "QUEUED_JOB_NOT_FOUND"
+	CodeQueuedJobNotPending  ErrorCode = "QUEUED_JOB_NOT_PENDING"
+	CodeQueueLeaseLost       ErrorCode = "QUEUE_LEASE_LOST"
 	CodeInternalError        ErrorCode = "INTERNAL_ERROR"
 )
 
@@ -102,6 +108,11 @@ var (
 		Status:  http.StatusBadRequest,
 		Message: "リクエスト内容が正しくありません。",
 	}
+	InvalidCursor = Descriptor{
+		Code:    CodeInvalidCursor,
+		Status:  http.StatusBadRequest,
+		Message: "カーソルが正しくないか、並び順と一致しません。",
+	}
 	InvalidCredentials = Descriptor{
 		Code:    CodeInvalidCredentials,
 		Status:  http.StatusUnauthorized,
@@ -132,16 +143,41 @@ var (
 		Status:  http.StatusNotFound,
 		Message: "ジョブが見つかりません。",
 	}
-	JobAlreadyRunning = Descriptor{
-		Code:    CodeJobAlreadyRunning,
+	NodeSlotsFull = Descriptor{
+		Code:    CodeNodeSlotsFull,
 		Status:  http.StatusConflict,
-		Message: "このノードではすでにジョブが実行中です。",
+		Message: "このノードの実行スロットはすべて使用中です。",
 	}
 	JobNotRunning = Descriptor{
 		Code:    CodeJobNotRunning,
 		Status:  http.StatusConflict,
 		Message: "実行中のジョブがありません。",
 	}
+	ArtifactNotFound = Descriptor{
+		Code:    CodeArtifactNotFound,
+		Status:  http.StatusNotFound,
+		Message: "アーティファクトが見つかりません。",
+	}
+	ArtifactTooLarge = Descriptor{
+		Code:    CodeArtifactTooLarge,
+		Status:  http.StatusRequestEntityTooLarge,
+		Message: "アーティファクトのサイズが上限を超えています。",
+	}
+	QueuedJobNotFound = Descriptor{
+		Code:    CodeQueuedJobNotFound,
+		Status:  http.StatusNotFound,
+		Message: "キューのジョブが見つかりません。",
+	}
+	QueuedJobNotPending = Descriptor{
+		Code:    CodeQueuedJobNotPending,
+		Status:  http.StatusConflict,
+		Message: "このジョブは既に開始または終了しています。",
+	}
+	QueueLeaseLost = Descriptor{
+		Code:    CodeQueueLeaseLost,
+		Status:  http.StatusConflict,
+		Message: "キューのジョブの貸し出しが失効しています。",
+	}
 	Internal = Descriptor{
 		Code:    CodeInternalError,
 		Status:  http.StatusInternalServerError,
@@ -0,0 +1,37 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kanaya/jobboard-hub/internal/config"
+)
+
+var (
+	ErrNotFound = errors.New("blob not found")
+	// ErrSizeMismatch は Put に渡したサイズと実際に読めたバイト数が食い違ったことを表す。
+	ErrSizeMismatch = errors.New("blob size does not match")
+)
+
+// Store はアーティファクトの中身を保存する。key は "/" 区切りで、Hub が生成したものだけを渡す。
+type Store interface {
+	// Put は r から size バイトを読み、key に保存する。同じ key があれば置き換える。
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get は key の中身を返す。呼び出し側で Close すること。
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete は key を削除する。存在しなくてもエラーにしない。
+	Delete(ctx context.Context, key string) error
+}
+
+func New(cfg config.BlobConfig) (Store, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +12,12 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Auth     AuthConfig
+	Reaper   ReaperConfig
+	Blob     BlobConfig
+	Queue    QueueConfig
+	Mail     MailConfig
+	Anomaly  AnomalyConfig
+	Events   EventsConfig
 }
 
 type ServerConfig struct {
@@ -30,6 +38,71 @@ type AuthConfig struct {
 	TokenTTL  time.Duration
 }
 
+type ReaperConfig struct {
+	Interval             time.Duration
+	HeartbeatTimeout     time.Duration
+	IdempotencyRetention time.Duration
+	// LostRequeueGrace は lost になったキューのジョブを再び貸し出すまでの猶予。ハートビートが遅れただけの実行から finish が届くのを待つ
+	LostRequeueGrace time.Duration
+}
+
+type BlobConfig struct {
+	Backend         string
+	LocalDir        string
+	S3              S3Config
+	MaxArtifactSize int64
+}
+
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool
+}
+
+type QueueConfig struct {
+	// LeaseTTL は agent に貸し出したジョブが start されないまま戻されるまでの時間
+	LeaseTTL time.Duration
+}
+
+// MailConfig はジョブ終了時のメール通知の設定。Host が空なら送らない。宛先はクラスターごとに API で設定する。
+type MailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// TLS は starttls、tls（接続直後から TLS）、none（平文）のいずれか
+	TLS string
+	// Statuses は通知するジョブの終了ステータス
+	Statuses []string
+	// Anomalies は所要時間が外れたジョブも通知するか
+	Anomalies bool
+	Timeout   time.Duration
+}
+
+// AnomalyConfig は所要時間の外れ値検知の設定。ベースラインはクラスタとタグごとに持つ。
+type AnomalyConfig struct {
+	// Window はベースラインの計算に使う直近の completed のジョブの件数
+	Window int32
+	// MinSamples はこの件数に満たないベースラインでは判定しない
+	MinSamples int32
+	// Threshold は slow / fast と判定する robust z-score の絶対値
+	Threshold float64
+	// RunningFactor は実行中のジョブを slow とする、中央値に対する経過時間の倍率。0 以下なら判定しない
+	RunningFactor float64
+}
+
+// EventsConfig は GET /api/events の設定。
+type EventsConfig struct {
+	// BufferSize は Last-Event-ID での再開のために保持する直近のイベントの件数（全クラスター合計）
+	BufferSize int
+	// KeepAlive はイベントがないときにコメント行を送る間隔。プロキシにアイドル接続として切られないようにする
+	KeepAlive time.Duration
+}
+
 func Load() *Config {
 	tokenTTL := parseDurationEnv("AUTH_TOKEN_TTL", 15*time.Minute)
 
@@ -49,6 +122,49 @@ func Load() *Config {
 			JWTSecret: getEnv("AUTH_JWT_SECRET", "dev-secret-change-me"),
 			TokenTTL:  tokenTTL,
 		},
+		Reaper: ReaperConfig{
+			Interval:             parseDurationEnv("REAPER_INTERVAL", 30*time.Second),
+			HeartbeatTimeout:     parseDurationEnv("HEARTBEAT_TIMEOUT", 3*time.Minute),
+			IdempotencyRetention: parseDurationEnv("IDEMPOTENCY_RETENTION", 7*24*time.Hour),
+			LostRequeueGrace:     parseDurationEnv("QUEUE_LOST_REQUEUE_GRACE", 10*time.Minute),
+		},
+		Blob: BlobConfig{
+			Backend:  getEnv("BLOB_BACKEND", "local"),
+			LocalDir: getEnv("BLOB_LOCAL_DIR", "data/blobs"),
+			S3: S3Config{
+				Endpoint:        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:          getEnv("S3_REGION", "us-east-1"),
+				Bucket:          getEnv("S3_BUCKET", ""),
+				AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+				ForcePathStyle:  parseBoolEnv("S3_FORCE_PATH_STYLE", false),
+			},
+			MaxArtifactSize: parseInt64Env("ARTIFACT_MAX_SIZE", 5<<30),
+		},
+		Queue: QueueConfig{
+			LeaseTTL: parseDurationEnv("QUEUE_LEASE_TTL", 2*time.Minute),
+		},
+		Mail: MailConfig{
+			Host:      getEnv("SMTP_HOST", ""),
+			Port:      getEnv("SMTP_PORT", "587"),
+			Username:  getEnv("SMTP_USERNAME", ""),
+			Password:  getEnv("SMTP_PASSWORD", ""),
+			From:      getEnv("SMTP_FROM", ""),
+			TLS:       getEnv("SMTP_TLS", "starttls"),
+			Statuses:  parseListEnv("NOTIFY_EMAIL_STATUSES", "failed,timed_out,cancelled,lost"),
+			Anomalies: parseBoolEnv("NOTIFY_EMAIL_ANOMALIES", true),
+			Timeout:   parseDurationEnv("SMTP_TIMEOUT", 30*time.Second),
+		},
+		Anomaly: AnomalyConfig{
+			Window:        int32(parseInt64Env("ANOMALY_WINDOW", 30)),
+			MinSamples:    int32(parseInt64Env("ANOMALY_MIN_SAMPLES", 5)),
+			Threshold:     parseFloatEnv("ANOMALY_THRESHOLD", 3.5),
+			RunningFactor: parseFloatEnv("ANOMALY_RUNNING_FACTOR", 3),
+		},
+		Events: EventsConfig{
+			BufferSize: int(parseInt64Env("EVENTS_BUFFER_SIZE", 1000)),
+			KeepAlive:  parseDurationEnv("EVENTS_KEEPALIVE", 15*time.Second),
+		},
 	}
 }
 
@@ -72,3 +188,40 @@ func parseDurationEnv(key string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+func parseListEnv(key, fallback string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseBoolEnv(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func parseInt64Env(key string, fallback int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func parseFloatEnv(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
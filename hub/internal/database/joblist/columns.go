@@ -0,0 +1,75 @@
+package joblist
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+)
+
+// jobColumns は一覧で SELECT する jobs の列と、読み込む repo.Job のフィールド。
+// SELECT の列と Scan の引数はどちらもここから作るので、列を足すときはここだけを直す。
+var jobColumns = []struct {
+	name  string
+	field func(*repo.Job) any
+}{
+	{"id", func(j *repo.Job) any { return &j.ID }},
+	{"cluster_id", func(j *repo.Job) any { return &j.ClusterID }},
+	{"node_id", func(j *repo.Job) any { return &j.NodeID }},
+	{"started_at", func(j *repo.Job) any { return &j.StartedAt }},
+	{"finished_at", func(j *repo.Job) any { return &j.FinishedAt }},
+	{"duration_hours", func(j *repo.Job) any { return &j.DurationHours }},
+	{"status", func(j *repo.Job) any { return &j.Status }},
+	{"tag", func(j *repo.Job) any { return &j.Tag }},
+	{"error_text", func(j *repo.Job) any { return &j.ErrorText }},
+	{"last_heartbeat_at", func(j *repo.Job) any { return &j.LastHeartbeatAt }},
+	{"exit_code", func(j *repo.Job) any { return &j.ExitCode }},
+	{"command", func(j *repo.Job) any { return &j.Command }},
+	{"working_dir", func(j *repo.Job) any { return &j.WorkingDir }},
+	{"termination_signal", func(j *repo.Job) any { return &j.TerminationSignal }},
+	{"cpu_user_seconds", func(j *repo.Job) any { return &j.CpuUserSeconds }},
+	{"cpu_system_seconds", func(j *repo.Job) any { return &j.CpuSystemSeconds }},
+	{"max_rss_bytes", func(j *repo.Job) any { return &j.MaxRssBytes }},
+	{"io_read_bytes", func(j *repo.Job) any { return &j.IoReadBytes }},
+	{"io_write_bytes", func(j *repo.Job) any { return &j.IoWriteBytes }},
+	{"run_id", func(j *repo.Job) any { return &j.RunID }},
+	{"labels", func(j *repo.Job) any { return &j.Labels }},
+	{"progress_percent", func(j *repo.Job) any { return &j.ProgressPercent }},
+	{"progress_step", func(j *repo.Job) any { return &j.ProgressStep }},
+	{"progress_total", func(j *repo.Job) any { return &j.ProgressTotal }},
+	{"progress_phase", func(j *repo.Job) any { return &j.ProgressPhase }},
+	{"progress_updated_at", func(j *repo.Job) any { return &j.ProgressUpdatedAt }},
+	{"cancel_requested_at", func(j *repo.Job) any { return &j.CancelRequestedAt }},
+	{"cancel_requested_by", func(j *repo.Job) any { return &j.CancelRequestedBy }},
+	{"duration_anomaly", func(j *repo.Job) any { return &j.DurationAnomaly }},
+	{"duration_anomaly_score", func(j *repo.Job) any { return &j.DurationAnomalyScore }},
+	{"duration_baseline_seconds", func(j *repo.Job) any { return &j.DurationBaselineSeconds }},
+	{"duration_anomaly_at", func(j *repo.Job) any { return &j.DurationAnomalyAt }},
+	{"queue_id", func(j *repo.Job) any { return &j.QueueID }},
+}
+
+// selectList は jobColumns をカンマ区切りにしたもの。
+var selectList string
+
+func init() {
+	// jobs に列を足して sqlc を生成し直したのに、ここを直し忘れた場合に気付けるようにする
+	if n := reflect.TypeFor[repo.Job]().NumField(); n != len(jobColumns) {
+		panic(fmt.Sprintf("joblist: repo.Job has %d fields but jobColumns has %d", n, len(jobColumns)))
+	}
+
+	names := make([]string, len(jobColumns))
+	for i, column := range jobColumns {
+		names[i] = column.name
+	}
+	selectList = strings.Join(names, ", ")
+}
+
+// scanTargets は job の各フィールドを jobColumns の順に返す。
+func scanTargets(job *repo.Job) []any {
+	dest := make([]any, len(jobColumns))
+	for i, column := range jobColumns {
+		dest[i] = column.field(job)
+	}
+	return dest
+}
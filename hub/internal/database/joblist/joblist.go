@@ -0,0 +1,239 @@
+// Package joblist はジョブ一覧のクエリ。絞り込み条件と並び順がリクエストごとに変わり、
+// sqlc では表せないため手書きしている。
+package joblist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+)
+
+// Order はジョブ一覧の並び順の 1 列。
+type Order struct {
+	Column Sort
+	Desc   bool
+}
+
+// Cursor は前のページの最後の行。ParseCursor で作る。
+type Cursor struct {
+	keys []any
+	id   int64
+}
+
+// Params はジョブ一覧の条件。ゼロ値のフィールドでは絞り込まない。
+type Params struct {
+	ClusterID     string
+	NodeID        *int64
+	Statuses      []string
+	Tag           *string
+	LabelMatch    []byte
+	LabelExclude  []byte
+	StartedAfter  pgtype.Timestamptz
+	StartedBefore pgtype.Timestamptz
+	// MinDurationSeconds / MaxDurationSeconds を指定すると、実行中のジョブは含まれない
+	MinDurationSeconds *float64
+	MaxDurationSeconds *float64
+	// Order の後ろには、最後の列と同じ向きで id が加わる
+	Order []Order
+	After *Cursor
+	Limit int32
+}
+
+type Row struct {
+	Job repo.Job
+	// SortKeys は Order の各列の値を文字列にしたもの。次のページのカーソルに使う
+	SortKeys []string
+}
+
+// Lister はジョブ一覧のクエリ。
+type Lister interface {
+	List(ctx context.Context, arg Params) ([]Row, error)
+	Count(ctx context.Context, arg Params) (int64, error)
+}
+
+type Queries struct {
+	db repo.DBTX
+}
+
+func New(db repo.DBTX) *Queries {
+	return &Queries{db: db}
+}
+
+var _ Lister = (*Queries)(nil)
+
+func (arg Params) where() ([]string, []any) {
+	args := []any{arg.ClusterID}
+	conds := []string{"cluster_id = $1"}
+	add := func(cond string, value any) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if arg.NodeID != nil {
+		add("node_id = $%d", *arg.NodeID)
+	}
+	if len(arg.Statuses) > 0 {
+		add("status = ANY($%d::text[])", arg.Statuses)
+	}
+	if arg.Tag != nil {
+		add("tag = $%d", *arg.Tag)
+	}
+	if len(arg.LabelMatch) > 0 {
+		add("labels @> $%d::jsonb", arg.LabelMatch)
+	}
+	if len(arg.LabelExclude) > 0 {
+		add(`NOT EXISTS (
+    SELECT 1 FROM jsonb_array_elements($%d::jsonb) AS excluded(label)
+    WHERE jobs.labels @> excluded.label
+  )`, arg.LabelExclude)
+	}
+	if arg.StartedAfter.Valid {
+		add("started_at >= $%d", arg.StartedAfter)
+	}
+	if arg.StartedBefore.Valid {
+		add("started_at < $%d", arg.StartedBefore)
+	}
+	if arg.MinDurationSeconds != nil {
+		add("duration_hours >= make_interval(secs => $%d::float8)", *arg.MinDurationSeconds)
+	}
+	if arg.MaxDurationSeconds != nil {
+		add("duration_hours <= make_interval(secs => $%d::float8)", *arg.MaxDurationSeconds)
+	}
+	return conds, args
+}
+
+// List は arg の条件に一致するジョブを Order の順に最大 Limit 件返す。
+// After を指定すると、その行より後ろから返す。
+func (q *Queries) List(ctx context.Context, arg Params) ([]Row, error) {
+	if len(arg.Order) == 0 {
+		return nil, errors.New("job order is empty")
+	}
+	if arg.After != nil && len(arg.After.keys) != len(arg.Order) {
+		return nil, errors.New("job cursor does not match order")
+	}
+
+	keys := make([]sortKey, 0, len(arg.Order)+1)
+	descs := make([]bool, 0, len(arg.Order)+1)
+	for _, order := range arg.Order {
+		key, ok := sortKeys[order.Column]
+		if !ok {
+			return nil, fmt.Errorf("unknown job sort %q", order.Column)
+		}
+		keys = append(keys, key)
+		descs = append(descs, order.Desc)
+	}
+	keys = append(keys, sortKeys[SortID])
+	descs = append(descs, arg.Order[len(arg.Order)-1].Desc)
+
+	exprs := make([]string, len(keys))
+	for i, key := range keys {
+		exprs[i] = key.expr
+	}
+
+	conds, args := arg.where()
+	if arg.After != nil {
+		params := make([]string, len(keys))
+		for i, key := range keys {
+			if i < len(arg.After.keys) {
+				args = append(args, arg.After.keys[i])
+			} else {
+				args = append(args, arg.After.id)
+			}
+			params[i] = fmt.Sprintf("$%d::%s", len(args), key.kind.cast())
+		}
+		conds = append(conds, keysetCondition(exprs, params, descs))
+	}
+	args = append(args, arg.Limit)
+
+	selected := make([]string, len(arg.Order))
+	ordered := make([]string, len(exprs))
+	for i, expr := range exprs {
+		if i < len(selected) {
+			selected[i] = ", " + expr
+		}
+		ordered[i] = expr + " " + direction(descs[i])
+	}
+
+	query := fmt.Sprintf(`SELECT %s%s FROM jobs
+WHERE %s
+ORDER BY %s
+LIMIT $%d`, selectList, strings.Join(selected, ""), strings.Join(conds, "\n  AND "), strings.Join(ordered, ", "), len(args))
+
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Row{}
+	for rows.Next() {
+		var i Row
+		values := make([]sortValue, len(arg.Order))
+		dest := scanTargets(&i.Job)
+		for k := range values {
+			values[k] = keys[k].kind.newValue()
+			dest = append(dest, values[k].target())
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		i.SortKeys = make([]string, len(values))
+		for k, value := range values {
+			i.SortKeys[k] = value.String()
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// keysetCondition は exprs の並びで params の行より後ろにある行の条件を返す。
+// 向きがすべて同じなら行値の比較にして、インデックスを使えるようにする。
+func keysetCondition(exprs, params []string, descs []bool) string {
+	same := true
+	for _, desc := range descs {
+		same = same && desc == descs[0]
+	}
+	if same {
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), comparison(descs[0]), strings.Join(params, ", "))
+	}
+
+	alternatives := make([]string, len(exprs))
+	for i := range exprs {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, exprs[j]+" = "+params[j])
+		}
+		terms = append(terms, exprs[i]+" "+comparison(descs[i])+" "+params[i])
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func comparison(desc bool) string {
+	if desc {
+		return "<"
+	}
+	return ">"
+}
+
+// Count は arg の条件に一致するジョブの件数を返す。Order / After / Limit は使わない。
+func (q *Queries) Count(ctx context.Context, arg Params) (int64, error) {
+	conds, args := arg.where()
+	row := q.db.QueryRow(ctx, "SELECT COUNT(*) FROM jobs\nWHERE "+strings.Join(conds, "\n  AND "), args...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
@@ -0,0 +1,175 @@
+package joblist
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Sort はジョブ一覧を並べる列。
+type Sort string
+
+const (
+	SortStartedAt  Sort = "started_at"
+	SortFinishedAt Sort = "finished_at"
+	SortDuration   Sort = "duration"
+	SortStatus     Sort = "status"
+	SortNodeID     Sort = "node_id"
+	SortTag        Sort = "tag"
+	SortID         Sort = "id"
+)
+
+type sortKey struct {
+	// expr は並べる式。NULL は行値の比較に使えないため、実行中のジョブは終了時刻・所要時間が最大、
+	// タグなしは空文字として扱う
+	expr string
+	kind sortKind
+}
+
+var sortKeys = map[Sort]sortKey{
+	SortStartedAt:  {expr: "started_at", kind: kindTime},
+	SortFinishedAt: {expr: "COALESCE(finished_at, 'infinity'::timestamptz)", kind: kindTime},
+	SortDuration:   {expr: "COALESCE(EXTRACT(EPOCH FROM duration_hours)::float8, 'Infinity'::float8)", kind: kindFloat},
+	SortStatus:     {expr: "status", kind: kindText},
+	SortNodeID:     {expr: "node_id", kind: kindInt},
+	SortTag:        {expr: "COALESCE(tag, '')", kind: kindText},
+	SortID:         {expr: "id", kind: kindInt},
+}
+
+// Valid は s が並べられる列かを返す。
+func (s Sort) Valid() bool {
+	_, ok := sortKeys[s]
+	return ok
+}
+
+var errInvalidSortKey = errors.New("invalid job sort key")
+
+// ParseCursor は Row.SortKeys と最後の行の ID からカーソルを作る。
+// キーの数や値が order の列と合わなければエラーを返す。クライアントが書き換えたカーソルもここで弾く。
+func ParseCursor(order []Order, keys []string, id int64) (*Cursor, error) {
+	if len(keys) != len(order) {
+		return nil, errInvalidSortKey
+	}
+	cursor := &Cursor{keys: make([]any, len(keys)), id: id}
+	for i, raw := range keys {
+		key, ok := sortKeys[order[i].Column]
+		if !ok {
+			return nil, errInvalidSortKey
+		}
+		value, err := key.kind.parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		cursor.keys[i] = value
+	}
+	return cursor, nil
+}
+
+// sortKind は並べる式の型。カーソルの文字列との変換と、SQL のパラメータのキャストを決める。
+type sortKind int
+
+const (
+	kindTime sortKind = iota
+	kindFloat
+	kindInt
+	kindText
+)
+
+func (k sortKind) cast() string {
+	switch k {
+	case kindTime:
+		return "timestamptz"
+	case kindFloat:
+		return "float8"
+	case kindInt:
+		return "bigint"
+	default:
+		return "text"
+	}
+}
+
+func (k sortKind) newValue() sortValue {
+	switch k {
+	case kindTime:
+		return &timeValue{}
+	case kindFloat:
+		return new(floatValue)
+	case kindInt:
+		return new(intValue)
+	default:
+		return new(textValue)
+	}
+}
+
+// parse はカーソルの文字列を SQL のパラメータに戻す。
+func (k sortKind) parse(raw string) (any, error) {
+	switch k {
+	case kindTime:
+		if raw == "infinity" {
+			return pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, errInvalidSortKey
+		}
+		return pgtype.Timestamptz{Time: t, Valid: true}, nil
+	case kindFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) {
+			return nil, errInvalidSortKey
+		}
+		return v, nil
+	case kindInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errInvalidSortKey
+		}
+		return v, nil
+	default:
+		// Postgres の text は NUL を含められない
+		if !utf8.ValidString(raw) || strings.ContainsRune(raw, 0) {
+			return nil, errInvalidSortKey
+		}
+		return raw, nil
+	}
+}
+
+// sortValue は並べる式の値を Scan で受け取り、カーソルの文字列にする。
+type sortValue interface {
+	target() any
+	String() string
+}
+
+type timeValue struct{ pgtype.Timestamptz }
+
+func (v *timeValue) target() any { return &v.Timestamptz }
+
+func (v *timeValue) String() string {
+	if v.InfinityModifier == pgtype.Infinity {
+		return "infinity"
+	}
+	return v.Time.UTC().Format(time.RFC3339Nano)
+}
+
+type floatValue float64
+
+func (v *floatValue) target() any { return (*float64)(v) }
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type intValue int64
+
+func (v *intValue) target() any { return (*int64)(v) }
+
+func (v *intValue) String() string { return strconv.FormatInt(int64(*v), 10) }
+
+type textValue string
+
+func (v *textValue) target() any { return (*string)(v) }
+
+func (v *textValue) String() string { return string(*v) }
@@ -16,6 +16,12 @@ SET password_hash = $2
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateClusterNotifyEmails :one
+UPDATE clusters
+SET notify_emails = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteCluster :exec
 DELETE FROM clusters
 WHERE id = $1;
@@ -0,0 +1,34 @@
+-- name: UpsertJobArtifact :one
+INSERT INTO job_artifacts (
+    job_id,
+    name,
+    size_bytes,
+    sha256,
+    storage_key
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (job_id, name) DO UPDATE
+SET size_bytes = EXCLUDED.size_bytes,
+    sha256 = EXCLUDED.sha256,
+    storage_key = EXCLUDED.storage_key,
+    created_at = NOW()
+RETURNING *;
+
+-- name: GetJobArtifactByJobAndName :one
+SELECT * FROM job_artifacts
+WHERE job_id = $1 AND name = $2;
+
+-- name: GetJobArtifactByCluster :one
+SELECT a.* FROM job_artifacts a
+JOIN jobs j ON j.id = a.job_id
+WHERE a.id = $1 AND a.job_id = $2 AND j.cluster_id = $3;
+
+-- name: ListJobArtifactsByJob :many
+SELECT * FROM job_artifacts
+WHERE job_id = $1
+ORDER BY name ASC;
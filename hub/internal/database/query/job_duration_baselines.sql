@@ -0,0 +1,42 @@
+-- name: GetJobDurationBaseline :one
+SELECT * FROM job_duration_baselines
+WHERE cluster_id = $1 AND tag = $2;
+
+-- name: RefreshJobDurationBaseline :exec
+-- 同じタグで最近 completed になった window_size 件の所要時間から、中央値と MAD（中央絶対偏差）を計算し直す
+WITH recent AS (
+    SELECT EXTRACT(EPOCH FROM COALESCE(duration_hours, finished_at - started_at))::float8 AS seconds
+    FROM jobs
+    WHERE cluster_id = sqlc.arg(cluster_id)
+      AND tag = sqlc.arg(tag)::text
+      AND status = 'completed'
+      AND finished_at IS NOT NULL
+    ORDER BY finished_at DESC, id DESC
+    LIMIT sqlc.arg(window_size)
+),
+median AS (
+    SELECT COUNT(*)::int AS sample_count,
+           percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds) AS median_seconds
+    FROM recent
+)
+INSERT INTO job_duration_baselines (
+    cluster_id,
+    tag,
+    sample_count,
+    median_seconds,
+    mad_seconds,
+    updated_at
+)
+SELECT sqlc.arg(cluster_id),
+       sqlc.arg(tag)::text,
+       median.sample_count,
+       median.median_seconds,
+       (SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY abs(recent.seconds - median.median_seconds)) FROM recent),
+       NOW()
+FROM median
+WHERE median.sample_count > 0
+ON CONFLICT (cluster_id, tag) DO UPDATE
+SET sample_count = EXCLUDED.sample_count,
+    median_seconds = EXCLUDED.median_seconds,
+    mad_seconds = EXCLUDED.mad_seconds,
+    updated_at = EXCLUDED.updated_at;
@@ -0,0 +1,28 @@
+-- name: CreateJobLogChunk :exec
+INSERT INTO job_logs (
+    job_id,
+    stream,
+    seq,
+    byte_offset,
+    data
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (job_id, stream, seq) DO NOTHING;
+
+-- name: ListJobLogChunksInRange :many
+SELECT * FROM job_logs
+WHERE job_id = sqlc.arg(job_id)
+  AND stream = sqlc.arg(stream)
+  AND byte_offset + octet_length(data) > sqlc.arg(range_start)::bigint
+  AND byte_offset < sqlc.arg(range_end)::bigint
+ORDER BY byte_offset ASC, seq ASC;
+
+-- name: GetJobLogSize :one
+SELECT COALESCE(MAX(byte_offset + octet_length(data)), 0)::bigint AS size
+FROM job_logs
+WHERE job_id = $1 AND stream = $2;
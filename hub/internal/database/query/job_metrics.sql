@@ -0,0 +1,54 @@
+-- name: CreateJobMetrics :exec
+INSERT INTO job_metrics (
+    job_id,
+    key,
+    step,
+    value,
+    recorded_at
+)
+SELECT sqlc.arg(job_id)::bigint, p.key, p.step, p.value, p.recorded_at
+FROM unnest(
+    sqlc.arg(keys)::text[],
+    sqlc.arg(steps)::bigint[],
+    sqlc.arg(metric_values)::float8[],
+    sqlc.arg(recorded_ats)::timestamptz[]
+) AS p(key, step, value, recorded_at)
+ON CONFLICT (job_id, key, step) DO UPDATE
+SET value = EXCLUDED.value,
+    recorded_at = EXCLUDED.recorded_at;
+
+-- name: ListJobMetricKeys :many
+SELECT key,
+       COUNT(*)::bigint AS points,
+       MIN(step)::bigint AS first_step,
+       MAX(step)::bigint AS last_step,
+       (array_agg(value ORDER BY step DESC))[1]::float8 AS last_value,
+       MAX(recorded_at)::timestamptz AS last_recorded_at
+FROM job_metrics
+WHERE job_id = $1
+GROUP BY key
+ORDER BY key ASC;
+
+-- name: ListJobMetricPoints :many
+-- 範囲内の点を step 順に buckets 個へ均等に分け、バケットごとに平均・最小・最大を返す。
+-- 点数が buckets 以下ならバケットは 1 点ずつになり、元の系列がそのまま返る。
+WITH series AS (
+    SELECT step,
+           value,
+           recorded_at,
+           ntile(sqlc.arg(buckets)::int) OVER (ORDER BY step) AS bucket
+    FROM job_metrics
+    WHERE job_id = sqlc.arg(job_id)
+      AND key = sqlc.arg(key)
+      AND (sqlc.narg(from_step)::bigint IS NULL OR step >= sqlc.narg(from_step)::bigint)
+      AND (sqlc.narg(to_step)::bigint IS NULL OR step <= sqlc.narg(to_step)::bigint)
+)
+SELECT MAX(step)::bigint AS step,
+       AVG(value)::float8 AS value,
+       MIN(value)::float8 AS min_value,
+       MAX(value)::float8 AS max_value,
+       MAX(recorded_at)::timestamptz AS recorded_at,
+       COUNT(*)::bigint AS points
+FROM series
+GROUP BY bucket
+ORDER BY step ASC;
@@ -0,0 +1,104 @@
+-- name: CreateQueuedJob :one
+INSERT INTO job_queue (
+    cluster_id,
+    command,
+    working_dir,
+    tag,
+    labels,
+    node_id,
+    node_selector,
+    priority,
+    max_attempts
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetQueuedJobByCluster :one
+SELECT * FROM job_queue
+WHERE id = $1 AND cluster_id = $2
+LIMIT 1;
+
+-- name: ListQueuedJobsByCluster :many
+SELECT * FROM job_queue
+WHERE cluster_id = sqlc.arg(cluster_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+ORDER BY id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: CancelQueuedJobByCluster :one
+UPDATE job_queue
+SET status = 'cancelled',
+    leased_node_id = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND cluster_id = $2 AND status IN ('queued', 'leased')
+RETURNING *;
+
+-- name: CountActiveLeasesByNode :one
+SELECT COUNT(*) FROM job_queue
+WHERE leased_node_id = $1 AND status = 'leased' AND lease_expires_at > NOW();
+
+-- name: LeaseQueuedJob :one
+-- 優先度の高い順、同じ優先度なら古い順に 1 件を貸し出す。期限切れのリースも取り直せる
+UPDATE job_queue
+SET status = 'leased',
+    leased_node_id = sqlc.arg(node_id)::bigint,
+    lease_expires_at = sqlc.arg(lease_expires_at),
+    attempts = attempts + 1,
+    updated_at = NOW()
+WHERE id = (
+    SELECT q.id FROM job_queue q
+    WHERE q.cluster_id = sqlc.arg(cluster_id)
+      AND (q.status = 'queued' OR (q.status = 'leased' AND q.lease_expires_at < NOW()))
+      AND q.attempts < q.max_attempts
+      AND (q.node_id IS NULL OR q.node_id = sqlc.arg(node_id))
+      AND sqlc.arg(node_labels)::jsonb @> q.node_selector
+    ORDER BY q.priority DESC, q.id ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: GetLeasedQueuedJobForUpdate :one
+SELECT * FROM job_queue
+WHERE id = $1 AND leased_node_id = $2 AND status = 'leased'
+FOR UPDATE;
+
+-- name: MarkQueuedJobStarted :one
+UPDATE job_queue
+SET status = 'started',
+    job_id = $2,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireQueuedJobLeases :many
+UPDATE job_queue
+SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+    leased_node_id = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE status = 'leased' AND lease_expires_at < NOW()
+RETURNING *;
+
+-- name: RequeueLostQueuedJobs :many
+-- lost_before より前に lost になったジョブは、試行回数が残っていればキューに戻す。
+-- jobs の行もロックするので、同時に届いた finish で lost でなくなったジョブは戻さない
+WITH lost AS (
+    SELECT job_queue.id
+    FROM job_queue
+    JOIN jobs ON jobs.id = job_queue.job_id
+    WHERE job_queue.status = 'started'
+      AND jobs.status = 'lost'
+      AND jobs.finished_at < sqlc.arg(lost_before)::timestamptz
+    FOR UPDATE OF job_queue, jobs
+)
+UPDATE job_queue
+SET status = CASE WHEN job_queue.attempts >= job_queue.max_attempts THEN 'failed' ELSE 'queued' END,
+    leased_node_id = NULL,
+    updated_at = NOW()
+FROM lost
+WHERE job_queue.id = lost.id
+RETURNING job_queue.*;
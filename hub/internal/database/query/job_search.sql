@@ -0,0 +1,51 @@
+-- name: SearchJobs :many
+-- error_text と job_logs のチャンクを全文検索し、ジョブごとに最もよく一致した箇所を rank の高い順に返す。
+-- snippet の一致部分は chr(1) と chr(2) で囲む。
+WITH query AS (
+    SELECT websearch_to_tsquery('simple', sqlc.arg(query)::text) AS q
+),
+hits AS (
+    SELECT jobs.id AS job_id,
+           'error_text'::text AS source,
+           jobs.error_text AS body,
+           ts_rank_cd(to_tsvector('simple', COALESCE(jobs.error_text, '')), query.q, 32) AS rank
+    FROM jobs
+    CROSS JOIN query
+    WHERE jobs.cluster_id = sqlc.arg(cluster_id)
+      AND to_tsvector('simple', COALESCE(jobs.error_text, '')) @@ query.q
+    UNION ALL
+    SELECT job_logs.job_id,
+           job_logs.stream::text,
+           job_logs.data,
+           ts_rank_cd(to_tsvector('simple', job_logs.data), query.q, 32)
+    FROM job_logs
+    JOIN jobs ON jobs.id = job_logs.job_id
+    CROSS JOIN query
+    WHERE jobs.cluster_id = sqlc.arg(cluster_id)
+      AND to_tsvector('simple', job_logs.data) @@ query.q
+),
+best AS (
+    SELECT DISTINCT ON (job_id)
+           job_id,
+           source,
+           body,
+           rank,
+           COUNT(*) OVER (PARTITION BY job_id) AS match_count
+    FROM hits
+    ORDER BY job_id, rank DESC
+),
+top AS (
+    SELECT * FROM best
+    ORDER BY rank DESC, job_id DESC
+    LIMIT sqlc.arg(max_results)
+)
+SELECT sqlc.embed(j),
+       top.source::text AS source,
+       ts_headline('simple', top.body, query.q,
+           'StartSel=' || chr(1) || ', StopSel=' || chr(2) || ', MaxFragments=3, MaxWords=24, MinWords=8, FragmentDelimiter=" ... "')::text AS snippet,
+       top.rank::float8 AS rank,
+       top.match_count::bigint AS match_count
+FROM top
+JOIN jobs j ON j.id = top.job_id
+CROSS JOIN query
+ORDER BY top.rank DESC, top.job_id DESC;
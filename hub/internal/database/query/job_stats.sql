@@ -0,0 +1,84 @@
+-- name: GetJobStats :one
+-- 期間内に開始したジョブの集計。所要時間のパーセンタイル（時間単位）は completed のジョブだけから求める
+SELECT COUNT(*)::bigint AS total,
+       COUNT(*) FILTER (WHERE status = 'running')::bigint AS running,
+       COUNT(*) FILTER (WHERE status = 'completed')::bigint AS completed,
+       COUNT(*) FILTER (WHERE status = 'failed')::bigint AS failed,
+       COUNT(*) FILTER (WHERE status = 'timed_out')::bigint AS timed_out,
+       COUNT(*) FILTER (WHERE status = 'cancelled')::bigint AS cancelled,
+       COUNT(*) FILTER (WHERE status = 'lost')::bigint AS lost,
+       (percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY (EXTRACT(EPOCH FROM duration_hours) / 3600)::float8)
+           FILTER (WHERE status = 'completed'))::float8[] AS duration_percentiles
+FROM jobs
+WHERE cluster_id = sqlc.arg(cluster_id)
+  AND started_at >= sqlc.arg(window_start)::timestamptz
+  AND started_at < sqlc.arg(window_end)::timestamptz;
+
+-- name: ListJobStatsByTag :many
+-- GetJobStats をタグごとに集計する。件数の多い順に max_groups 件まで。タグなしは tag が NULL
+SELECT tag,
+       COUNT(*)::bigint AS total,
+       COUNT(*) FILTER (WHERE status = 'running')::bigint AS running,
+       COUNT(*) FILTER (WHERE status = 'completed')::bigint AS completed,
+       COUNT(*) FILTER (WHERE status = 'failed')::bigint AS failed,
+       COUNT(*) FILTER (WHERE status = 'timed_out')::bigint AS timed_out,
+       COUNT(*) FILTER (WHERE status = 'cancelled')::bigint AS cancelled,
+       COUNT(*) FILTER (WHERE status = 'lost')::bigint AS lost,
+       (percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY (EXTRACT(EPOCH FROM duration_hours) / 3600)::float8)
+           FILTER (WHERE status = 'completed'))::float8[] AS duration_percentiles
+FROM jobs
+WHERE cluster_id = sqlc.arg(cluster_id)
+  AND started_at >= sqlc.arg(window_start)::timestamptz
+  AND started_at < sqlc.arg(window_end)::timestamptz
+GROUP BY tag
+ORDER BY COUNT(*) DESC, tag ASC NULLS LAST
+LIMIT sqlc.arg(max_groups);
+
+-- name: ListJobStatsByNode :many
+-- GetJobStats をノードごとに集計する
+SELECT nodes.id AS node_id,
+       nodes.node_name,
+       COUNT(*)::bigint AS total,
+       COUNT(*) FILTER (WHERE jobs.status = 'running')::bigint AS running,
+       COUNT(*) FILTER (WHERE jobs.status = 'completed')::bigint AS completed,
+       COUNT(*) FILTER (WHERE jobs.status = 'failed')::bigint AS failed,
+       COUNT(*) FILTER (WHERE jobs.status = 'timed_out')::bigint AS timed_out,
+       COUNT(*) FILTER (WHERE jobs.status = 'cancelled')::bigint AS cancelled,
+       COUNT(*) FILTER (WHERE jobs.status = 'lost')::bigint AS lost,
+       (percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY (EXTRACT(EPOCH FROM jobs.duration_hours) / 3600)::float8)
+           FILTER (WHERE jobs.status = 'completed'))::float8[] AS duration_percentiles
+FROM jobs
+JOIN nodes ON nodes.id = jobs.node_id
+WHERE jobs.cluster_id = sqlc.arg(cluster_id)
+  AND jobs.started_at >= sqlc.arg(window_start)::timestamptz
+  AND jobs.started_at < sqlc.arg(window_end)::timestamptz
+GROUP BY nodes.id, nodes.node_name
+ORDER BY COUNT(*) DESC, nodes.id ASC;
+
+-- name: ListDailyJobStats :many
+-- GetJobStats を time_zone の日ごとに集計する。ジョブのない日も 0 件の行として返す
+WITH days AS (
+    SELECT generate_series(
+        date_trunc('day', sqlc.arg(window_start)::timestamptz AT TIME ZONE sqlc.arg(time_zone)::text),
+        date_trunc('day', (sqlc.arg(window_end)::timestamptz - interval '1 microsecond') AT TIME ZONE sqlc.arg(time_zone)::text),
+        interval '1 day'
+    ) AS day
+)
+SELECT days.day::date AS day,
+       COUNT(jobs.id)::bigint AS total,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'running')::bigint AS running,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'completed')::bigint AS completed,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'failed')::bigint AS failed,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'timed_out')::bigint AS timed_out,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'cancelled')::bigint AS cancelled,
+       COUNT(jobs.id) FILTER (WHERE jobs.status = 'lost')::bigint AS lost,
+       (percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY (EXTRACT(EPOCH FROM jobs.duration_hours) / 3600)::float8)
+           FILTER (WHERE jobs.status = 'completed'))::float8[] AS duration_percentiles
+FROM days
+LEFT JOIN jobs
+    ON jobs.cluster_id = sqlc.arg(cluster_id)
+   AND jobs.started_at >= sqlc.arg(window_start)::timestamptz
+   AND jobs.started_at < sqlc.arg(window_end)::timestamptz
+   AND date_trunc('day', jobs.started_at AT TIME ZONE sqlc.arg(time_zone)::text) = days.day
+GROUP BY days.day
+ORDER BY days.day ASC;
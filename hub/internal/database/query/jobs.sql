@@ -2,15 +2,37 @@
 SELECT * FROM jobs
 WHERE cluster_id = $1 AND id = $2 LIMIT 1;
 
--- name: ListJobsByCluster :many
+-- name: GetJobByNodeAndJobID :one
 SELECT * FROM jobs
-WHERE cluster_id = $1
-ORDER BY started_at DESC, id DESC;
+WHERE node_id = $1 AND id = $2 LIMIT 1;
 
--- name: ListJobsByNode :many
+-- name: GetJobByNodeAndRunID :one
 SELECT * FROM jobs
-WHERE node_id = $1
-ORDER BY started_at DESC, id DESC;
+WHERE node_id = $1 AND run_id = $2 LIMIT 1;
+
+-- name: GetLastFinishedJobByTag :one
+-- 同じタグで最後に終了したジョブ。通知の判定中のジョブ自身は除く
+SELECT * FROM jobs
+WHERE cluster_id = sqlc.arg(cluster_id)
+  AND tag = sqlc.arg(tag)::text
+  AND finished_at IS NOT NULL
+  AND id <> sqlc.arg(exclude_id)
+ORDER BY finished_at DESC, id DESC
+LIMIT 1;
+
+-- name: CountActiveJobsByNode :one
+SELECT COUNT(*) FROM jobs
+WHERE node_id = $1 AND finished_at IS NULL;
+
+-- name: ListActiveJobsByCluster :many
+SELECT id, node_id FROM jobs
+WHERE cluster_id = $1 AND finished_at IS NULL
+ORDER BY node_id, id;
+
+-- name: ListActiveJobIDsByNode :many
+SELECT id FROM jobs
+WHERE cluster_id = $1 AND node_id = $2 AND finished_at IS NULL
+ORDER BY id;
 
 -- name: CreateJob :one
 INSERT INTO jobs (
@@ -18,13 +40,25 @@ INSERT INTO jobs (
     node_id,
     started_at,
     status,
-    tag
+    tag,
+    last_heartbeat_at,
+    command,
+    working_dir,
+    run_id,
+    labels,
+    queue_id
 ) VALUES (
     $1,
     $2,
     COALESCE($3, NOW()),
     COALESCE($4, 'running'),
-    $5
+    $5,
+    NOW(),
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 )
 RETURNING *;
 
@@ -35,6 +69,77 @@ SET started_at  = COALESCE($2, started_at),
     status      = COALESCE($4, status),
     tag         = COALESCE($5, tag),
     duration_hours = COALESCE($6, duration_hours),
-    error_text = $7
+    error_text = $7,
+    exit_code = COALESCE($8, exit_code),
+    termination_signal = COALESCE($9, termination_signal),
+    cpu_user_seconds = COALESCE($10, cpu_user_seconds),
+    cpu_system_seconds = COALESCE($11, cpu_system_seconds),
+    max_rss_bytes = COALESCE($12, max_rss_bytes),
+    io_read_bytes = COALESCE($13, io_read_bytes),
+    io_write_bytes = COALESCE($14, io_write_bytes)
 WHERE id = $1
 RETURNING *;
+
+-- name: UpdateJobProgress :execrows
+UPDATE jobs
+SET progress_percent = $3,
+    progress_step = $4,
+    progress_total = $5,
+    progress_phase = $6,
+    progress_updated_at = $7
+WHERE id = $1
+  AND node_id = $2
+  AND finished_at IS NULL
+  AND (progress_updated_at IS NULL OR progress_updated_at <= $7);
+
+-- name: RecordJobHeartbeat :one
+UPDATE jobs
+SET last_heartbeat_at = GREATEST(last_heartbeat_at, LEAST(COALESCE(sqlc.narg(sent_at)::timestamptz, NOW()), NOW()))
+WHERE id = sqlc.arg(id) AND node_id = sqlc.arg(node_id) AND finished_at IS NULL
+RETURNING *;
+
+-- name: RequestJobCancel :one
+-- 取り消し要求済みなら最初の要求者と時刻を残す
+UPDATE jobs
+SET cancel_requested_at = COALESCE(cancel_requested_at, NOW()),
+    cancel_requested_by = COALESCE(cancel_requested_by, sqlc.arg(requested_by)::text)
+WHERE cluster_id = sqlc.arg(cluster_id) AND id = sqlc.arg(id) AND finished_at IS NULL
+RETURNING *;
+
+-- name: MarkStaleJobsLost :many
+UPDATE jobs
+SET status = 'lost',
+    finished_at = NOW(),
+    duration_hours = NOW() - started_at,
+    error_text = 'lost: no heartbeat received since ' || to_char(last_heartbeat_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+WHERE finished_at IS NULL
+  AND last_heartbeat_at < sqlc.arg(stale_before)::timestamptz
+RETURNING *;
+
+-- name: SetJobDurationAnomaly :one
+-- 終了したジョブの所要時間をベースラインと比べた結果を記録する。外れていなければ anomaly は NULL
+UPDATE jobs
+SET duration_anomaly = sqlc.narg(anomaly),
+    duration_anomaly_score = sqlc.narg(score),
+    duration_baseline_seconds = sqlc.arg(baseline_seconds)::float8,
+    duration_anomaly_at = CASE
+        WHEN sqlc.narg(anomaly)::text IS NULL THEN NULL
+        ELSE COALESCE(duration_anomaly_at, NOW())
+    END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: FlagOverrunningJobs :many
+-- 実行中のまま、ベースラインの中央値の factor 倍を超えたジョブを slow にする。既に印を付けたジョブは返さない
+UPDATE jobs
+SET duration_anomaly = 'slow',
+    duration_baseline_seconds = b.median_seconds,
+    duration_anomaly_at = NOW()
+FROM job_duration_baselines b
+WHERE jobs.cluster_id = b.cluster_id
+  AND jobs.tag = b.tag
+  AND jobs.finished_at IS NULL
+  AND jobs.duration_anomaly IS NULL
+  AND b.sample_count >= sqlc.arg(min_samples)::int
+  AND NOW() - jobs.started_at > make_interval(secs => b.median_seconds * sqlc.arg(factor)::float8)
+RETURNING jobs.*;
@@ -1,29 +1,42 @@
 -- name: GetNodeByNodeTokenHash :one
-SELECT id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 FROM nodes
 WHERE node_token_hash = $1
 LIMIT 1;
 
+-- name: GetNodeByCluster :one
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
+FROM nodes
+WHERE id = $1 AND cluster_id = $2
+LIMIT 1;
+
+-- name: GetNodeForUpdate :one
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
+FROM nodes
+WHERE id = $1
+FOR UPDATE;
+
 -- name: ListNodesByCluster :many
-SELECT id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 FROM nodes
 WHERE cluster_id = $1
 ORDER BY node_name ASC;
 
 -- name: CreateNode :one
 INSERT INTO nodes (
-  cluster_id, node_name, node_token_hash
+  cluster_id, node_name, node_token_hash, slots, labels
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
-RETURNING id, cluster_id, node_name, node_token_hash, current_job_id, created_at;
+RETURNING id, cluster_id, node_name, node_token_hash, created_at, slots, labels;
 
--- name: UpdateNodeCurrentJob :one
+-- name: UpdateNodeByCluster :one
 UPDATE nodes
-SET current_job_id = $2
-WHERE id = $1
-RETURNING id, cluster_id, node_name, node_token_hash, current_job_id, created_at;
+SET slots = COALESCE(sqlc.narg(slots), slots),
+    labels = COALESCE(sqlc.narg(labels)::jsonb, labels)
+WHERE id = sqlc.arg(id) AND cluster_id = sqlc.arg(cluster_id)
+RETURNING id, cluster_id, node_name, node_token_hash, created_at, slots, labels;
 
 -- name: DeleteNodeByCluster :execrows
 DELETE FROM nodes
-WHERE id = $1 AND cluster_id = $2;
\ No newline at end of file
+WHERE id = $1 AND cluster_id = $2;
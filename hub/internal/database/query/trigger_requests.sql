@@ -0,0 +1,22 @@
+-- name: GetTriggerRequest :one
+SELECT * FROM trigger_requests
+WHERE node_id = $1 AND idempotency_key = $2
+LIMIT 1;
+
+-- name: CreateTriggerRequest :exec
+INSERT INTO trigger_requests (
+    node_id,
+    idempotency_key,
+    status_code,
+    response
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (node_id, idempotency_key) DO NOTHING;
+
+-- name: DeleteTriggerRequestsBefore :execrows
+DELETE FROM trigger_requests
+WHERE created_at < $1;
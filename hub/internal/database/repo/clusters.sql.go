@@ -15,7 +15,7 @@ INSERT INTO clusters (
 ) VALUES (
   $1, $2
 )
-RETURNING id, password_hash, created_at
+RETURNING id, password_hash, created_at, notify_emails
 `
 
 type CreateClusterParams struct {
@@ -26,7 +26,12 @@ type CreateClusterParams struct {
 func (q *Queries) CreateCluster(ctx context.Context, arg CreateClusterParams) (Cluster, error) {
 	row := q.db.QueryRow(ctx, createCluster, arg.ID, arg.PasswordHash)
 	var i Cluster
-	err := row.Scan(&i.ID, &i.PasswordHash, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.NotifyEmails,
+	)
 	return i, err
 }
 
@@ -41,14 +46,19 @@ func (q *Queries) DeleteCluster(ctx context.Context, id string) error {
 }
 
 const getCluster = `-- name: GetCluster :one
-SELECT id, password_hash, created_at FROM clusters
+SELECT id, password_hash, created_at, notify_emails FROM clusters
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetCluster(ctx context.Context, id string) (Cluster, error) {
 	row := q.db.QueryRow(ctx, getCluster, id)
 	var i Cluster
-	err := row.Scan(&i.ID, &i.PasswordHash, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.NotifyEmails,
+	)
 	return i, err
 }
 
@@ -56,7 +66,7 @@ const updateCluster = `-- name: UpdateCluster :one
 UPDATE clusters
 SET password_hash = $2
 WHERE id = $1
-RETURNING id, password_hash, created_at
+RETURNING id, password_hash, created_at, notify_emails
 `
 
 type UpdateClusterParams struct {
@@ -67,6 +77,35 @@ type UpdateClusterParams struct {
 func (q *Queries) UpdateCluster(ctx context.Context, arg UpdateClusterParams) (Cluster, error) {
 	row := q.db.QueryRow(ctx, updateCluster, arg.ID, arg.PasswordHash)
 	var i Cluster
-	err := row.Scan(&i.ID, &i.PasswordHash, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.NotifyEmails,
+	)
+	return i, err
+}
+
+const updateClusterNotifyEmails = `-- name: UpdateClusterNotifyEmails :one
+UPDATE clusters
+SET notify_emails = $2
+WHERE id = $1
+RETURNING id, password_hash, created_at, notify_emails
+`
+
+type UpdateClusterNotifyEmailsParams struct {
+	ID           string   `json:"id"`
+	NotifyEmails []string `json:"notify_emails"`
+}
+
+func (q *Queries) UpdateClusterNotifyEmails(ctx context.Context, arg UpdateClusterNotifyEmailsParams) (Cluster, error) {
+	row := q.db.QueryRow(ctx, updateClusterNotifyEmails, arg.ID, arg.NotifyEmails)
+	var i Cluster
+	err := row.Scan(
+		&i.ID,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.NotifyEmails,
+	)
 	return i, err
 }
@@ -11,29 +11,58 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countActiveJobsByNode = `-- name: CountActiveJobsByNode :one
+SELECT COUNT(*) FROM jobs
+WHERE node_id = $1 AND finished_at IS NULL
+`
+
+func (q *Queries) CountActiveJobsByNode(ctx context.Context, nodeID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countActiveJobsByNode, nodeID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createJob = `-- name: CreateJob :one
 INSERT INTO jobs (
     cluster_id,
     node_id,
     started_at,
     status,
-    tag
+    tag,
+    last_heartbeat_at,
+    command,
+    working_dir,
+    run_id,
+    labels,
+    queue_id
 ) VALUES (
     $1,
     $2,
     COALESCE($3, NOW()),
     COALESCE($4, 'running'),
-    $5
+    $5,
+    NOW(),
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 )
-RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
 `
 
 type CreateJobParams struct {
-	ClusterID string  `json:"cluster_id"`
-	NodeID    int64   `json:"node_id"`
-	Column3   any     `json:"column_3"`
-	Column4   any     `json:"column_4"`
-	Tag       *string `json:"tag"`
+	ClusterID  string      `json:"cluster_id"`
+	NodeID     int64       `json:"node_id"`
+	Column3    any         `json:"column_3"`
+	Column4    any         `json:"column_4"`
+	Tag        *string     `json:"tag"`
+	Command    []string    `json:"command"`
+	WorkingDir *string     `json:"working_dir"`
+	RunID      pgtype.UUID `json:"run_id"`
+	Labels     []byte      `json:"labels"`
+	QueueID    *int64      `json:"queue_id"`
 }
 
 func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
@@ -43,6 +72,11 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, erro
 		arg.Column3,
 		arg.Column4,
 		arg.Tag,
+		arg.Command,
+		arg.WorkingDir,
+		arg.RunID,
+		arg.Labels,
+		arg.QueueID,
 	)
 	var i Job
 	err := row.Scan(
@@ -55,12 +89,111 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, erro
 		&i.Status,
 		&i.Tag,
 		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
 	)
 	return i, err
 }
 
+const flagOverrunningJobs = `-- name: FlagOverrunningJobs :many
+UPDATE jobs
+SET duration_anomaly = 'slow',
+    duration_baseline_seconds = b.median_seconds,
+    duration_anomaly_at = NOW()
+FROM job_duration_baselines b
+WHERE jobs.cluster_id = b.cluster_id
+  AND jobs.tag = b.tag
+  AND jobs.finished_at IS NULL
+  AND jobs.duration_anomaly IS NULL
+  AND b.sample_count >= $1::int
+  AND NOW() - jobs.started_at > make_interval(secs => b.median_seconds * $2::float8)
+RETURNING jobs.id, jobs.cluster_id, jobs.node_id, jobs.started_at, jobs.finished_at, jobs.duration_hours, jobs.status, jobs.tag, jobs.error_text, jobs.last_heartbeat_at, jobs.exit_code, jobs.command, jobs.working_dir, jobs.termination_signal, jobs.cpu_user_seconds, jobs.cpu_system_seconds, jobs.max_rss_bytes, jobs.io_read_bytes, jobs.io_write_bytes, jobs.run_id, jobs.labels, jobs.progress_percent, jobs.progress_step, jobs.progress_total, jobs.progress_phase, jobs.progress_updated_at, jobs.cancel_requested_at, jobs.cancel_requested_by, jobs.duration_anomaly, jobs.duration_anomaly_score, jobs.duration_baseline_seconds, jobs.duration_anomaly_at, jobs.queue_id
+`
+
+type FlagOverrunningJobsParams struct {
+	MinSamples int32   `json:"min_samples"`
+	Factor     float64 `json:"factor"`
+}
+
+// 実行中のまま、ベースラインの中央値の factor 倍を超えたジョブを slow にする。既に印を付けたジョブは返さない
+func (q *Queries) FlagOverrunningJobs(ctx context.Context, arg FlagOverrunningJobsParams) ([]Job, error) {
+	rows, err := q.db.Query(ctx, flagOverrunningJobs, arg.MinSamples, arg.Factor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClusterID,
+			&i.NodeID,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.DurationHours,
+			&i.Status,
+			&i.Tag,
+			&i.ErrorText,
+			&i.LastHeartbeatAt,
+			&i.ExitCode,
+			&i.Command,
+			&i.WorkingDir,
+			&i.TerminationSignal,
+			&i.CpuUserSeconds,
+			&i.CpuSystemSeconds,
+			&i.MaxRssBytes,
+			&i.IoReadBytes,
+			&i.IoWriteBytes,
+			&i.RunID,
+			&i.Labels,
+			&i.ProgressPercent,
+			&i.ProgressStep,
+			&i.ProgressTotal,
+			&i.ProgressPhase,
+			&i.ProgressUpdatedAt,
+			&i.CancelRequestedAt,
+			&i.CancelRequestedBy,
+			&i.DurationAnomaly,
+			&i.DurationAnomalyScore,
+			&i.DurationBaselineSeconds,
+			&i.DurationAnomalyAt,
+			&i.QueueID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getJobByClusterAndJobID = `-- name: GetJobByClusterAndJobID :one
-SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text FROM jobs
+SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id FROM jobs
 WHERE cluster_id = $1 AND id = $2 LIMIT 1
 `
 
@@ -82,36 +215,246 @@ func (q *Queries) GetJobByClusterAndJobID(ctx context.Context, arg GetJobByClust
 		&i.Status,
 		&i.Tag,
 		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
 	)
 	return i, err
 }
 
-const listJobsByCluster = `-- name: ListJobsByCluster :many
-SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text FROM jobs
+const getJobByNodeAndJobID = `-- name: GetJobByNodeAndJobID :one
+SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id FROM jobs
+WHERE node_id = $1 AND id = $2 LIMIT 1
+`
+
+type GetJobByNodeAndJobIDParams struct {
+	NodeID int64 `json:"node_id"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) GetJobByNodeAndJobID(ctx context.Context, arg GetJobByNodeAndJobIDParams) (Job, error) {
+	row := q.db.QueryRow(ctx, getJobByNodeAndJobID, arg.NodeID, arg.ID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
+const getJobByNodeAndRunID = `-- name: GetJobByNodeAndRunID :one
+SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id FROM jobs
+WHERE node_id = $1 AND run_id = $2 LIMIT 1
+`
+
+type GetJobByNodeAndRunIDParams struct {
+	NodeID int64       `json:"node_id"`
+	RunID  pgtype.UUID `json:"run_id"`
+}
+
+func (q *Queries) GetJobByNodeAndRunID(ctx context.Context, arg GetJobByNodeAndRunIDParams) (Job, error) {
+	row := q.db.QueryRow(ctx, getJobByNodeAndRunID, arg.NodeID, arg.RunID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
+const getLastFinishedJobByTag = `-- name: GetLastFinishedJobByTag :one
+SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id FROM jobs
 WHERE cluster_id = $1
-ORDER BY started_at DESC, id DESC
+  AND tag = $2::text
+  AND finished_at IS NOT NULL
+  AND id <> $3
+ORDER BY finished_at DESC, id DESC
+LIMIT 1
 `
 
-func (q *Queries) ListJobsByCluster(ctx context.Context, clusterID string) ([]Job, error) {
-	rows, err := q.db.Query(ctx, listJobsByCluster, clusterID)
+type GetLastFinishedJobByTagParams struct {
+	ClusterID string `json:"cluster_id"`
+	Tag       string `json:"tag"`
+	ExcludeID int64  `json:"exclude_id"`
+}
+
+// 同じタグで最後に終了したジョブ。通知の判定中のジョブ自身は除く
+func (q *Queries) GetLastFinishedJobByTag(ctx context.Context, arg GetLastFinishedJobByTagParams) (Job, error) {
+	row := q.db.QueryRow(ctx, getLastFinishedJobByTag, arg.ClusterID, arg.Tag, arg.ExcludeID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
+const listActiveJobIDsByNode = `-- name: ListActiveJobIDsByNode :many
+SELECT id FROM jobs
+WHERE cluster_id = $1 AND node_id = $2 AND finished_at IS NULL
+ORDER BY id
+`
+
+type ListActiveJobIDsByNodeParams struct {
+	ClusterID string `json:"cluster_id"`
+	NodeID    int64  `json:"node_id"`
+}
+
+func (q *Queries) ListActiveJobIDsByNode(ctx context.Context, arg ListActiveJobIDsByNodeParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listActiveJobIDsByNode, arg.ClusterID, arg.NodeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Job{}
+	items := []int64{}
 	for rows.Next() {
-		var i Job
-		if err := rows.Scan(
-			&i.ID,
-			&i.ClusterID,
-			&i.NodeID,
-			&i.StartedAt,
-			&i.FinishedAt,
-			&i.DurationHours,
-			&i.Status,
-			&i.Tag,
-			&i.ErrorText,
-		); err != nil {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveJobsByCluster = `-- name: ListActiveJobsByCluster :many
+SELECT id, node_id FROM jobs
+WHERE cluster_id = $1 AND finished_at IS NULL
+ORDER BY node_id, id
+`
+
+type ListActiveJobsByClusterRow struct {
+	ID     int64 `json:"id"`
+	NodeID int64 `json:"node_id"`
+}
+
+func (q *Queries) ListActiveJobsByCluster(ctx context.Context, clusterID string) ([]ListActiveJobsByClusterRow, error) {
+	rows, err := q.db.Query(ctx, listActiveJobsByCluster, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActiveJobsByClusterRow{}
+	for rows.Next() {
+		var i ListActiveJobsByClusterRow
+		if err := rows.Scan(&i.ID, &i.NodeID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -122,14 +465,19 @@ func (q *Queries) ListJobsByCluster(ctx context.Context, clusterID string) ([]Jo
 	return items, nil
 }
 
-const listJobsByNode = `-- name: ListJobsByNode :many
-SELECT id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text FROM jobs
-WHERE node_id = $1
-ORDER BY started_at DESC, id DESC
+const markStaleJobsLost = `-- name: MarkStaleJobsLost :many
+UPDATE jobs
+SET status = 'lost',
+    finished_at = NOW(),
+    duration_hours = NOW() - started_at,
+    error_text = 'lost: no heartbeat received since ' || to_char(last_heartbeat_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+WHERE finished_at IS NULL
+  AND last_heartbeat_at < $1::timestamptz
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
 `
 
-func (q *Queries) ListJobsByNode(ctx context.Context, nodeID int64) ([]Job, error) {
-	rows, err := q.db.Query(ctx, listJobsByNode, nodeID)
+func (q *Queries) MarkStaleJobsLost(ctx context.Context, staleBefore pgtype.Timestamptz) ([]Job, error) {
+	rows, err := q.db.Query(ctx, markStaleJobsLost, staleBefore)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +495,30 @@ func (q *Queries) ListJobsByNode(ctx context.Context, nodeID int64) ([]Job, erro
 			&i.Status,
 			&i.Tag,
 			&i.ErrorText,
+			&i.LastHeartbeatAt,
+			&i.ExitCode,
+			&i.Command,
+			&i.WorkingDir,
+			&i.TerminationSignal,
+			&i.CpuUserSeconds,
+			&i.CpuSystemSeconds,
+			&i.MaxRssBytes,
+			&i.IoReadBytes,
+			&i.IoWriteBytes,
+			&i.RunID,
+			&i.Labels,
+			&i.ProgressPercent,
+			&i.ProgressStep,
+			&i.ProgressTotal,
+			&i.ProgressPhase,
+			&i.ProgressUpdatedAt,
+			&i.CancelRequestedAt,
+			&i.CancelRequestedBy,
+			&i.DurationAnomaly,
+			&i.DurationAnomalyScore,
+			&i.DurationBaselineSeconds,
+			&i.DurationAnomalyAt,
+			&i.QueueID,
 		); err != nil {
 			return nil, err
 		}
@@ -158,6 +530,183 @@ func (q *Queries) ListJobsByNode(ctx context.Context, nodeID int64) ([]Job, erro
 	return items, nil
 }
 
+const recordJobHeartbeat = `-- name: RecordJobHeartbeat :one
+UPDATE jobs
+SET last_heartbeat_at = GREATEST(last_heartbeat_at, LEAST(COALESCE($1::timestamptz, NOW()), NOW()))
+WHERE id = $2 AND node_id = $3 AND finished_at IS NULL
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
+`
+
+type RecordJobHeartbeatParams struct {
+	SentAt pgtype.Timestamptz `json:"sent_at"`
+	ID     int64              `json:"id"`
+	NodeID int64              `json:"node_id"`
+}
+
+func (q *Queries) RecordJobHeartbeat(ctx context.Context, arg RecordJobHeartbeatParams) (Job, error) {
+	row := q.db.QueryRow(ctx, recordJobHeartbeat, arg.SentAt, arg.ID, arg.NodeID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
+const requestJobCancel = `-- name: RequestJobCancel :one
+UPDATE jobs
+SET cancel_requested_at = COALESCE(cancel_requested_at, NOW()),
+    cancel_requested_by = COALESCE(cancel_requested_by, $1::text)
+WHERE cluster_id = $2 AND id = $3 AND finished_at IS NULL
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
+`
+
+type RequestJobCancelParams struct {
+	RequestedBy string `json:"requested_by"`
+	ClusterID   string `json:"cluster_id"`
+	ID          int64  `json:"id"`
+}
+
+// 取り消し要求済みなら最初の要求者と時刻を残す
+func (q *Queries) RequestJobCancel(ctx context.Context, arg RequestJobCancelParams) (Job, error) {
+	row := q.db.QueryRow(ctx, requestJobCancel, arg.RequestedBy, arg.ClusterID, arg.ID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
+const setJobDurationAnomaly = `-- name: SetJobDurationAnomaly :one
+UPDATE jobs
+SET duration_anomaly = $1,
+    duration_anomaly_score = $2,
+    duration_baseline_seconds = $3::float8,
+    duration_anomaly_at = CASE
+        WHEN $1::text IS NULL THEN NULL
+        ELSE COALESCE(duration_anomaly_at, NOW())
+    END
+WHERE id = $4
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
+`
+
+type SetJobDurationAnomalyParams struct {
+	Anomaly         *string  `json:"anomaly"`
+	Score           *float64 `json:"score"`
+	BaselineSeconds float64  `json:"baseline_seconds"`
+	ID              int64    `json:"id"`
+}
+
+// 終了したジョブの所要時間をベースラインと比べた結果を記録する。外れていなければ anomaly は NULL
+func (q *Queries) SetJobDurationAnomaly(ctx context.Context, arg SetJobDurationAnomalyParams) (Job, error) {
+	row := q.db.QueryRow(ctx, setJobDurationAnomaly,
+		arg.Anomaly,
+		arg.Score,
+		arg.BaselineSeconds,
+		arg.ID,
+	)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.DurationHours,
+		&i.Status,
+		&i.Tag,
+		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
+	)
+	return i, err
+}
+
 const updateJob = `-- name: UpdateJob :one
 UPDATE jobs
 SET started_at  = COALESCE($2, started_at),
@@ -165,19 +714,33 @@ SET started_at  = COALESCE($2, started_at),
     status      = COALESCE($4, status),
     tag         = COALESCE($5, tag),
     duration_hours = COALESCE($6, duration_hours),
-    error_text = $7
+    error_text = $7,
+    exit_code = COALESCE($8, exit_code),
+    termination_signal = COALESCE($9, termination_signal),
+    cpu_user_seconds = COALESCE($10, cpu_user_seconds),
+    cpu_system_seconds = COALESCE($11, cpu_system_seconds),
+    max_rss_bytes = COALESCE($12, max_rss_bytes),
+    io_read_bytes = COALESCE($13, io_read_bytes),
+    io_write_bytes = COALESCE($14, io_write_bytes)
 WHERE id = $1
-RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text
+RETURNING id, cluster_id, node_id, started_at, finished_at, duration_hours, status, tag, error_text, last_heartbeat_at, exit_code, command, working_dir, termination_signal, cpu_user_seconds, cpu_system_seconds, max_rss_bytes, io_read_bytes, io_write_bytes, run_id, labels, progress_percent, progress_step, progress_total, progress_phase, progress_updated_at, cancel_requested_at, cancel_requested_by, duration_anomaly, duration_anomaly_score, duration_baseline_seconds, duration_anomaly_at, queue_id
 `
 
 type UpdateJobParams struct {
-	ID            int64              `json:"id"`
-	StartedAt     pgtype.Timestamptz `json:"started_at"`
-	FinishedAt    pgtype.Timestamptz `json:"finished_at"`
-	Status        string             `json:"status"`
-	Tag           *string            `json:"tag"`
-	DurationHours pgtype.Interval    `json:"duration_hours"`
-	ErrorText     *string            `json:"error_text"`
+	ID                int64              `json:"id"`
+	StartedAt         pgtype.Timestamptz `json:"started_at"`
+	FinishedAt        pgtype.Timestamptz `json:"finished_at"`
+	Status            string             `json:"status"`
+	Tag               *string            `json:"tag"`
+	DurationHours     pgtype.Interval    `json:"duration_hours"`
+	ErrorText         *string            `json:"error_text"`
+	ExitCode          *int32             `json:"exit_code"`
+	TerminationSignal *string            `json:"termination_signal"`
+	CpuUserSeconds    *float64           `json:"cpu_user_seconds"`
+	CpuSystemSeconds  *float64           `json:"cpu_system_seconds"`
+	MaxRssBytes       *int64             `json:"max_rss_bytes"`
+	IoReadBytes       *int64             `json:"io_read_bytes"`
+	IoWriteBytes      *int64             `json:"io_write_bytes"`
 }
 
 func (q *Queries) UpdateJob(ctx context.Context, arg UpdateJobParams) (Job, error) {
@@ -189,6 +752,13 @@ func (q *Queries) UpdateJob(ctx context.Context, arg UpdateJobParams) (Job, erro
 		arg.Tag,
 		arg.DurationHours,
 		arg.ErrorText,
+		arg.ExitCode,
+		arg.TerminationSignal,
+		arg.CpuUserSeconds,
+		arg.CpuSystemSeconds,
+		arg.MaxRssBytes,
+		arg.IoReadBytes,
+		arg.IoWriteBytes,
 	)
 	var i Job
 	err := row.Scan(
@@ -201,6 +771,69 @@ func (q *Queries) UpdateJob(ctx context.Context, arg UpdateJobParams) (Job, erro
 		&i.Status,
 		&i.Tag,
 		&i.ErrorText,
+		&i.LastHeartbeatAt,
+		&i.ExitCode,
+		&i.Command,
+		&i.WorkingDir,
+		&i.TerminationSignal,
+		&i.CpuUserSeconds,
+		&i.CpuSystemSeconds,
+		&i.MaxRssBytes,
+		&i.IoReadBytes,
+		&i.IoWriteBytes,
+		&i.RunID,
+		&i.Labels,
+		&i.ProgressPercent,
+		&i.ProgressStep,
+		&i.ProgressTotal,
+		&i.ProgressPhase,
+		&i.ProgressUpdatedAt,
+		&i.CancelRequestedAt,
+		&i.CancelRequestedBy,
+		&i.DurationAnomaly,
+		&i.DurationAnomalyScore,
+		&i.DurationBaselineSeconds,
+		&i.DurationAnomalyAt,
+		&i.QueueID,
 	)
 	return i, err
 }
+
+const updateJobProgress = `-- name: UpdateJobProgress :execrows
+UPDATE jobs
+SET progress_percent = $3,
+    progress_step = $4,
+    progress_total = $5,
+    progress_phase = $6,
+    progress_updated_at = $7
+WHERE id = $1
+  AND node_id = $2
+  AND finished_at IS NULL
+  AND (progress_updated_at IS NULL OR progress_updated_at <= $7)
+`
+
+type UpdateJobProgressParams struct {
+	ID                int64              `json:"id"`
+	NodeID            int64              `json:"node_id"`
+	ProgressPercent   *float64           `json:"progress_percent"`
+	ProgressStep      *int64             `json:"progress_step"`
+	ProgressTotal     *int64             `json:"progress_total"`
+	ProgressPhase     *string            `json:"progress_phase"`
+	ProgressUpdatedAt pgtype.Timestamptz `json:"progress_updated_at"`
+}
+
+func (q *Queries) UpdateJobProgress(ctx context.Context, arg UpdateJobProgressParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateJobProgress,
+		arg.ID,
+		arg.NodeID,
+		arg.ProgressPercent,
+		arg.ProgressStep,
+		arg.ProgressTotal,
+		arg.ProgressPhase,
+		arg.ProgressUpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
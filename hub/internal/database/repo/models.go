@@ -12,18 +12,99 @@ type Cluster struct {
 	ID           string             `json:"id"`
 	PasswordHash string             `json:"password_hash"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	NotifyEmails []string           `json:"notify_emails"`
 }
 
 type Job struct {
-	ID            int64              `json:"id"`
+	ID                      int64              `json:"id"`
+	ClusterID               string             `json:"cluster_id"`
+	NodeID                  int64              `json:"node_id"`
+	StartedAt               pgtype.Timestamptz `json:"started_at"`
+	FinishedAt              pgtype.Timestamptz `json:"finished_at"`
+	DurationHours           pgtype.Interval    `json:"duration_hours"`
+	Status                  string             `json:"status"`
+	Tag                     *string            `json:"tag"`
+	ErrorText               *string            `json:"error_text"`
+	LastHeartbeatAt         pgtype.Timestamptz `json:"last_heartbeat_at"`
+	ExitCode                *int32             `json:"exit_code"`
+	Command                 []string           `json:"command"`
+	WorkingDir              *string            `json:"working_dir"`
+	TerminationSignal       *string            `json:"termination_signal"`
+	CpuUserSeconds          *float64           `json:"cpu_user_seconds"`
+	CpuSystemSeconds        *float64           `json:"cpu_system_seconds"`
+	MaxRssBytes             *int64             `json:"max_rss_bytes"`
+	IoReadBytes             *int64             `json:"io_read_bytes"`
+	IoWriteBytes            *int64             `json:"io_write_bytes"`
+	RunID                   pgtype.UUID        `json:"run_id"`
+	Labels                  []byte             `json:"labels"`
+	ProgressPercent         *float64           `json:"progress_percent"`
+	ProgressStep            *int64             `json:"progress_step"`
+	ProgressTotal           *int64             `json:"progress_total"`
+	ProgressPhase           *string            `json:"progress_phase"`
+	ProgressUpdatedAt       pgtype.Timestamptz `json:"progress_updated_at"`
+	CancelRequestedAt       pgtype.Timestamptz `json:"cancel_requested_at"`
+	CancelRequestedBy       *string            `json:"cancel_requested_by"`
+	DurationAnomaly         *string            `json:"duration_anomaly"`
+	DurationAnomalyScore    *float64           `json:"duration_anomaly_score"`
+	DurationBaselineSeconds *float64           `json:"duration_baseline_seconds"`
+	DurationAnomalyAt       pgtype.Timestamptz `json:"duration_anomaly_at"`
+	QueueID                 *int64             `json:"queue_id"`
+}
+
+type JobArtifact struct {
+	ID         int64              `json:"id"`
+	JobID      int64              `json:"job_id"`
+	Name       string             `json:"name"`
+	SizeBytes  int64              `json:"size_bytes"`
+	Sha256     string             `json:"sha256"`
+	StorageKey string             `json:"storage_key"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type JobDurationBaseline struct {
 	ClusterID     string             `json:"cluster_id"`
-	NodeID        int64              `json:"node_id"`
-	StartedAt     pgtype.Timestamptz `json:"started_at"`
-	FinishedAt    pgtype.Timestamptz `json:"finished_at"`
-	DurationHours pgtype.Interval    `json:"duration_hours"`
-	Status        string             `json:"status"`
-	Tag           *string            `json:"tag"`
-	ErrorText     *string            `json:"error_text"`
+	Tag           string             `json:"tag"`
+	SampleCount   int32              `json:"sample_count"`
+	MedianSeconds float64            `json:"median_seconds"`
+	MadSeconds    float64            `json:"mad_seconds"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type JobLog struct {
+	JobID      int64              `json:"job_id"`
+	Stream     string             `json:"stream"`
+	Seq        int64              `json:"seq"`
+	ByteOffset int64              `json:"byte_offset"`
+	Data       string             `json:"data"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type JobMetric struct {
+	JobID      int64              `json:"job_id"`
+	Key        string             `json:"key"`
+	Step       int64              `json:"step"`
+	Value      float64            `json:"value"`
+	RecordedAt pgtype.Timestamptz `json:"recorded_at"`
+}
+
+type JobQueue struct {
+	ID             int64              `json:"id"`
+	ClusterID      string             `json:"cluster_id"`
+	Command        []string           `json:"command"`
+	WorkingDir     *string            `json:"working_dir"`
+	Tag            *string            `json:"tag"`
+	Labels         []byte             `json:"labels"`
+	NodeID         *int64             `json:"node_id"`
+	NodeSelector   []byte             `json:"node_selector"`
+	Priority       int32              `json:"priority"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	MaxAttempts    int32              `json:"max_attempts"`
+	LeasedNodeID   *int64             `json:"leased_node_id"`
+	LeaseExpiresAt pgtype.Timestamptz `json:"lease_expires_at"`
+	JobID          *int64             `json:"job_id"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
 }
 
 type Node struct {
@@ -31,6 +112,15 @@ type Node struct {
 	ClusterID     string             `json:"cluster_id"`
 	NodeName      string             `json:"node_name"`
 	NodeTokenHash string             `json:"node_token_hash"`
-	CurrentJobID  *int64             `json:"current_job_id"`
 	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Slots         int32              `json:"slots"`
+	Labels        []byte             `json:"labels"`
+}
+
+type TriggerRequest struct {
+	NodeID         int64              `json:"node_id"`
+	IdempotencyKey string             `json:"idempotency_key"`
+	StatusCode     int32              `json:"status_code"`
+	Response       []byte             `json:"response"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
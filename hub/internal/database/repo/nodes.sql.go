@@ -11,29 +11,38 @@ import (
 
 const createNode = `-- name: CreateNode :one
 INSERT INTO nodes (
-  cluster_id, node_name, node_token_hash
+  cluster_id, node_name, node_token_hash, slots, labels
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
-RETURNING id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+RETURNING id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 `
 
 type CreateNodeParams struct {
 	ClusterID     string `json:"cluster_id"`
 	NodeName      string `json:"node_name"`
 	NodeTokenHash string `json:"node_token_hash"`
+	Slots         int32  `json:"slots"`
+	Labels        []byte `json:"labels"`
 }
 
 func (q *Queries) CreateNode(ctx context.Context, arg CreateNodeParams) (Node, error) {
-	row := q.db.QueryRow(ctx, createNode, arg.ClusterID, arg.NodeName, arg.NodeTokenHash)
+	row := q.db.QueryRow(ctx, createNode,
+		arg.ClusterID,
+		arg.NodeName,
+		arg.NodeTokenHash,
+		arg.Slots,
+		arg.Labels,
+	)
 	var i Node
 	err := row.Scan(
 		&i.ID,
 		&i.ClusterID,
 		&i.NodeName,
 		&i.NodeTokenHash,
-		&i.CurrentJobID,
 		&i.CreatedAt,
+		&i.Slots,
+		&i.Labels,
 	)
 	return i, err
 }
@@ -56,8 +65,35 @@ func (q *Queries) DeleteNodeByCluster(ctx context.Context, arg DeleteNodeByClust
 	return result.RowsAffected(), nil
 }
 
+const getNodeByCluster = `-- name: GetNodeByCluster :one
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
+FROM nodes
+WHERE id = $1 AND cluster_id = $2
+LIMIT 1
+`
+
+type GetNodeByClusterParams struct {
+	ID        int64  `json:"id"`
+	ClusterID string `json:"cluster_id"`
+}
+
+func (q *Queries) GetNodeByCluster(ctx context.Context, arg GetNodeByClusterParams) (Node, error) {
+	row := q.db.QueryRow(ctx, getNodeByCluster, arg.ID, arg.ClusterID)
+	var i Node
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeName,
+		&i.NodeTokenHash,
+		&i.CreatedAt,
+		&i.Slots,
+		&i.Labels,
+	)
+	return i, err
+}
+
 const getNodeByNodeTokenHash = `-- name: GetNodeByNodeTokenHash :one
-SELECT id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 FROM nodes
 WHERE node_token_hash = $1
 LIMIT 1
@@ -71,14 +107,37 @@ func (q *Queries) GetNodeByNodeTokenHash(ctx context.Context, nodeTokenHash stri
 		&i.ClusterID,
 		&i.NodeName,
 		&i.NodeTokenHash,
-		&i.CurrentJobID,
 		&i.CreatedAt,
+		&i.Slots,
+		&i.Labels,
+	)
+	return i, err
+}
+
+const getNodeForUpdate = `-- name: GetNodeForUpdate :one
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
+FROM nodes
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetNodeForUpdate(ctx context.Context, id int64) (Node, error) {
+	row := q.db.QueryRow(ctx, getNodeForUpdate, id)
+	var i Node
+	err := row.Scan(
+		&i.ID,
+		&i.ClusterID,
+		&i.NodeName,
+		&i.NodeTokenHash,
+		&i.CreatedAt,
+		&i.Slots,
+		&i.Labels,
 	)
 	return i, err
 }
 
 const listNodesByCluster = `-- name: ListNodesByCluster :many
-SELECT id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+SELECT id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 FROM nodes
 WHERE cluster_id = $1
 ORDER BY node_name ASC
@@ -98,8 +157,9 @@ func (q *Queries) ListNodesByCluster(ctx context.Context, clusterID string) ([]N
 			&i.ClusterID,
 			&i.NodeName,
 			&i.NodeTokenHash,
-			&i.CurrentJobID,
 			&i.CreatedAt,
+			&i.Slots,
+			&i.Labels,
 		); err != nil {
 			return nil, err
 		}
@@ -111,28 +171,37 @@ func (q *Queries) ListNodesByCluster(ctx context.Context, clusterID string) ([]N
 	return items, nil
 }
 
-const updateNodeCurrentJob = `-- name: UpdateNodeCurrentJob :one
+const updateNodeByCluster = `-- name: UpdateNodeByCluster :one
 UPDATE nodes
-SET current_job_id = $2
-WHERE id = $1
-RETURNING id, cluster_id, node_name, node_token_hash, current_job_id, created_at
+SET slots = COALESCE($1, slots),
+    labels = COALESCE($2::jsonb, labels)
+WHERE id = $3 AND cluster_id = $4
+RETURNING id, cluster_id, node_name, node_token_hash, created_at, slots, labels
 `
 
-type UpdateNodeCurrentJobParams struct {
-	ID           int64  `json:"id"`
-	CurrentJobID *int64 `json:"current_job_id"`
+type UpdateNodeByClusterParams struct {
+	Slots     *int32 `json:"slots"`
+	Labels    []byte `json:"labels"`
+	ID        int64  `json:"id"`
+	ClusterID string `json:"cluster_id"`
 }
 
-func (q *Queries) UpdateNodeCurrentJob(ctx context.Context, arg UpdateNodeCurrentJobParams) (Node, error) {
-	row := q.db.QueryRow(ctx, updateNodeCurrentJob, arg.ID, arg.CurrentJobID)
+func (q *Queries) UpdateNodeByCluster(ctx context.Context, arg UpdateNodeByClusterParams) (Node, error) {
+	row := q.db.QueryRow(ctx, updateNodeByCluster,
+		arg.Slots,
+		arg.Labels,
+		arg.ID,
+		arg.ClusterID,
+	)
 	var i Node
 	err := row.Scan(
 		&i.ID,
 		&i.ClusterID,
 		&i.NodeName,
 		&i.NodeTokenHash,
-		&i.CurrentJobID,
 		&i.CreatedAt,
+		&i.Slots,
+		&i.Labels,
 	)
 	return i, err
 }
@@ -6,23 +6,68 @@ package repo
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	CancelQueuedJobByCluster(ctx context.Context, arg CancelQueuedJobByClusterParams) (JobQueue, error)
+	CountActiveJobsByNode(ctx context.Context, nodeID int64) (int64, error)
+	CountActiveLeasesByNode(ctx context.Context, leasedNodeID *int64) (int64, error)
 	CreateCluster(ctx context.Context, arg CreateClusterParams) (Cluster, error)
 	CreateJob(ctx context.Context, arg CreateJobParams) (Job, error)
+	CreateJobLogChunk(ctx context.Context, arg CreateJobLogChunkParams) error
+	CreateJobMetrics(ctx context.Context, arg CreateJobMetricsParams) error
 	CreateNode(ctx context.Context, arg CreateNodeParams) (Node, error)
+	CreateQueuedJob(ctx context.Context, arg CreateQueuedJobParams) (JobQueue, error)
+	CreateTriggerRequest(ctx context.Context, arg CreateTriggerRequestParams) error
 	DeleteCluster(ctx context.Context, id string) error
 	DeleteNodeByCluster(ctx context.Context, arg DeleteNodeByClusterParams) (int64, error)
+	DeleteTriggerRequestsBefore(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error)
+	ExpireQueuedJobLeases(ctx context.Context) ([]JobQueue, error)
+	FlagOverrunningJobs(ctx context.Context, arg FlagOverrunningJobsParams) ([]Job, error)
 	GetCluster(ctx context.Context, id string) (Cluster, error)
+	GetJobArtifactByCluster(ctx context.Context, arg GetJobArtifactByClusterParams) (JobArtifact, error)
+	GetJobArtifactByJobAndName(ctx context.Context, arg GetJobArtifactByJobAndNameParams) (JobArtifact, error)
 	GetJobByClusterAndJobID(ctx context.Context, arg GetJobByClusterAndJobIDParams) (Job, error)
+	GetJobByNodeAndJobID(ctx context.Context, arg GetJobByNodeAndJobIDParams) (Job, error)
+	GetJobByNodeAndRunID(ctx context.Context, arg GetJobByNodeAndRunIDParams) (Job, error)
+	GetJobDurationBaseline(ctx context.Context, arg GetJobDurationBaselineParams) (JobDurationBaseline, error)
+	GetJobLogSize(ctx context.Context, arg GetJobLogSizeParams) (int64, error)
+	GetJobStats(ctx context.Context, arg GetJobStatsParams) (GetJobStatsRow, error)
+	GetLastFinishedJobByTag(ctx context.Context, arg GetLastFinishedJobByTagParams) (Job, error)
+	GetLeasedQueuedJobForUpdate(ctx context.Context, arg GetLeasedQueuedJobForUpdateParams) (JobQueue, error)
+	GetNodeByCluster(ctx context.Context, arg GetNodeByClusterParams) (Node, error)
 	GetNodeByNodeTokenHash(ctx context.Context, nodeTokenHash string) (Node, error)
-	ListJobsByCluster(ctx context.Context, clusterID string) ([]Job, error)
-	ListJobsByNode(ctx context.Context, nodeID int64) ([]Job, error)
+	GetNodeForUpdate(ctx context.Context, id int64) (Node, error)
+	GetQueuedJobByCluster(ctx context.Context, arg GetQueuedJobByClusterParams) (JobQueue, error)
+	GetTriggerRequest(ctx context.Context, arg GetTriggerRequestParams) (TriggerRequest, error)
+	LeaseQueuedJob(ctx context.Context, arg LeaseQueuedJobParams) (JobQueue, error)
+	ListActiveJobIDsByNode(ctx context.Context, arg ListActiveJobIDsByNodeParams) ([]int64, error)
+	ListActiveJobsByCluster(ctx context.Context, clusterID string) ([]ListActiveJobsByClusterRow, error)
+	ListDailyJobStats(ctx context.Context, arg ListDailyJobStatsParams) ([]ListDailyJobStatsRow, error)
+	ListJobArtifactsByJob(ctx context.Context, jobID int64) ([]JobArtifact, error)
+	ListJobLogChunksInRange(ctx context.Context, arg ListJobLogChunksInRangeParams) ([]JobLog, error)
+	ListJobMetricKeys(ctx context.Context, jobID int64) ([]ListJobMetricKeysRow, error)
+	ListJobMetricPoints(ctx context.Context, arg ListJobMetricPointsParams) ([]ListJobMetricPointsRow, error)
+	ListJobStatsByNode(ctx context.Context, arg ListJobStatsByNodeParams) ([]ListJobStatsByNodeRow, error)
+	ListJobStatsByTag(ctx context.Context, arg ListJobStatsByTagParams) ([]ListJobStatsByTagRow, error)
 	ListNodesByCluster(ctx context.Context, clusterID string) ([]Node, error)
+	ListQueuedJobsByCluster(ctx context.Context, arg ListQueuedJobsByClusterParams) ([]JobQueue, error)
+	MarkQueuedJobStarted(ctx context.Context, arg MarkQueuedJobStartedParams) (JobQueue, error)
+	MarkStaleJobsLost(ctx context.Context, staleBefore pgtype.Timestamptz) ([]Job, error)
+	RecordJobHeartbeat(ctx context.Context, arg RecordJobHeartbeatParams) (Job, error)
+	RefreshJobDurationBaseline(ctx context.Context, arg RefreshJobDurationBaselineParams) error
+	RequestJobCancel(ctx context.Context, arg RequestJobCancelParams) (Job, error)
+	RequeueLostQueuedJobs(ctx context.Context, lostBefore pgtype.Timestamptz) ([]JobQueue, error)
+	SearchJobs(ctx context.Context, arg SearchJobsParams) ([]SearchJobsRow, error)
+	SetJobDurationAnomaly(ctx context.Context, arg SetJobDurationAnomalyParams) (Job, error)
 	UpdateCluster(ctx context.Context, arg UpdateClusterParams) (Cluster, error)
+	UpdateClusterNotifyEmails(ctx context.Context, arg UpdateClusterNotifyEmailsParams) (Cluster, error)
 	UpdateJob(ctx context.Context, arg UpdateJobParams) (Job, error)
-	UpdateNodeCurrentJob(ctx context.Context, arg UpdateNodeCurrentJobParams) (Node, error)
+	UpdateJobProgress(ctx context.Context, arg UpdateJobProgressParams) (int64, error)
+	UpdateNodeByCluster(ctx context.Context, arg UpdateNodeByClusterParams) (Node, error)
+	UpsertJobArtifact(ctx context.Context, arg UpsertJobArtifactParams) (JobArtifact, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// イベントの種類。data には対応する API のレスポンスと同じ形の JSON が入る
+const (
+	JobCreated   = "job.created"
+	JobUpdated   = "job.updated"
+	JobFinished  = "job.finished"
+	NodeCreated  = "node.created"
+	NodeUpdated  = "node.updated"
+	NodeDeleted  = "node.deleted"
+	QueueCreated = "queue.created"
+	QueueUpdated = "queue.updated"
+)
+
+// subscriberBuffer を超えて溜まった購読者は切断する。クライアントは Last-Event-ID で取りこぼしを受け取り直せる
+const subscriberBuffer = 64
+
+type Event struct {
+	// ID は Last-Event-ID に使う。中身の形式には依存させない
+	ID        string
+	ClusterID string
+	Type      string
+	Data      json.RawMessage
+}
+
+// Broker はクラスター内の変更を購読者に配る。
+// 今はプロセス内で配るだけだが、Hub を複数台で動かす場合は Postgres の LISTEN/NOTIFY で置き換える。
+type Broker interface {
+	// Publish は data を JSON にして配る。失敗してもログに残すだけで、呼び出し側の処理には影響させない
+	Publish(clusterID, eventType string, data any)
+	// Subscribe は clusterID のイベントの購読を始める。lastEventID より後に配られたイベントを backlog で返し、
+	// 保持期間を過ぎたなどで取りこぼしなく再開できなければ resumed は false になる
+	Subscribe(clusterID, lastEventID string) (sub *Subscription, backlog []Event, resumed bool)
+}
+
+// Subscription の C は、購読者の受け取りが追いつかず切断されたときに閉じられる。
+type Subscription struct {
+	C     <-chan Event
+	close func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// MemoryBroker は直近の size 件のイベントを保持し、再接続した購読者に配り直す。
+// ID にはプロセスの起動時刻を含めるので、再起動前の ID では再開できない。
+type MemoryBroker struct {
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	events []Event
+	// next は events の次に書き込む位置。events が size に達するまでは末尾に追加する
+	next        int
+	size        int
+	subscribers map[chan Event]string
+}
+
+func NewMemoryBroker(size int) *MemoryBroker {
+	if size < 1 {
+		size = 1
+	}
+	return &MemoryBroker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		events:      make([]Event, 0, size),
+		size:        size,
+		subscribers: map[chan Event]string{},
+	}
+}
+
+func (b *MemoryBroker) Publish(clusterID, eventType string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := Event{
+		ID:        b.epoch + "-" + strconv.FormatUint(b.seq, 10),
+		ClusterID: clusterID,
+		Type:      eventType,
+		Data:      raw,
+	}
+	if len(b.events) < b.size {
+		b.events = append(b.events, event)
+	} else {
+		b.events[b.next] = event
+	}
+	b.next = (b.next + 1) % b.size
+
+	for ch, subscribed := range b.subscribers {
+		if subscribed != clusterID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(clusterID, lastEventID string) (*Subscription, []Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[ch] = clusterID
+	sub := &Subscription{C: ch, close: func() { b.unsubscribe(ch) }}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	seq, ok := b.parseID(lastEventID)
+	if !ok {
+		return sub, nil, false
+	}
+
+	var backlog []Event
+	resumed := seq >= b.seq
+	for i := range b.events {
+		event := b.events[(b.next+i)%len(b.events)]
+		eventSeq, _ := b.parseID(event.ID)
+		if eventSeq <= seq {
+			continue
+		}
+		// lastEventID の直後のイベントがまだ残っていれば、取りこぼしはない
+		if eventSeq == seq+1 {
+			resumed = true
+		}
+		if event.ClusterID == clusterID {
+			backlog = append(backlog, event)
+		}
+	}
+	if !resumed {
+		return sub, nil, false
+	}
+	return sub, backlog, true
+}
+
+func (b *MemoryBroker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (b *MemoryBroker) parseID(id string) (uint64, bool) {
+	epoch, rawSeq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+	if err != nil || seq > b.seq {
+		return 0, false
+	}
+	return seq, true
+}
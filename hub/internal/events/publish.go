@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/response"
+)
+
+// PublishJob はジョブの変更を GET /api/jobs/:job_id と同じ形で配る。
+func PublishJob(broker Broker, eventType string, job repo.Job) {
+	broker.Publish(job.ClusterID, eventType, response.NewJob(job))
+}
+
+// PublishQueuedJob はキューのジョブの変更を GET /api/queue の要素と同じ形で配る。
+func PublishQueuedJob(broker Broker, eventType string, item repo.JobQueue) {
+	broker.Publish(item.ClusterID, eventType, response.NewQueuedJob(item))
+}
+
+// PublishNode はジョブの開始・終了で running_job_ids が変わったノードを、GET /api/nodes と同じ形で node.updated として配る。
+// 読み込みに失敗した場合はログに残すだけにする。
+func PublishNode(ctx context.Context, broker Broker, queries repo.Querier, clusterID string, nodeID int64) {
+	node, err := queries.GetNodeByCluster(ctx, repo.GetNodeByClusterParams{ID: nodeID, ClusterID: clusterID})
+	if err != nil {
+		log.Printf("failed to load node %d for %s event: %v", nodeID, NodeUpdated, err)
+		return
+	}
+	running, err := queries.ListActiveJobIDsByNode(ctx, repo.ListActiveJobIDsByNodeParams{ClusterID: clusterID, NodeID: nodeID})
+	if err != nil {
+		log.Printf("failed to list running jobs for %s event: %v", NodeUpdated, err)
+		return
+	}
+	broker.Publish(clusterID, NodeUpdated, response.NewNode(node, running))
+}
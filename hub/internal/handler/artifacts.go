@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+)
+
+const (
+	maxArtifactNameLength = 512
+	maxUploadFieldSize    = 4096
+)
+
+type artifactResponse struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func artifactToResponse(artifact repo.JobArtifact) artifactResponse {
+	return artifactResponse{
+		ID:        artifact.ID,
+		JobID:     artifact.JobID,
+		Name:      artifact.Name,
+		SizeBytes: artifact.SizeBytes,
+		SHA256:    artifact.Sha256,
+		CreatedAt: artifact.CreatedAt.Time,
+	}
+}
+
+// validArtifactName は CLI の作業ディレクトリからの相対パスとして妥当な名前かを判定する。
+// ダウンロード時のファイル名にも使うため、絶対パスや .. を含むものは受け付けない。
+func validArtifactName(name string) bool {
+	if name == "" || len(name) > maxArtifactNameLength || !utf8.ValidString(name) {
+		return false
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsControl(r) || r == '\\' }) {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// artifactStorageKey はアップロードごとに新しい保存先を返す。
+// 同じ名前で上書きされても、差し替えが終わるまでは古い中身を読める。
+func artifactStorageKey(jobID int64) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "jobs/" + strconv.FormatInt(jobID, 10) + "/artifacts/" + hex.EncodeToString(b[:]), nil
+}
+
+// readUploadField は multipart のテキスト項目を読む。長すぎる値は不正として扱う。
+func readUploadField(part *multipart.Part) (string, bool) {
+	value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+	if err != nil || len(value) > maxUploadFieldSize {
+		return "", false
+	}
+	return string(value), true
+}
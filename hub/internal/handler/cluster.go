@@ -3,6 +3,8 @@ package handler
 import (
 	"log"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -24,6 +26,12 @@ func NewClusterHandler(queries repo.Querier) *ClusterHandler {
 type clusterResponse struct {
 	ID        string    `json:"cluster_id"`
 	CreatedAt time.Time `json:"created_at"`
+	// NotifyEmails は Hub からのメール通知の宛先。空なら送らない
+	NotifyEmails []string `json:"notify_emails"`
+}
+
+type updateClusterRequest struct {
+	NotifyEmails []string `json:"notify_emails" binding:"required,max=20"`
 }
 
 func (h *ClusterHandler) Me(c *gin.Context) {
@@ -35,13 +43,62 @@ func (h *ClusterHandler) Me(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, clusterToResponse(cluster))
+}
+
+// UpdateMe はクラスターの設定を更新する。今はメール通知の宛先だけで、空の配列を渡すと通知を止める。
+func (h *ClusterHandler) UpdateMe(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+
+	var req updateClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	emails := make([]string, 0, len(req.NotifyEmails))
+	seen := map[string]bool{}
+	for _, raw := range req.NotifyEmails {
+		raw = strings.TrimSpace(raw)
+		addr, err := mail.ParseAddress(raw)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		key := strings.ToLower(addr.Address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		emails = append(emails, raw)
+	}
+
+	cluster, err := h.queries.UpdateClusterNotifyEmails(c.Request.Context(), repo.UpdateClusterNotifyEmailsParams{
+		ID:           clusterID,
+		NotifyEmails: emails,
+	})
+	if err != nil {
+		log.Printf("failed to update cluster: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterToResponse(cluster))
+}
+
+func clusterToResponse(cluster repo.Cluster) clusterResponse {
 	var createdAt time.Time
 	if cluster.CreatedAt.Valid {
 		createdAt = cluster.CreatedAt.Time
 	}
+	emails := cluster.NotifyEmails
+	if emails == nil {
+		emails = []string{}
+	}
 
-	c.JSON(http.StatusOK, clusterResponse{
-		ID:        cluster.ID,
-		CreatedAt: createdAt,
-	})
+	return clusterResponse{
+		ID:           cluster.ID,
+		CreatedAt:    createdAt,
+		NotifyEmails: emails,
+	}
 }
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanaya/jobboard-hub/internal/events"
+	"github.com/kanaya/jobboard-hub/internal/middleware"
+)
+
+// eventReset は Last-Event-ID から再開できなかったときに送る。クライアントは一覧を取り直す
+const eventReset = "reset"
+
+// eventRetry はクライアントが切断後に再接続するまでの待ち時間（ミリ秒）
+const eventRetry = 3000
+
+type EventHandler struct {
+	broker    events.Broker
+	keepAlive time.Duration
+}
+
+func NewEventHandler(broker events.Broker, keepAlive time.Duration) *EventHandler {
+	return &EventHandler{
+		broker:    broker,
+		keepAlive: keepAlive,
+	}
+}
+
+type nodeDeletedEvent struct {
+	ID int64 `json:"id"`
+}
+
+// Stream はログイン中のクラスターのジョブ・ノード・キューの変更を Server-Sent Events で送り続ける。
+// Last-Event-ID を付けて再接続すると、切断中に配られたイベントから送り直す。
+// トークンの有効期限で接続を閉じるので、クライアントは新しいトークンで再接続する。
+func (h *EventHandler) Stream(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	lastEventID := c.GetHeader("Last-Event-ID")
+
+	sub, backlog, resumed := h.broker.Subscribe(clusterID, lastEventID)
+	defer sub.Close()
+
+	var expired <-chan time.Time
+	if expiresAt, ok := c.Get(middleware.TokenExpiresAtContextKey); ok {
+		timer := time.NewTimer(time.Until(expiresAt.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx などのプロキシにバッファさせない
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventRetry)
+	if !resumed {
+		fmt.Fprintf(c.Writer, "event: %s\ndata: {}\n\n", eventReset)
+	}
+	for _, event := range backlog {
+		writeEvent(c.Writer, event)
+	}
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// 受け取りが追いつかず切断された。クライアントは Last-Event-ID で再接続する
+				return
+			}
+			writeEvent(c.Writer, event)
+		case <-keepAlive.C:
+			io.WriteString(c.Writer, ": keepalive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent は 1 件のイベントを書く。Data は改行を含まない JSON なので、data 行は 1 行で済む。
+func writeEvent(w io.Writer, event events.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
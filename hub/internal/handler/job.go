@@ -3,56 +3,57 @@ package handler
 import (
 	"errors"
 	"log"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/blobstore"
+	"github.com/kanaya/jobboard-hub/internal/database/joblist"
 	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
 	"github.com/kanaya/jobboard-hub/internal/middleware"
+	"github.com/kanaya/jobboard-hub/internal/response"
 )
 
 type JobHandler struct {
 	queries repo.Querier
+	lister  joblist.Lister
+	store   blobstore.Store
+	broker  events.Broker
 }
 
-func NewJobHandler(queries repo.Querier) *JobHandler {
+func NewJobHandler(queries repo.Querier, lister joblist.Lister, store blobstore.Store, broker events.Broker) *JobHandler {
 	return &JobHandler{
 		queries: queries,
+		lister:  lister,
+		store:   store,
+		broker:  broker,
 	}
 }
 
-type jobResponse struct {
-	ID            int64      `json:"id"`
-	ClusterID     string     `json:"cluster_id"`
-	NodeID        int64      `json:"node_id"`
-	Status        string     `json:"status"`
-	StartedAt     *time.Time `json:"started_at,omitempty"`
-	FinishedAt    *time.Time `json:"finished_at,omitempty"`
-	DurationHours *float64   `json:"duration_hours,omitempty"`
-	Tag           *string    `json:"tag,omitempty"`
-	ErrorText     *string    `json:"error_text,omitempty"`
+type cancelJobRequest struct {
+	// RequestedBy は取り消しを要求した人。省略した場合は要求元のクラスター（cluster:<cluster_id>）を記録する
+	RequestedBy *string `json:"requested_by" binding:"omitempty,min=1,max=128"`
 }
 
+// List はジョブを 1 ページずつ返す。絞り込みと並び順のクエリパラメータは parseJobListQuery を参照。
 func (h *JobHandler) List(c *gin.Context) {
 	clusterID := c.GetString(middleware.ClusterIDContextKey)
 
-	jobs, err := h.queries.ListJobsByCluster(c.Request.Context(), clusterID)
-	if err != nil {
-		log.Printf("failed to list jobs: %v", err)
-		apierror.Write(c, apierror.Internal)
+	q, desc := parseJobListQuery(c, clusterID)
+	if desc != nil {
+		apierror.Write(c, *desc)
 		return
 	}
 
-	resp := make([]jobResponse, 0, len(jobs))
-	for _, job := range jobs {
-		resp = append(resp, jobToResponse(job))
-	}
-
-	c.JSON(200, resp)
+	h.listJobs(c, q)
 }
 
 func (h *JobHandler) Get(c *gin.Context) {
@@ -77,7 +78,66 @@ func (h *JobHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, jobToResponse(job))
+	c.JSON(http.StatusOK, response.NewJob(job))
+}
+
+// Cancel は実行中のジョブに取り消し要求を記録する。CLI は次のハートビートで要求を受け取り、
+// 子プロセスを終了させてから cancelled として finish する。本文は省略できる。
+func (h *JobHandler) Cancel(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	jobID, err := strconv.ParseInt(c.Param("job_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	var req cancelJobRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+	}
+	// トークンはクラスター単位なので、要求者の名前がなければクラスターとして記録する
+	requestedBy := "cluster:" + clusterID
+	if req.RequestedBy != nil {
+		requestedBy = strings.TrimSpace(*req.RequestedBy)
+		if requestedBy == "" {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+	}
+
+	job, err := h.queries.RequestJobCancel(c.Request.Context(), repo.RequestJobCancelParams{
+		RequestedBy: requestedBy,
+		ClusterID:   clusterID,
+		ID:          jobID,
+	})
+	if err == nil {
+		events.PublishJob(h.broker, events.JobUpdated, job)
+		c.JSON(http.StatusAccepted, response.NewJob(job))
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("failed to request job cancel: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	// 更新できなかったのが存在しないためか、終了済みのためかを区別する
+	_, err = h.queries.GetJobByClusterAndJobID(c.Request.Context(), repo.GetJobByClusterAndJobIDParams{
+		ClusterID: clusterID,
+		ID:        jobID,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		apierror.Write(c, apierror.JobNotFound)
+	case err != nil:
+		log.Printf("failed to load job: %v", err)
+		apierror.Write(c, apierror.Internal)
+	default:
+		apierror.Write(c, apierror.JobNotRunning)
+	}
 }
 
 func (h *JobHandler) ListByNode(c *gin.Context) {
@@ -108,38 +168,417 @@ func (h *JobHandler) ListByNode(c *gin.Context) {
 		return
 	}
 
-	jobs, err := h.queries.ListJobsByNode(c.Request.Context(), nodeID)
+	q, desc := parseJobListQuery(c, clusterID)
+	if desc != nil {
+		apierror.Write(c, *desc)
+		return
+	}
+	q.params.NodeID = &nodeID
+
+	h.listJobs(c, q)
+}
+
+const (
+	defaultLogReadLimit = 64 * 1024
+	maxLogReadLimit     = 1024 * 1024
+)
+
+type jobLogResponse struct {
+	JobID      int64  `json:"job_id"`
+	Stream     string `json:"stream"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	Size       int64  `json:"size"`
+	Data       string `json:"data"`
+	Complete   bool   `json:"complete"`
+}
+
+// Logs は stream ごとのログをバイトオフセット指定で返す。
+// offset に負数を渡すと末尾からの相対位置として扱うため、tail -f 相当の追跡は
+// offset=-N から始めて next_offset を次の offset に渡していけばよい。
+func (h *JobHandler) Logs(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	jobID, err := strconv.ParseInt(c.Param("job_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	stream := c.DefaultQuery("stream", "stdout")
+	if stream != "stdout" && stream != "stderr" {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultLogReadLimit)), 10, 64)
+	if err != nil || limit <= 0 {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if limit > maxLogReadLimit {
+		limit = maxLogReadLimit
+	}
+
+	job, err := h.queries.GetJobByClusterAndJobID(c.Request.Context(), repo.GetJobByClusterAndJobIDParams{
+		ClusterID: clusterID,
+		ID:        jobID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotFound)
+			return
+		}
+		log.Printf("failed to load job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	size, err := h.queries.GetJobLogSize(c.Request.Context(), repo.GetJobLogSizeParams{
+		JobID:  job.ID,
+		Stream: stream,
+	})
 	if err != nil {
-		log.Printf("failed to list jobs: %v", err)
+		log.Printf("failed to load job log size: %v", err)
 		apierror.Write(c, apierror.Internal)
 		return
 	}
 
-	resp := make([]jobResponse, 0, len(jobs))
-	for _, job := range jobs {
-		resp = append(resp, jobToResponse(job))
+	if offset < 0 {
+		offset = max(size+offset, 0)
+	}
+	end := min(offset+limit, size)
+
+	var data []byte
+	next := offset
+	if offset < end {
+		chunks, err := h.queries.ListJobLogChunksInRange(c.Request.Context(), repo.ListJobLogChunksInRangeParams{
+			JobID:      job.ID,
+			Stream:     stream,
+			RangeStart: offset,
+			RangeEnd:   end,
+		})
+		if err != nil {
+			log.Printf("failed to load job logs: %v", err)
+			apierror.Write(c, apierror.Internal)
+			return
+		}
+		data, next = sliceLogChunks(chunks, offset, end)
+
+		// ページの境目で UTF-8 の文字を分けない。分けると JSON にしたときに両側が U+FFFD になる。
+		// 先頭は次の文字の始まりまで進め、末尾の書きかけの文字は次の読み出しに回す（ログの最後まで読み終えた場合を除く）
+		skip := partialRuneHead(data)
+		data = data[skip:]
+		offset += int64(skip)
+		if end < size || !job.FinishedAt.Valid {
+			trim := partialRuneTail(data)
+			data = data[:len(data)-trim]
+			next -= int64(trim)
+		}
+	}
+
+	c.JSON(http.StatusOK, jobLogResponse{
+		JobID:      job.ID,
+		Stream:     stream,
+		Offset:     offset,
+		NextOffset: next,
+		Size:       size,
+		Data:       string(data),
+		Complete:   job.FinishedAt.Valid && next >= size,
+	})
+}
+
+// sliceLogChunks は [start, end) に重なるチャンクを連結し、読み終えた位置を返す。
+// 欠番になっている範囲は詰めて返すので、呼び出し側は返り値の位置をそのまま次の読み出しに使える。
+func sliceLogChunks(chunks []repo.JobLog, start, end int64) ([]byte, int64) {
+	var out []byte
+	cursor := start
+	for _, chunk := range chunks {
+		chunkStart := chunk.ByteOffset
+		chunkEnd := chunk.ByteOffset + int64(len(chunk.Data))
+		from := max(cursor, chunkStart)
+		to := min(end, chunkEnd)
+		if from >= to {
+			continue
+		}
+		out = append(out, chunk.Data[from-chunkStart:to-chunkStart]...)
+		cursor = to
+	}
+	return out, cursor
+}
+
+// partialRuneHead は data の先頭にある、途中から始まった文字の続きのバイト数を返す。
+func partialRuneHead(data []byte) int {
+	n := 0
+	for n < len(data) && n < utf8.UTFMax-1 && !utf8.RuneStart(data[n]) {
+		n++
+	}
+	if n == len(data) {
+		return 0
+	}
+	return n
+}
+
+// partialRuneTail は data の末尾にある、まだ続きが届いていない文字のバイト数を返す。
+// 末尾まで読むと data が空になる場合は 0 を返し、読み出しが進まなくなるのを防ぐ。
+func partialRuneTail(data []byte) int {
+	for n := 1; n < utf8.UTFMax && n <= len(data); n++ {
+		start := len(data) - n
+		if !utf8.RuneStart(data[start]) {
+			continue
+		}
+		if utf8.FullRune(data[start:]) || start == 0 {
+			return 0
+		}
+		return n
 	}
+	return 0
+}
+
+const (
+	defaultMetricPoints = 1000
+	maxMetricPoints     = 5000
+)
+
+type metricKeyResponse struct {
+	Key       string    `json:"key"`
+	Points    int64     `json:"points"`
+	FirstStep int64     `json:"first_step"`
+	LastStep  int64     `json:"last_step"`
+	LastValue float64   `json:"last_value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type jobMetricKeysResponse struct {
+	JobID int64               `json:"job_id"`
+	Keys  []metricKeyResponse `json:"keys"`
+}
+
+type metricPointResponse struct {
+	Step      int64     `json:"step"`
+	Value     float64   `json:"value"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type jobMetricSeriesResponse struct {
+	JobID       int64                 `json:"job_id"`
+	Key         string                `json:"key"`
+	TotalPoints int64                 `json:"total_points"`
+	Downsampled bool                  `json:"downsampled"`
+	Points      []metricPointResponse `json:"points"`
+}
+
+// Metrics は key 指定がなければ記録されている系列の一覧を、指定があればその系列を返す。
+// 系列が max_points を超える場合は step 順に均等なバケットへまとめ、
+// 各点の value にバケットの平均、min / max に範囲を入れて返す。
+func (h *JobHandler) Metrics(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	jobID, err := strconv.ParseInt(c.Param("job_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	maxPoints, err := strconv.Atoi(c.DefaultQuery("max_points", strconv.Itoa(defaultMetricPoints)))
+	if err != nil || maxPoints <= 0 {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	maxPoints = min(maxPoints, maxMetricPoints)
+
+	fromStep, ok := int64Query(c, "from_step")
+	if !ok {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	toStep, ok := int64Query(c, "to_step")
+	if !ok {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	job, err := h.queries.GetJobByClusterAndJobID(c.Request.Context(), repo.GetJobByClusterAndJobIDParams{
+		ClusterID: clusterID,
+		ID:        jobID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotFound)
+			return
+		}
+		log.Printf("failed to load job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	key := c.Query("key")
+	if key == "" {
+		keys, err := h.queries.ListJobMetricKeys(c.Request.Context(), job.ID)
+		if err != nil {
+			log.Printf("failed to list job metric keys: %v", err)
+			apierror.Write(c, apierror.Internal)
+			return
+		}
+
+		resp := jobMetricKeysResponse{JobID: job.ID, Keys: make([]metricKeyResponse, 0, len(keys))}
+		for _, k := range keys {
+			resp.Keys = append(resp.Keys, metricKeyResponse{
+				Key:       k.Key,
+				Points:    k.Points,
+				FirstStep: k.FirstStep,
+				LastStep:  k.LastStep,
+				LastValue: k.LastValue,
+				UpdatedAt: k.LastRecordedAt.Time,
+			})
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	rows, err := h.queries.ListJobMetricPoints(c.Request.Context(), repo.ListJobMetricPointsParams{
+		Buckets:  int32(maxPoints),
+		JobID:    job.ID,
+		Key:      key,
+		FromStep: fromStep,
+		ToStep:   toStep,
+	})
+	if err != nil {
+		log.Printf("failed to load job metrics: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	resp := jobMetricSeriesResponse{
+		JobID:  job.ID,
+		Key:    key,
+		Points: make([]metricPointResponse, 0, len(rows)),
+	}
+	for _, row := range rows {
+		resp.TotalPoints += row.Points
+		resp.Points = append(resp.Points, metricPointResponse{
+			Step:      row.Step,
+			Value:     row.Value,
+			Min:       row.MinValue,
+			Max:       row.MaxValue,
+			Timestamp: row.RecordedAt.Time,
+		})
+	}
+	resp.Downsampled = resp.TotalPoints > int64(len(resp.Points))
 
 	c.JSON(http.StatusOK, resp)
 }
 
-func timestamptzPtr(ts pgtype.Timestamptz) *time.Time {
-	if !ts.Valid {
-		return nil
+type jobArtifactsResponse struct {
+	JobID     int64              `json:"job_id"`
+	Artifacts []artifactResponse `json:"artifacts"`
+}
+
+func (h *JobHandler) Artifacts(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	jobID, err := strconv.ParseInt(c.Param("job_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
 	}
-	return &ts.Time
+
+	job, err := h.queries.GetJobByClusterAndJobID(c.Request.Context(), repo.GetJobByClusterAndJobIDParams{
+		ClusterID: clusterID,
+		ID:        jobID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotFound)
+			return
+		}
+		log.Printf("failed to load job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	artifacts, err := h.queries.ListJobArtifactsByJob(c.Request.Context(), job.ID)
+	if err != nil {
+		log.Printf("failed to list job artifacts: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	resp := jobArtifactsResponse{JobID: job.ID, Artifacts: make([]artifactResponse, 0, len(artifacts))}
+	for _, artifact := range artifacts {
+		resp.Artifacts = append(resp.Artifacts, artifactToResponse(artifact))
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
-func jobToResponse(job repo.Job) jobResponse {
-	return jobResponse{
-		ID:            job.ID,
-		ClusterID:     job.ClusterID,
-		NodeID:        job.NodeID,
-		Status:        job.Status,
-		StartedAt:     timestamptzPtr(job.StartedAt),
-		FinishedAt:    timestamptzPtr(job.FinishedAt),
-		DurationHours: intervalToHours(job.DurationHours),
-		Tag:           job.Tag,
-		ErrorText:     job.ErrorText,
+// DownloadArtifact はアーティファクトの中身をそのまま返す。ファイル名は name の最後の要素を使う。
+func (h *JobHandler) DownloadArtifact(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	jobID, err := strconv.ParseInt(c.Param("job_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	artifactID, err := strconv.ParseInt(c.Param("artifact_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	artifact, err := h.queries.GetJobArtifactByCluster(c.Request.Context(), repo.GetJobArtifactByClusterParams{
+		ID:        artifactID,
+		JobID:     jobID,
+		ClusterID: clusterID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.ArtifactNotFound)
+			return
+		}
+		log.Printf("failed to load job artifact: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	body, err := h.store.Get(c.Request.Context(), artifact.StorageKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			apierror.Write(c, apierror.ArtifactNotFound)
+			return
+		}
+		log.Printf("failed to read artifact blob: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+	defer body.Close()
+
+	filename := path.Base(artifact.Name)
+	contentType := mime.TypeByExtension(path.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, artifact.SizeBytes, contentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+		"ETag":                `"` + artifact.Sha256 + `"`,
+	})
+}
+
+// int64Query は省略可能な整数のクエリパラメータを読む。省略時は nil を返す。
+func int64Query(c *gin.Context, name string) (*int64, bool) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, true
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, false
 	}
+	return &v, true
 }
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/database/joblist"
+	"github.com/kanaya/jobboard-hub/internal/response"
+)
+
+const (
+	defaultJobListLimit = 50
+	maxJobListLimit     = 200
+	maxJobSortColumns   = 3
+	defaultJobSort      = "-started_at"
+)
+
+var jobStatuses = map[string]bool{
+	"running":   true,
+	"completed": true,
+	"failed":    true,
+	"timed_out": true,
+	"cancelled": true,
+	"lost":      true,
+}
+
+type jobListResponse struct {
+	Items []response.Job `json:"items"`
+	// NextCursor は次のページがなければ null
+	NextCursor *string `json:"next_cursor"`
+	TotalCount *int64  `json:"total_count,omitempty"`
+}
+
+// jobListQuery は GET /api/jobs と GET /api/nodes/:node_id/jobs のクエリパラメータ。
+type jobListQuery struct {
+	params joblist.Params
+	// sort は正規化した ?sort。カーソルが同じ並び順で作られたかの確認に使う
+	sort         string
+	includeTotal bool
+	// 同じキーに異なる値のラベル条件が指定され、どのジョブにも一致しない
+	empty bool
+}
+
+// jobCursor は next_cursor の中身。クライアントには base64url の文字列として渡し、中身には依存させない。
+type jobCursor struct {
+	Sort string   `json:"s"`
+	Keys []string `json:"k"`
+	ID   int64    `json:"id"`
+}
+
+// parseJobListQuery はクエリパラメータを読む。正しくなければ返すべきエラーを返す。
+func parseJobListQuery(c *gin.Context, clusterID string) (jobListQuery, *apierror.Descriptor) {
+	q := jobListQuery{params: joblist.Params{ClusterID: clusterID, Limit: defaultJobListLimit}}
+
+	nodeID, ok := int64Query(c, "node_id")
+	if !ok {
+		return q, &apierror.InvalidRequest
+	}
+	q.params.NodeID = nodeID
+
+	for _, raw := range c.QueryArray("status") {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !jobStatuses[status] {
+				return q, &apierror.InvalidRequest
+			}
+			q.params.Statuses = append(q.params.Statuses, status)
+		}
+	}
+
+	if tag, ok := c.GetQuery("tag"); ok {
+		q.params.Tag = &tag
+	}
+
+	if labels := c.QueryArray("label"); len(labels) > 0 {
+		selector, ok := parseLabelSelectors(labels)
+		if !ok {
+			return q, &apierror.InvalidRequest
+		}
+		q.empty = selector.empty
+		match, exclude, err := selector.params()
+		if err != nil {
+			log.Printf("failed to encode label selector: %v", err)
+			return q, &apierror.Internal
+		}
+		if len(selector.match) > 0 {
+			q.params.LabelMatch = match
+		}
+		if len(selector.exclude) > 0 {
+			q.params.LabelExclude = exclude
+		}
+	}
+
+	if q.params.StartedAfter, ok = timeQuery(c, "started_after"); !ok {
+		return q, &apierror.InvalidRequest
+	}
+	if q.params.StartedBefore, ok = timeQuery(c, "started_before"); !ok {
+		return q, &apierror.InvalidRequest
+	}
+	if q.params.MinDurationSeconds, ok = durationQuery(c, "min_duration"); !ok {
+		return q, &apierror.InvalidRequest
+	}
+	if q.params.MaxDurationSeconds, ok = durationQuery(c, "max_duration"); !ok {
+		return q, &apierror.InvalidRequest
+	}
+	if min, max := q.params.MinDurationSeconds, q.params.MaxDurationSeconds; min != nil && max != nil && *min > *max {
+		return q, &apierror.InvalidRequest
+	}
+
+	if q.params.Order, q.sort, ok = parseJobSort(c.DefaultQuery("sort", defaultJobSort)); !ok {
+		return q, &apierror.InvalidRequest
+	}
+
+	if raw, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxJobListLimit {
+			return q, &apierror.InvalidRequest
+		}
+		q.params.Limit = int32(limit)
+	}
+
+	if raw, ok := c.GetQuery("include_total"); ok {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, &apierror.InvalidRequest
+		}
+		q.includeTotal = include
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, ok := decodeJobCursor(raw)
+		if !ok || cursor.Sort != q.sort || len(cursor.Keys) != len(q.params.Order) {
+			return q, &apierror.InvalidCursor
+		}
+		after, err := joblist.ParseCursor(q.params.Order, cursor.Keys, cursor.ID)
+		if err != nil {
+			return q, &apierror.InvalidCursor
+		}
+		q.params.After = after
+	}
+
+	return q, nil
+}
+
+// parseJobSort は ?sort=-started_at,status のような列の並びを読む。先頭に - を付けた列は降順。
+func parseJobSort(raw string) ([]joblist.Order, string, bool) {
+	columns := strings.Split(raw, ",")
+	if len(columns) > maxJobSortColumns {
+		return nil, "", false
+	}
+
+	orders := make([]joblist.Order, 0, len(columns))
+	normalized := make([]string, 0, len(columns))
+	seen := map[joblist.Sort]bool{}
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		order := joblist.Order{Column: joblist.Sort(strings.TrimPrefix(column, "-")), Desc: strings.HasPrefix(column, "-")}
+		if !order.Column.Valid() || seen[order.Column] {
+			return nil, "", false
+		}
+		seen[order.Column] = true
+		orders = append(orders, order)
+		normalized = append(normalized, column)
+	}
+	return orders, strings.Join(normalized, ","), true
+}
+
+// listJobs は q の条件でジョブを 1 ページ分読み、レスポンスを書く。
+func (h *JobHandler) listJobs(c *gin.Context, q jobListQuery) {
+	resp := jobListResponse{Items: []response.Job{}}
+	if q.empty {
+		if q.includeTotal {
+			resp.TotalCount = new(int64)
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// 1 件多く読んで、次のページがあるかを判定する
+	params := q.params
+	params.Limit++
+	rows, err := h.lister.List(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("failed to list jobs: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	if len(rows) > int(q.params.Limit) {
+		rows = rows[:q.params.Limit]
+		last := rows[len(rows)-1]
+		next := encodeJobCursor(jobCursor{Sort: q.sort, Keys: last.SortKeys, ID: last.Job.ID})
+		resp.NextCursor = &next
+	}
+	for _, row := range rows {
+		resp.Items = append(resp.Items, response.NewJob(row.Job))
+	}
+
+	if q.includeTotal {
+		total, err := h.lister.Count(c.Request.Context(), q.params)
+		if err != nil {
+			log.Printf("failed to count jobs: %v", err)
+			apierror.Write(c, apierror.Internal)
+			return
+		}
+		resp.TotalCount = &total
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func encodeJobCursor(cursor jobCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeJobCursor(raw string) (jobCursor, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return jobCursor{}, false
+	}
+	var cursor jobCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return jobCursor{}, false
+	}
+	return cursor, true
+}
+
+// timeQuery は省略可能な RFC 3339 の時刻のクエリパラメータを読む。
+func timeQuery(c *gin.Context, name string) (pgtype.Timestamptz, bool) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return pgtype.Timestamptz{}, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return pgtype.Timestamptz{}, false
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}, true
+}
+
+// durationQuery は省略可能な 90m や 1h30m 形式のクエリパラメータを秒で返す。
+func durationQuery(c *gin.Context, name string) (*float64, bool) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, true
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return nil, false
+	}
+	seconds := d.Seconds()
+	return &seconds, true
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/middleware"
+	"github.com/kanaya/jobboard-hub/internal/response"
+)
+
+const (
+	defaultJobSearchLimit = 20
+	maxJobSearchLimit     = 100
+	maxJobSearchChars     = 256
+)
+
+// SearchJobs の snippet で一致部分を囲む文字。
+const (
+	snippetStartSel = "\x01"
+	snippetStopSel  = "\x02"
+)
+
+type jobSearchResponse struct {
+	Query   string            `json:"query"`
+	Results []jobSearchResult `json:"results"`
+}
+
+type jobSearchResult struct {
+	Job response.Job `json:"job"`
+	// Source は最もよく一致した箇所。error_text / stdout / stderr のいずれか
+	Source     string           `json:"source"`
+	Rank       float64          `json:"rank"`
+	MatchCount int64            `json:"match_count"`
+	Snippet    []snippetSegment `json:"snippet"`
+}
+
+// snippetSegment は snippet の一部分。HTML を組み立てずに返すので、クライアントはそのまま表示してよい。
+type snippetSegment struct {
+	Text      string `json:"text"`
+	Highlight bool   `json:"highlight,omitempty"`
+}
+
+// Search は error_text とログを全文検索する。q は websearch_to_tsquery の書式で、
+// "..." でフレーズ、or で OR、先頭の - で除外を指定できる。
+func (h *JobHandler) Search(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || utf8.RuneCountInString(query) > maxJobSearchChars {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	limit := defaultJobSearchLimit
+	if raw, ok := c.GetQuery("limit"); ok {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxJobSearchLimit {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		limit = v
+	}
+
+	rows, err := h.queries.SearchJobs(c.Request.Context(), repo.SearchJobsParams{
+		Query:      query,
+		ClusterID:  clusterID,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		log.Printf("failed to search jobs: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	resp := jobSearchResponse{Query: query, Results: make([]jobSearchResult, 0, len(rows))}
+	for _, row := range rows {
+		resp.Results = append(resp.Results, jobSearchResult{
+			Job:        response.NewJob(row.Job),
+			Source:     row.Source,
+			Rank:       row.Rank,
+			MatchCount: row.MatchCount,
+			Snippet:    parseSnippet(row.Snippet),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseSnippet は ts_headline の結果を一致部分とそれ以外に分ける。
+func parseSnippet(headline string) []snippetSegment {
+	segments := []snippetSegment{}
+	for headline != "" {
+		before, rest, found := strings.Cut(headline, snippetStartSel)
+		if before != "" {
+			segments = append(segments, snippetSegment{Text: before})
+		}
+		if !found {
+			break
+		}
+		match, after, _ := strings.Cut(rest, snippetStopSel)
+		if match != "" {
+			segments = append(segments, snippetSegment{Text: match, Highlight: true})
+		}
+		headline = after
+	}
+	return segments
+}
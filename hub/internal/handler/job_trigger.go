@@ -1,44 +1,176 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/anomaly"
 	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/blobstore"
+	"github.com/kanaya/jobboard-hub/internal/database"
 	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
+	"github.com/kanaya/jobboard-hub/internal/mailer"
+	"github.com/kanaya/jobboard-hub/internal/response"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 128
+)
+
+var (
+	errNodeSlotsFull = errors.New("all node slots are busy")
+	errLeaseLost     = errors.New("queue lease is no longer held by the node")
 )
 
 type JobTriggerHandler struct {
-	queries repo.Querier
+	db              *database.Database
+	queries         repo.Querier
+	store           blobstore.Store
+	maxArtifactSize int64
+	leaseTTL        time.Duration
+	mailer          *mailer.Mailer
+	detector        *anomaly.Detector
+	broker          events.Broker
 }
 
-func NewJobTriggerHandler(queries repo.Querier) *JobTriggerHandler {
+func NewJobTriggerHandler(db *database.Database, queries repo.Querier, store blobstore.Store, maxArtifactSize int64, leaseTTL time.Duration, mailer *mailer.Mailer, detector *anomaly.Detector, broker events.Broker) *JobTriggerHandler {
 	return &JobTriggerHandler{
-		queries: queries,
+		db:              db,
+		queries:         queries,
+		store:           store,
+		maxArtifactSize: maxArtifactSize,
+		leaseTTL:        leaseTTL,
+		mailer:          mailer,
+		detector:        detector,
+		broker:          broker,
 	}
 }
 
 type startJobRequest struct {
-	NodeToken string     `json:"node_token" binding:"required"`
-	Tag       *string    `json:"tag"`
-	StartedAt *time.Time `json:"started_at"`
+	NodeToken  string            `json:"node_token" binding:"required"`
+	RunID      *string           `json:"run_id" binding:"omitempty,uuid"`
+	Tag        *string           `json:"tag"`
+	StartedAt  *time.Time        `json:"started_at"`
+	Command    []string          `json:"command"`
+	WorkingDir *string           `json:"working_dir"`
+	Labels     map[string]string `json:"labels"`
+	// QueueID は agent がキューから借りたジョブを start する場合に指定する
+	QueueID *int64 `json:"queue_id"`
+}
+
+type leaseJobRequest struct {
+	NodeToken string `json:"node_token" binding:"required"`
+}
+
+type leaseJobResponse struct {
+	QueueID        int64             `json:"queue_id"`
+	Command        []string          `json:"command"`
+	WorkingDir     *string           `json:"working_dir"`
+	Tag            *string           `json:"tag"`
+	Labels         map[string]string `json:"labels"`
+	Attempt        int32             `json:"attempt"`
+	LeaseExpiresAt time.Time         `json:"lease_expires_at"`
+}
+
+type previousRunRequest struct {
+	NodeToken string `json:"node_token" binding:"required"`
+	Tag       string `json:"tag" binding:"required"`
+	// JobID は判定中のジョブ。結果から除く
+	JobID int64 `json:"job_id"`
+}
+
+type previousRunResponse struct {
+	JobID      int64     `json:"job_id"`
+	NodeID     int64     `json:"node_id"`
+	Status     string    `json:"status"`
+	ExitCode   *int32    `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
 }
 
 type finishJobRequest struct {
-	NodeToken     string     `json:"node_token" binding:"required"`
-	Status        string     `json:"status" binding:"omitempty,oneof=completed failed"`
-	FinishedAt    *time.Time `json:"finished_at"`
-	DurationHours *float64   `json:"duration_hours"`
-	ErrorText     *string    `json:"error_text"`
+	NodeToken     string            `json:"node_token" binding:"required"`
+	JobID         int64             `json:"job_id" binding:"required"`
+	Status        string            `json:"status" binding:"omitempty,oneof=completed failed timed_out cancelled"`
+	FinishedAt    *time.Time        `json:"finished_at"`
+	DurationHours *float64          `json:"duration_hours"`
+	ErrorText     *string           `json:"error_text"`
+	ExitCode      *int32            `json:"exit_code"`
+	Signal        *string           `json:"signal" binding:"omitempty,max=16"`
+	Resources     *resourcesRequest `json:"resources"`
+}
+
+type resourcesRequest struct {
+	CPUUserSeconds   *float64 `json:"cpu_user_seconds" binding:"omitempty,min=0"`
+	CPUSystemSeconds *float64 `json:"cpu_system_seconds" binding:"omitempty,min=0"`
+	MaxRSSBytes      *int64   `json:"max_rss_bytes" binding:"omitempty,min=0"`
+	IOReadBytes      *int64   `json:"io_read_bytes" binding:"omitempty,min=0"`
+	IOWriteBytes     *int64   `json:"io_write_bytes" binding:"omitempty,min=0"`
+}
+
+type heartbeatRequest struct {
+	NodeToken string     `json:"node_token" binding:"required"`
+	JobID     int64      `json:"job_id" binding:"required"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+type progressRequest struct {
+	NodeToken  string     `json:"node_token" binding:"required"`
+	JobID      int64      `json:"job_id" binding:"required"`
+	Percent    *float64   `json:"percent" binding:"omitempty,min=0,max=100"`
+	Step       *int64     `json:"step" binding:"omitempty,min=0"`
+	Total      *int64     `json:"total" binding:"omitempty,min=0"`
+	Phase      *string    `json:"phase" binding:"omitempty,max=256"`
+	ReportedAt *time.Time `json:"reported_at"`
+}
+
+type logChunkRequest struct {
+	Stream string `json:"stream" binding:"required,oneof=stdout stderr"`
+	Seq    int64  `json:"seq" binding:"min=0"`
+	Offset int64  `json:"offset" binding:"min=0"`
+	Data   string `json:"data"`
+}
+
+type appendLogsRequest struct {
+	NodeToken string            `json:"node_token" binding:"required"`
+	JobID     int64             `json:"job_id" binding:"required"`
+	Chunks    []logChunkRequest `json:"chunks" binding:"required,max=256,dive"`
+}
+
+type metricPointRequest struct {
+	Key       string     `json:"key" binding:"required,max=128"`
+	Step      int64      `json:"step" binding:"min=0"`
+	Value     float64    `json:"value"`
+	Timestamp *time.Time `json:"timestamp"`
+}
+
+type ingestMetricsRequest struct {
+	NodeToken string               `json:"node_token" binding:"required"`
+	JobID     int64                `json:"job_id" binding:"required"`
+	Points    []metricPointRequest `json:"points" binding:"required,max=1000,dive"`
 }
 
 type JobTriggerResponse struct {
-	Success bool `json:"success"`
+	Success bool   `json:"success"`
+	JobID   *int64 `json:"job_id,omitempty"`
+	// CancelRequested はハートビートの応答でのみ返す。true なら CLI はジョブを取り消す
+	CancelRequested   bool    `json:"cancel_requested,omitempty"`
+	CancelRequestedBy *string `json:"cancel_requested_by,omitempty"`
 }
 
 func (h *JobTriggerHandler) StartJob(c *gin.Context) {
@@ -48,13 +180,33 @@ func (h *JobTriggerHandler) StartJob(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(req.Labels) {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if req.Labels == nil {
+		req.Labels = map[string]string{}
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	var runID pgtype.UUID
+	if req.RunID != nil {
+		if err := runID.Scan(*req.RunID); err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+	}
+
 	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
 	if !ok {
 		return
 	}
 
-	if node.CurrentJobID != nil {
-		apierror.Write(c, apierror.JobAlreadyRunning)
+	if h.replayStoredResponse(c, node.ID) {
 		return
 	}
 
@@ -63,30 +215,178 @@ func (h *JobTriggerHandler) StartJob(c *gin.Context) {
 		started = timestamptz(*req.StartedAt)
 	}
 
-	job, err := h.queries.CreateJob(c.Request.Context(), repo.CreateJobParams{
-		ClusterID: node.ClusterID,
-		NodeID:    node.ID,
-		Column3:   started,
-		Column4:   nil,
-		Tag:       req.Tag,
+	var (
+		job     repo.Job
+		created bool
+		// startedItem は start したキューのジョブ。キューから借りていなければ nil
+		startedItem *repo.JobQueue
+	)
+	err = h.withIdempotentNodeLock(c, node.ID, func(q *repo.Queries, node repo.Node) error {
+		// 同じ run_id の start が再送された場合は、作成済みのジョブを返す
+		if runID.Valid {
+			existing, err := q.GetJobByNodeAndRunID(c.Request.Context(), repo.GetJobByNodeAndRunIDParams{
+				NodeID: node.ID,
+				RunID:  runID,
+			})
+			if err == nil {
+				job = existing
+				return nil
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+
+		var leased *repo.JobQueue
+		if req.QueueID != nil {
+			item, err := q.GetLeasedQueuedJobForUpdate(c.Request.Context(), repo.GetLeasedQueuedJobForUpdateParams{
+				ID:           *req.QueueID,
+				LeasedNodeID: &node.ID,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errLeaseLost
+			}
+			if err != nil {
+				return err
+			}
+			leased = &item
+		}
+
+		used, err := usedSlots(c.Request.Context(), q, node.ID)
+		if err != nil {
+			return err
+		}
+		// 借りているジョブ自身のリースは、これから始めるジョブのスロットとして数えない
+		if leased != nil && leased.LeaseExpiresAt.Time.After(time.Now()) {
+			used--
+		}
+		if used >= int64(node.Slots) {
+			return errNodeSlotsFull
+		}
+
+		job, err = q.CreateJob(c.Request.Context(), repo.CreateJobParams{
+			ClusterID:  node.ClusterID,
+			NodeID:     node.ID,
+			Column3:    started,
+			Column4:    nil,
+			Tag:        req.Tag,
+			Command:    req.Command,
+			WorkingDir: req.WorkingDir,
+			RunID:      runID,
+			Labels:     labels,
+			QueueID:    req.QueueID,
+		})
+		if err != nil {
+			return err
+		}
+		if leased != nil {
+			item, err := q.MarkQueuedJobStarted(c.Request.Context(), repo.MarkQueuedJobStartedParams{
+				ID:    leased.ID,
+				JobID: &job.ID,
+			})
+			if err != nil {
+				return err
+			}
+			startedItem = &item
+		}
+
+		created = true
+		return nil
+	}, func() (int, JobTriggerResponse) {
+		if created {
+			return http.StatusCreated, JobTriggerResponse{Success: true, JobID: &job.ID}
+		}
+		return http.StatusOK, JobTriggerResponse{Success: true, JobID: &job.ID}
 	})
 	if err != nil {
-		log.Printf("failed to create job: %v", err)
+		if errors.Is(err, errNodeSlotsFull) {
+			apierror.Write(c, apierror.NodeSlotsFull)
+			return
+		}
+		if errors.Is(err, errLeaseLost) {
+			apierror.Write(c, apierror.QueueLeaseLost)
+			return
+		}
+		log.Printf("failed to start job: %v", err)
 		apierror.Write(c, apierror.Internal)
 		return
 	}
 
-	_, err = h.queries.UpdateNodeCurrentJob(c.Request.Context(), repo.UpdateNodeCurrentJobParams{
-		ID:           node.ID,
-		CurrentJobID: &job.ID,
+	if created {
+		events.PublishJob(h.broker, events.JobCreated, job)
+		events.PublishNode(c.Request.Context(), h.broker, h.queries, node.ClusterID, node.ID)
+	}
+	if startedItem != nil {
+		events.PublishQueuedJob(h.broker, events.QueueUpdated, *startedItem)
+	}
+}
+
+// LeaseJob は jobboard agent にキューのジョブを 1 件貸し出す。貸し出せるジョブがなければ 204 を返す。
+// 貸し出したジョブは leaseTTL 以内に queue_id 付きで start されなければ、再びキューに戻る。
+func (h *JobTriggerHandler) LeaseJob(c *gin.Context) {
+	var req leaseJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	var (
+		item   repo.JobQueue
+		leased bool
+	)
+	err := h.withNodeLock(c.Request.Context(), node.ID, func(q *repo.Queries, node repo.Node) error {
+		used, err := usedSlots(c.Request.Context(), q, node.ID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(node.Slots) {
+			return errNodeSlotsFull
+		}
+
+		item, err = q.LeaseQueuedJob(c.Request.Context(), repo.LeaseQueuedJobParams{
+			NodeID:         node.ID,
+			LeaseExpiresAt: timestamptz(time.Now().Add(h.leaseTTL)),
+			ClusterID:      node.ClusterID,
+			NodeLabels:     node.Labels,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		leased = true
+		return nil
 	})
 	if err != nil {
-		log.Printf("failed to update node state: %v", err)
+		if errors.Is(err, errNodeSlotsFull) {
+			apierror.Write(c, apierror.NodeSlotsFull)
+			return
+		}
+		log.Printf("failed to lease queued job: %v", err)
 		apierror.Write(c, apierror.Internal)
 		return
 	}
+	if !leased {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	events.PublishQueuedJob(h.broker, events.QueueUpdated, item)
 
-	c.JSON(http.StatusCreated, JobTriggerResponse{Success: true})
+	c.JSON(http.StatusOK, leaseJobResponse{
+		QueueID:        item.ID,
+		Command:        item.Command,
+		WorkingDir:     item.WorkingDir,
+		Tag:            item.Tag,
+		Labels:         response.Labels(item.Labels),
+		Attempt:        item.Attempts,
+		LeaseExpiresAt: item.LeaseExpiresAt.Time,
+	})
 }
 
 func (h *JobTriggerHandler) FinishJob(c *gin.Context) {
@@ -101,8 +401,7 @@ func (h *JobTriggerHandler) FinishJob(c *gin.Context) {
 		return
 	}
 
-	if node.CurrentJobID == nil {
-		apierror.Write(c, apierror.JobNotRunning)
+	if h.replayStoredResponse(c, node.ID) {
 		return
 	}
 
@@ -123,33 +422,603 @@ func (h *JobTriggerHandler) FinishJob(c *gin.Context) {
 		duration = intervalFromHours(*req.DurationHours)
 	}
 
-	_, err := h.queries.UpdateJob(c.Request.Context(), repo.UpdateJobParams{
-		ID:            *node.CurrentJobID,
-		StartedAt:     pgtype.Timestamptz{},
-		FinishedAt:    timestamptz(finishedAt),
-		Status:        status,
-		DurationHours: duration,
-		ErrorText:     req.ErrorText,
+	var resources resourcesRequest
+	if req.Resources != nil {
+		resources = *req.Resources
+	}
+
+	var finished *repo.Job
+	err := h.withIdempotentNodeLock(c, node.ID, func(q *repo.Queries, node repo.Node) error {
+		job, err := q.GetJobByNodeAndJobID(c.Request.Context(), repo.GetJobByNodeAndJobIDParams{
+			NodeID: node.ID,
+			ID:     req.JobID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 終了済みのジョブへの再送は何もしない。
+		// lost はハートビートが遅れただけの場合があるため、実際の結果で上書きする
+		if job.FinishedAt.Valid && job.Status != "lost" {
+			return nil
+		}
+
+		updated, err := q.UpdateJob(c.Request.Context(), repo.UpdateJobParams{
+			ID:                job.ID,
+			StartedAt:         pgtype.Timestamptz{},
+			FinishedAt:        timestamptz(finishedAt),
+			Status:            status,
+			DurationHours:     duration,
+			ErrorText:         req.ErrorText,
+			ExitCode:          req.ExitCode,
+			TerminationSignal: req.Signal,
+			CpuUserSeconds:    resources.CPUUserSeconds,
+			CpuSystemSeconds:  resources.CPUSystemSeconds,
+			MaxRssBytes:       resources.MaxRSSBytes,
+			IoReadBytes:       resources.IOReadBytes,
+			IoWriteBytes:      resources.IOWriteBytes,
+		})
+		if err != nil {
+			return err
+		}
+		// キューに戻して再び貸し出した後なら、古い試行の結果で上書きしない。
+		// UpdateJob で jobs の行をロックしてから確かめるので、Reaper が同時にキューへ戻すことはない
+		if job.Status == "lost" && job.QueueID != nil {
+			item, err := q.GetQueuedJobByCluster(c.Request.Context(), repo.GetQueuedJobByClusterParams{
+				ID:        *job.QueueID,
+				ClusterID: node.ClusterID,
+			})
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+			if err == nil && (item.JobID == nil || *item.JobID != job.ID || item.Status == "queued" || item.Status == "leased") {
+				return errLeaseLost
+			}
+		}
+		finished = &updated
+		return nil
+	}, func() (int, JobTriggerResponse) {
+		return http.StatusOK, JobTriggerResponse{Success: true, JobID: &req.JobID}
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotFound)
+			return
+		}
+		if errors.Is(err, errLeaseLost) {
+			apierror.Write(c, apierror.QueueLeaseLost)
+			return
+		}
+		log.Printf("failed to finish job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	if finished != nil {
+		*finished = h.detector.JobFinished(c.Request.Context(), *finished, node.NodeName)
+		h.mailer.JobFinished(*finished, node.NodeName)
+		events.PublishJob(h.broker, events.JobFinished, *finished)
+		events.PublishNode(c.Request.Context(), h.broker, h.queries, node.ClusterID, node.ID)
+	}
+}
+
+// PreviousRun はクラスター内で同じタグを持つ、最後に終了したジョブを返す。
+// CLI が前回から結果が変わったときだけ通知するために使う。なければ 204 を返す。
+func (h *JobTriggerHandler) PreviousRun(c *gin.Context) {
+	var req previousRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	job, err := h.queries.GetLastFinishedJobByTag(c.Request.Context(), repo.GetLastFinishedJobByTagParams{
+		ClusterID: node.ClusterID,
+		Tag:       req.Tag,
+		ExcludeID: req.JobID,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Status(http.StatusNoContent)
+		return
+	}
 	if err != nil {
-		log.Printf("failed to update job: %v", err)
+		log.Printf("failed to get previous run: %v", err)
 		apierror.Write(c, apierror.Internal)
 		return
 	}
 
-	_, err = h.queries.UpdateNodeCurrentJob(c.Request.Context(), repo.UpdateNodeCurrentJobParams{
-		ID:           node.ID,
-		CurrentJobID: nil,
+	c.JSON(http.StatusOK, previousRunResponse{
+		JobID:      job.ID,
+		NodeID:     job.NodeID,
+		Status:     job.Status,
+		ExitCode:   job.ExitCode,
+		StartedAt:  job.StartedAt.Time,
+		FinishedAt: job.FinishedAt.Time,
+	})
+}
+
+func (h *JobTriggerHandler) Heartbeat(c *gin.Context) {
+	var req heartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	if h.replayStoredResponse(c, node.ID) {
+		return
+	}
+
+	var sentAt pgtype.Timestamptz
+	if req.SentAt != nil {
+		sentAt = timestamptz(*req.SentAt)
+	}
+
+	job, err := h.queries.RecordJobHeartbeat(c.Request.Context(), repo.RecordJobHeartbeatParams{
+		SentAt: sentAt,
+		ID:     req.JobID,
+		NodeID: node.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotRunning)
+			return
+		}
+		log.Printf("failed to record heartbeat: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	h.writeResponse(c, node.ID, http.StatusOK, JobTriggerResponse{
+		Success:           true,
+		CancelRequested:   job.CancelRequestedAt.Valid,
+		CancelRequestedBy: job.CancelRequestedBy,
+	})
+}
+
+func (h *JobTriggerHandler) ReportProgress(c *gin.Context) {
+	var req progressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	job, ok := h.getJobByNode(c, node.ID, req.JobID)
+	if !ok {
+		return
+	}
+	if job.FinishedAt.Valid {
+		apierror.Write(c, apierror.JobNotRunning)
+		return
+	}
+
+	// 未来の時刻で後続の報告を締め出さないよう、受信時刻で頭打ちにする
+	reportedAt := time.Now()
+	if req.ReportedAt != nil && req.ReportedAt.Before(reportedAt) {
+		reportedAt = *req.ReportedAt
+	}
+
+	// 0 件なら、より新しい進捗がすでに記録されているので読み捨てる
+	updated, err := h.queries.UpdateJobProgress(c.Request.Context(), repo.UpdateJobProgressParams{
+		ID:                job.ID,
+		NodeID:            node.ID,
+		ProgressPercent:   req.Percent,
+		ProgressStep:      req.Step,
+		ProgressTotal:     req.Total,
+		ProgressPhase:     req.Phase,
+		ProgressUpdatedAt: timestamptz(reportedAt),
 	})
 	if err != nil {
-		log.Printf("failed to reset node state: %v", err)
+		log.Printf("failed to update job progress: %v", err)
 		apierror.Write(c, apierror.Internal)
 		return
 	}
+	if updated > 0 {
+		job.ProgressPercent = req.Percent
+		job.ProgressStep = req.Step
+		job.ProgressTotal = req.Total
+		job.ProgressPhase = req.Phase
+		job.ProgressUpdatedAt = timestamptz(reportedAt)
+		events.PublishJob(h.broker, events.JobUpdated, job)
+	}
 
 	c.JSON(http.StatusOK, JobTriggerResponse{Success: true})
 }
 
+func (h *JobTriggerHandler) AppendLogs(c *gin.Context) {
+	var req appendLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.getJobByNode(c, node.ID, req.JobID); !ok {
+		return
+	}
+
+	for _, chunk := range req.Chunks {
+		// 再送されたチャンクは (job_id, stream, seq) の一意制約で読み捨てる
+		err := h.queries.CreateJobLogChunk(c.Request.Context(), repo.CreateJobLogChunkParams{
+			JobID:      req.JobID,
+			Stream:     chunk.Stream,
+			Seq:        chunk.Seq,
+			ByteOffset: chunk.Offset,
+			Data:       chunk.Data,
+		})
+		if err != nil {
+			log.Printf("failed to store job log chunk: %v", err)
+			apierror.Write(c, apierror.Internal)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, JobTriggerResponse{Success: true})
+}
+
+// IngestMetrics は学習スクリプトなどが記録した時系列の値をまとめて保存する。
+// 同じ (key, step) の点は後から届いた値で上書きするため、再送しても結果は変わらない。
+func (h *JobTriggerHandler) IngestMetrics(c *gin.Context) {
+	var req ingestMetricsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, req.NodeToken)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.getJobByNode(c, node.ID, req.JobID); !ok {
+		return
+	}
+
+	params := repo.CreateJobMetricsParams{JobID: req.JobID}
+	// 同じ行を 1 つの INSERT で二度更新できないため、バッチ内の重複は後勝ちでまとめる
+	index := make(map[string]int, len(req.Points))
+	now := time.Now()
+	for _, point := range req.Points {
+		recordedAt := now
+		if point.Timestamp != nil && point.Timestamp.Before(now) {
+			recordedAt = *point.Timestamp
+		}
+
+		id := point.Key + "\x00" + strconv.FormatInt(point.Step, 10)
+		if i, ok := index[id]; ok {
+			params.MetricValues[i] = point.Value
+			params.RecordedAts[i] = timestamptz(recordedAt)
+			continue
+		}
+		index[id] = len(params.Keys)
+		params.Keys = append(params.Keys, point.Key)
+		params.Steps = append(params.Steps, point.Step)
+		params.MetricValues = append(params.MetricValues, point.Value)
+		params.RecordedAts = append(params.RecordedAts, timestamptz(recordedAt))
+	}
+
+	if err := h.queries.CreateJobMetrics(c.Request.Context(), params); err != nil {
+		log.Printf("failed to store job metrics: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	c.JSON(http.StatusOK, JobTriggerResponse{Success: true})
+}
+
+// UploadArtifact は multipart/form-data でジョブの成果物を 1 件受け取り、blob store に保存する。
+// node_token / job_id / name / size を file より前に送る必要がある。sha256 は省略でき、
+// 送られた場合は受信した中身のハッシュと一致しなければ保存しない。
+// 同じ名前のアーティファクトがあれば置き換える。
+func (h *JobTriggerHandler) UploadArtifact(c *gin.Context) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	fields := map[string]string{}
+	var file *multipart.Part
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+			break
+		}
+		value, ok := readUploadField(part)
+		if !ok {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		fields[part.FormName()] = value
+	}
+
+	jobID, err := strconv.ParseInt(fields["job_id"], 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	size, err := strconv.ParseInt(fields["size"], 10, 64)
+	if err != nil || size < 0 {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	name := fields["name"]
+	if fields["node_token"] == "" || !validArtifactName(name) {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if size > h.maxArtifactSize {
+		apierror.Write(c, apierror.ArtifactTooLarge)
+		return
+	}
+
+	node, ok := h.getNodeByNodeToken(c, fields["node_token"])
+	if !ok {
+		return
+	}
+	job, ok := h.getJobByNode(c, node.ID, jobID)
+	if !ok {
+		return
+	}
+
+	storageKey, err := artifactStorageKey(job.ID)
+	if err != nil {
+		log.Printf("failed to generate artifact key: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	ctx := c.Request.Context()
+	hash := sha256.New()
+	if err := h.store.Put(ctx, storageKey, io.TeeReader(file, hash), size); err != nil {
+		if errors.Is(err, blobstore.ErrSizeMismatch) {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		log.Printf("failed to store artifact: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	// file の後ろに sha256 が送られてくる場合もあるため、残りの項目も読む
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		var value string
+		if err == nil {
+			value, ok = readUploadField(part)
+		}
+		if err != nil || !ok {
+			h.deleteBlob(storageKey)
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		fields[part.FormName()] = value
+	}
+	if expected := fields["sha256"]; expected != "" && !strings.EqualFold(expected, sum) {
+		h.deleteBlob(storageKey)
+		apierror.Write(c, apierror.InvalidRequest, apierror.WithDetail("sha256 does not match"))
+		return
+	}
+
+	previous, err := h.queries.GetJobArtifactByJobAndName(ctx, repo.GetJobArtifactByJobAndNameParams{
+		JobID: job.ID,
+		Name:  name,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.deleteBlob(storageKey)
+		log.Printf("failed to load job artifact: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	artifact, err := h.queries.UpsertJobArtifact(ctx, repo.UpsertJobArtifactParams{
+		JobID:      job.ID,
+		Name:       name,
+		SizeBytes:  size,
+		Sha256:     sum,
+		StorageKey: storageKey,
+	})
+	if err != nil {
+		h.deleteBlob(storageKey)
+		log.Printf("failed to store job artifact: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+	if previous.StorageKey != "" && previous.StorageKey != artifact.StorageKey {
+		h.deleteBlob(previous.StorageKey)
+	}
+
+	c.JSON(http.StatusCreated, artifactToResponse(artifact))
+}
+
+// deleteBlob は不要になった blob を消す。リクエストが中断されても消せるよう独立した context を使う。
+func (h *JobTriggerHandler) deleteBlob(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.store.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete artifact blob %s: %v", key, err)
+	}
+}
+
+// replayStoredResponse は Idempotency-Key が処理済みであれば保存済みのレスポンスを返す。
+// CLI のスプールから再送されたイベントを二重に適用しないために使う。
+func (h *JobTriggerHandler) replayStoredResponse(c *gin.Context, nodeID int64) bool {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return false
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		apierror.Write(c, apierror.InvalidRequest)
+		return true
+	}
+
+	stored, err := h.queries.GetTriggerRequest(c.Request.Context(), repo.GetTriggerRequestParams{
+		NodeID:         nodeID,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false
+		}
+		log.Printf("failed to load trigger request: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return true
+	}
+
+	c.Data(int(stored.StatusCode), "application/json; charset=utf-8", stored.Response)
+	return true
+}
+
+// writeResponse はレスポンスを返し、Idempotency-Key があれば再送に備えて保存する。
+// 二重に適用すると困る操作には withIdempotentNodeLock を使う。
+func (h *JobTriggerHandler) writeResponse(c *gin.Context, nodeID int64, status int, resp JobTriggerResponse) {
+	if key := c.GetHeader(idempotencyKeyHeader); key != "" {
+		body, err := json.Marshal(resp)
+		if err == nil {
+			err = h.queries.CreateTriggerRequest(c.Request.Context(), repo.CreateTriggerRequestParams{
+				NodeID:         nodeID,
+				IdempotencyKey: key,
+				StatusCode:     int32(status),
+				Response:       body,
+			})
+		}
+		if err != nil {
+			log.Printf("failed to store trigger request: %v", err)
+		}
+	}
+
+	c.JSON(status, resp)
+}
+
+// withIdempotentNodeLock は withNodeLock で fn を実行してから respond のレスポンスを返す。
+// Idempotency-Key があれば、レスポンスを fn と同じトランザクションで保存するので、適用したのに保存されていない、
+// あるいは保存したのに適用されていない状態にはならない。ロックを取ってから処理済みかを確かめ直すので、
+// 同じキーの再送が並行しても適用は 1 回になり、後から来た方には保存済みのレスポンスを返す。
+func (h *JobTriggerHandler) withIdempotentNodeLock(c *gin.Context, nodeID int64, fn func(q *repo.Queries, node repo.Node) error, respond func() (int, JobTriggerResponse)) error {
+	ctx := c.Request.Context()
+	key := c.GetHeader(idempotencyKeyHeader)
+
+	var (
+		stored *repo.TriggerRequest
+		status int
+		resp   JobTriggerResponse
+	)
+	err := h.withNodeLock(ctx, nodeID, func(q *repo.Queries, node repo.Node) error {
+		if key != "" {
+			existing, err := q.GetTriggerRequest(ctx, repo.GetTriggerRequestParams{
+				NodeID:         node.ID,
+				IdempotencyKey: key,
+			})
+			if err == nil {
+				stored = &existing
+				return nil
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+
+		if err := fn(q, node); err != nil {
+			return err
+		}
+		status, resp = respond()
+		if key == "" {
+			return nil
+		}
+
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		return q.CreateTriggerRequest(ctx, repo.CreateTriggerRequestParams{
+			NodeID:         node.ID,
+			IdempotencyKey: key,
+			StatusCode:     int32(status),
+			Response:       body,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if stored != nil {
+		c.Data(int(stored.StatusCode), "application/json; charset=utf-8", stored.Response)
+		return nil
+	}
+	c.JSON(status, resp)
+	return nil
+}
+
+// withNodeLock はノードの行をロックしたトランザクション内で fn を実行する。
+// 同じノードへの start が並行しても、スロット数を超えてジョブが作られないようにする。
+func (h *JobTriggerHandler) withNodeLock(ctx context.Context, nodeID int64, fn func(q *repo.Queries, node repo.Node) error) error {
+	return pgx.BeginFunc(ctx, h.db.Pool, func(tx pgx.Tx) error {
+		q := repo.New(tx)
+
+		node, err := q.GetNodeForUpdate(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+		return fn(q, node)
+	})
+}
+
+// usedSlots は実行中のジョブと、まだ start されていない有効なリースの合計を返す。
+func usedSlots(ctx context.Context, q *repo.Queries, nodeID int64) (int64, error) {
+	active, err := q.CountActiveJobsByNode(ctx, nodeID)
+	if err != nil {
+		return 0, err
+	}
+	leases, err := q.CountActiveLeasesByNode(ctx, &nodeID)
+	if err != nil {
+		return 0, err
+	}
+	return active + leases, nil
+}
+
+func (h *JobTriggerHandler) getJobByNode(c *gin.Context, nodeID, jobID int64) (repo.Job, bool) {
+	job, err := h.queries.GetJobByNodeAndJobID(c.Request.Context(), repo.GetJobByNodeAndJobIDParams{
+		NodeID: nodeID,
+		ID:     jobID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.JobNotFound)
+			return repo.Job{}, false
+		}
+		log.Printf("failed to load job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return repo.Job{}, false
+	}
+	return job, true
+}
+
 func (h *JobTriggerHandler) getNodeByNodeToken(c *gin.Context, secret string) (repo.Node, bool) {
 	node, err := h.queries.GetNodeByNodeTokenHash(c.Request.Context(), hashNodeToken(secret))
 	if err != nil {
@@ -178,11 +1047,3 @@ func intervalFromHours(hours float64) pgtype.Interval {
 		Valid:        true,
 	}
 }
-
-func intervalToHours(iv pgtype.Interval) *float64 {
-	if !iv.Valid {
-		return nil
-	}
-	hours := float64(iv.Microseconds) / float64(time.Hour/time.Microsecond)
-	return &hours
-}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxJobLabels       = 32
+	maxLabelValueChars = 256
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-/]{0,62}$`)
+
+func validLabels(labels map[string]string) bool {
+	if len(labels) > maxJobLabels {
+		return false
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) || utf8.RuneCountInString(value) > maxLabelValueChars {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelector は ?label=key=value / ?label=key!=value の組み合わせ。
+// 一致条件は 1 つの JSON オブジェクトにまとめて GIN インデックスで絞り込む。
+type labelSelector struct {
+	match   map[string]string
+	exclude []map[string]string
+	// 同じキーに異なる値の一致条件が指定され、どのジョブにも一致しない
+	empty bool
+}
+
+func parseLabelSelectors(values []string) (labelSelector, bool) {
+	sel := labelSelector{
+		match:   map[string]string{},
+		exclude: []map[string]string{},
+	}
+	for _, raw := range values {
+		key, value, negate := raw, "", false
+		if k, v, ok := strings.Cut(raw, "!="); ok {
+			key, value, negate = k, v, true
+		} else if k, v, ok := strings.Cut(raw, "="); ok {
+			key, value = k, v
+		} else {
+			return labelSelector{}, false
+		}
+
+		key = strings.TrimSpace(key)
+		if !labelKeyPattern.MatchString(key) {
+			return labelSelector{}, false
+		}
+
+		if negate {
+			sel.exclude = append(sel.exclude, map[string]string{key: value})
+			continue
+		}
+		if existing, ok := sel.match[key]; ok && existing != value {
+			sel.empty = true
+		}
+		sel.match[key] = value
+	}
+	return sel, true
+}
+
+func (s labelSelector) params() (match, exclude []byte, err error) {
+	if match, err = json.Marshal(s.match); err != nil {
+		return nil, nil, err
+	}
+	if exclude, err = json.Marshal(s.exclude); err != nil {
+		return nil, nil, err
+	}
+	return match, exclude, nil
+}
@@ -5,47 +5,34 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/kanaya/jobboard-hub/internal/apierror"
 	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
 	"github.com/kanaya/jobboard-hub/internal/middleware"
+	"github.com/kanaya/jobboard-hub/internal/response"
 )
 
 type NodeHandler struct {
 	queries repo.Querier
+	broker  events.Broker
 }
 
-func NewNodeHandler(queries repo.Querier) *NodeHandler {
+func NewNodeHandler(queries repo.Querier, broker events.Broker) *NodeHandler {
 	return &NodeHandler{
 		queries: queries,
+		broker:  broker,
 	}
 }
 
-type nodeResponse struct {
-	ID           int64     `json:"id"`
-	NodeName     string    `json:"node_name"`
-	CurrentJobID *int64    `json:"current_job_id"`
-	CreatedAt    time.Time `json:"created_at"`
-	NodeToken    string    `json:"node_token,omitempty"`
-}
-
-func nodeToResponse(node repo.Node) nodeResponse {
-	var createdAt time.Time
-	if node.CreatedAt.Valid {
-		createdAt = node.CreatedAt.Time
-	}
-	return nodeResponse{
-		ID:           node.ID,
-		NodeName:     node.NodeName,
-		CurrentJobID: node.CurrentJobID,
-		CreatedAt:    createdAt,
-	}
-}
+const defaultNodeSlots = 1
 
 func (h *NodeHandler) List(c *gin.Context) {
 	clusterID := c.GetString(middleware.ClusterIDContextKey)
@@ -55,19 +42,33 @@ func (h *NodeHandler) List(c *gin.Context) {
 		apierror.Write(c, apierror.Internal)
 		return
 	}
-	resp := make([]nodeResponse, 0, len(nodes))
+
+	running, ok := h.runningJobIDsByNode(c, clusterID)
+	if !ok {
+		return
+	}
+
+	resp := make([]response.Node, 0, len(nodes))
 	for _, node := range nodes {
-		resp = append(resp, nodeToResponse(node))
+		resp = append(resp, response.NewNode(node, running[node.ID]))
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
 type createNodeRequest struct {
-	NodeName string `json:"node_name" binding:"required"`
+	NodeName string            `json:"node_name" binding:"required"`
+	Slots    *int32            `json:"slots" binding:"omitempty,min=1,max=256"`
+	Labels   map[string]string `json:"labels"`
+}
+
+// updateNodeRequest は指定した項目だけを更新する。labels は丸ごと置き換える。
+type updateNodeRequest struct {
+	Slots  *int32            `json:"slots" binding:"omitempty,min=1,max=256"`
+	Labels map[string]string `json:"labels"`
 }
 
 type createNodeResponse struct {
-	nodeResponse
+	response.Node
 	NodeToken string `json:"node_token"`
 }
 
@@ -78,6 +79,19 @@ func (h *NodeHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if !validLabels(req.Labels) {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if req.Labels == nil {
+		req.Labels = map[string]string{}
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
 	clusterID := c.GetString(middleware.ClusterIDContextKey)
 	nodeToken, err := generateNodeToken()
 	if err != nil {
@@ -86,10 +100,17 @@ func (h *NodeHandler) Create(c *gin.Context) {
 		return
 	}
 
+	slots := int32(defaultNodeSlots)
+	if req.Slots != nil {
+		slots = *req.Slots
+	}
+
 	node, err := h.queries.CreateNode(c.Request.Context(), repo.CreateNodeParams{
 		ClusterID:     clusterID,
 		NodeName:      req.NodeName,
 		NodeTokenHash: hashNodeToken(nodeToken),
+		Slots:         slots,
+		Labels:        labels,
 	})
 	if err != nil {
 		log.Printf("failed to create node: %v", err)
@@ -97,12 +118,72 @@ func (h *NodeHandler) Create(c *gin.Context) {
 		return
 	}
 
+	h.broker.Publish(clusterID, events.NodeCreated, response.NewNode(node, nil))
+
 	c.JSON(http.StatusOK, createNodeResponse{
-		nodeResponse: nodeToResponse(node),
-		NodeToken:    nodeToken,
+		Node:      response.NewNode(node, nil),
+		NodeToken: nodeToken,
 	})
 }
 
+func (h *NodeHandler) Update(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	nodeID, err := strconv.ParseInt(c.Param("node_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	var req updateNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	if req.Slots == nil && req.Labels == nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	var labels []byte
+	if req.Labels != nil {
+		if !validLabels(req.Labels) {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		if labels, err = json.Marshal(req.Labels); err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+	}
+
+	// スロットを減らしても実行中のジョブは止めず、新しい start だけを制限する
+	node, err := h.queries.UpdateNodeByCluster(c.Request.Context(), repo.UpdateNodeByClusterParams{
+		Slots:     req.Slots,
+		Labels:    labels,
+		ID:        nodeID,
+		ClusterID: clusterID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.NodeNotFound)
+			return
+		}
+		log.Printf("failed to update node: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	running, ok := h.runningJobIDsByNode(c, clusterID)
+	if !ok {
+		return
+	}
+
+	resp := response.NewNode(node, running[node.ID])
+	h.broker.Publish(clusterID, events.NodeUpdated, resp)
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *NodeHandler) Delete(c *gin.Context) {
 	clusterID := c.GetString(middleware.ClusterIDContextKey)
 	nodeID, err := strconv.ParseInt(c.Param("node_id"), 10, 64)
@@ -127,9 +208,26 @@ func (h *NodeHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	h.broker.Publish(clusterID, events.NodeDeleted, nodeDeletedEvent{ID: nodeID})
+
 	c.Status(http.StatusNoContent)
 }
 
+func (h *NodeHandler) runningJobIDsByNode(c *gin.Context, clusterID string) (map[int64][]int64, bool) {
+	jobs, err := h.queries.ListActiveJobsByCluster(c.Request.Context(), clusterID)
+	if err != nil {
+		log.Printf("failed to list running jobs: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return nil, false
+	}
+
+	running := make(map[int64][]int64)
+	for _, job := range jobs {
+		running[job.NodeID] = append(running[job.NodeID], job.ID)
+	}
+	return running, true
+}
+
 func generateNodeToken() (string, error) {
 	buf := make([]byte, 32)
 	if _, err := rand.Read(buf); err != nil {
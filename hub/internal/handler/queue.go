@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
+	"github.com/kanaya/jobboard-hub/internal/middleware"
+	"github.com/kanaya/jobboard-hub/internal/response"
+)
+
+const (
+	defaultQueueMaxAttempts = 3
+
+	defaultQueueListLimit = 100
+	maxQueueListLimit     = 500
+)
+
+var queueStatuses = map[string]bool{
+	"queued":    true,
+	"leased":    true,
+	"started":   true,
+	"failed":    true,
+	"cancelled": true,
+}
+
+// QueueHandler は Hub に積んで jobboard agent に実行させるジョブを管理する。
+type QueueHandler struct {
+	queries repo.Querier
+	broker  events.Broker
+}
+
+func NewQueueHandler(queries repo.Querier, broker events.Broker) *QueueHandler {
+	return &QueueHandler{
+		queries: queries,
+		broker:  broker,
+	}
+}
+
+// enqueueJobRequest の node_id と node_selector は両方指定でき、どちらも満たすノードにだけ貸し出す。
+// どちらも省略するとクラスタ内のどのノードでも実行できる。
+type enqueueJobRequest struct {
+	Command      []string          `json:"command" binding:"required,min=1,max=256"`
+	WorkingDir   *string           `json:"working_dir"`
+	Tag          *string           `json:"tag" binding:"omitempty,max=128"`
+	Labels       map[string]string `json:"labels"`
+	NodeID       *int64            `json:"node_id"`
+	NodeSelector map[string]string `json:"node_selector"`
+	Priority     int32             `json:"priority" binding:"min=-1000,max=1000"`
+	MaxAttempts  *int32            `json:"max_attempts" binding:"omitempty,min=1,max=100"`
+}
+
+func (h *QueueHandler) List(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+
+	var status *string
+	if raw, ok := c.GetQuery("status"); ok {
+		if !queueStatuses[raw] {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		status = &raw
+	}
+
+	limit := defaultQueueListLimit
+	if raw, ok := c.GetQuery("limit"); ok {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxQueueListLimit {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		limit = v
+	}
+
+	// 新しい順に limit 件まで返す
+	items, err := h.queries.ListQueuedJobsByCluster(c.Request.Context(), repo.ListQueuedJobsByClusterParams{
+		ClusterID:  clusterID,
+		Status:     status,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		log.Printf("failed to list queued jobs: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	resp := make([]response.QueuedJob, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, response.NewQueuedJob(item))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *QueueHandler) Get(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	queueID, err := strconv.ParseInt(c.Param("queue_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	item, err := h.queries.GetQueuedJobByCluster(c.Request.Context(), repo.GetQueuedJobByClusterParams{
+		ID:        queueID,
+		ClusterID: clusterID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(c, apierror.QueuedJobNotFound)
+			return
+		}
+		log.Printf("failed to load queued job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.NewQueuedJob(item))
+}
+
+func (h *QueueHandler) Create(c *gin.Context) {
+	var req enqueueJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if req.Command[0] == "" || !validLabels(req.Labels) || !validLabels(req.NodeSelector) {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	if req.Labels == nil {
+		req.Labels = map[string]string{}
+	}
+	if req.NodeSelector == nil {
+		req.NodeSelector = map[string]string{}
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+	selector, err := json.Marshal(req.NodeSelector)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	if req.NodeID != nil {
+		_, err := h.queries.GetNodeByCluster(c.Request.Context(), repo.GetNodeByClusterParams{
+			ID:        *req.NodeID,
+			ClusterID: clusterID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				apierror.Write(c, apierror.NodeNotFound)
+				return
+			}
+			log.Printf("failed to load node: %v", err)
+			apierror.Write(c, apierror.Internal)
+			return
+		}
+	}
+
+	maxAttempts := int32(defaultQueueMaxAttempts)
+	if req.MaxAttempts != nil {
+		maxAttempts = *req.MaxAttempts
+	}
+
+	item, err := h.queries.CreateQueuedJob(c.Request.Context(), repo.CreateQueuedJobParams{
+		ClusterID:    clusterID,
+		Command:      req.Command,
+		WorkingDir:   req.WorkingDir,
+		Tag:          req.Tag,
+		Labels:       labels,
+		NodeID:       req.NodeID,
+		NodeSelector: selector,
+		Priority:     req.Priority,
+		MaxAttempts:  maxAttempts,
+	})
+	if err != nil {
+		log.Printf("failed to enqueue job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	events.PublishQueuedJob(h.broker, events.QueueCreated, item)
+	c.JSON(http.StatusCreated, response.NewQueuedJob(item))
+}
+
+// Cancel は開始前のジョブをキューから取り除く。貸し出し中でも、agent が start する前なら取り消せる。
+func (h *QueueHandler) Cancel(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+	queueID, err := strconv.ParseInt(c.Param("queue_id"), 10, 64)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	item, err := h.queries.CancelQueuedJobByCluster(c.Request.Context(), repo.CancelQueuedJobByClusterParams{
+		ID:        queueID,
+		ClusterID: clusterID,
+	})
+	if err == nil {
+		events.PublishQueuedJob(h.broker, events.QueueUpdated, item)
+		c.JSON(http.StatusOK, response.NewQueuedJob(item))
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("failed to cancel queued job: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	_, err = h.queries.GetQueuedJobByCluster(c.Request.Context(), repo.GetQueuedJobByClusterParams{
+		ID:        queueID,
+		ClusterID: clusterID,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		apierror.Write(c, apierror.QueuedJobNotFound)
+	case err != nil:
+		log.Printf("failed to load queued job: %v", err)
+		apierror.Write(c, apierror.Internal)
+	default:
+		apierror.Write(c, apierror.QueuedJobNotPending)
+	}
+}
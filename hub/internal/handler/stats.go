@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/apierror"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/middleware"
+)
+
+const (
+	defaultStatsWindow   = 30 * 24 * time.Hour
+	maxStatsWindow       = 366 * 24 * time.Hour
+	defaultStatsTagLimit = 50
+	maxStatsTagLimit     = 500
+)
+
+type StatsHandler struct {
+	queries repo.Querier
+}
+
+func NewStatsHandler(queries repo.Querier) *StatsHandler {
+	return &StatsHandler{queries: queries}
+}
+
+type statsResponse struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	TimeZone string          `json:"time_zone"`
+	Overall  jobStats        `json:"overall"`
+	ByTag    []tagStats      `json:"by_tag"`
+	ByNode   []nodeStats     `json:"by_node"`
+	Daily    []dailyJobStats `json:"daily"`
+}
+
+type jobStats struct {
+	Total     int64 `json:"total"`
+	Running   int64 `json:"running"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	TimedOut  int64 `json:"timed_out"`
+	Cancelled int64 `json:"cancelled"`
+	Lost      int64 `json:"lost"`
+	// FailureRate は終了したジョブのうち failed / timed_out / lost の割合。cancelled は数えない
+	FailureRate *float64 `json:"failure_rate"`
+	// DurationHours は completed のジョブの所要時間のパーセンタイル
+	DurationHours durationPercentiles `json:"duration_hours"`
+}
+
+type durationPercentiles struct {
+	P50 *float64 `json:"p50"`
+	P90 *float64 `json:"p90"`
+	P99 *float64 `json:"p99"`
+}
+
+type tagStats struct {
+	Tag *string `json:"tag"`
+	jobStats
+}
+
+type nodeStats struct {
+	NodeID   int64  `json:"node_id"`
+	NodeName string `json:"node_name"`
+	jobStats
+}
+
+type dailyJobStats struct {
+	// Date は time_zone での日付（YYYY-MM-DD）
+	Date string `json:"date"`
+	jobStats
+}
+
+// Get は from から to までに開始したジョブを、全体・タグ別・ノード別・日別に集計して返す。
+func (h *StatsHandler) Get(c *gin.Context) {
+	clusterID := c.GetString(middleware.ClusterIDContextKey)
+
+	to := time.Now()
+	if raw, ok := c.GetQuery("to"); ok {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultStatsWindow)
+	if raw, ok := c.GetQuery("from"); ok {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) || to.Sub(from) > maxStatsWindow {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	timeZone := c.DefaultQuery("tz", "UTC")
+	if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "" || timeZone == "Local" {
+		apierror.Write(c, apierror.InvalidRequest)
+		return
+	}
+
+	tagLimit := defaultStatsTagLimit
+	if raw, ok := c.GetQuery("tag_limit"); ok {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxStatsTagLimit {
+			apierror.Write(c, apierror.InvalidRequest)
+			return
+		}
+		tagLimit = v
+	}
+
+	ctx := c.Request.Context()
+	windowStart := pgtype.Timestamptz{Time: from, Valid: true}
+	windowEnd := pgtype.Timestamptz{Time: to, Valid: true}
+
+	overall, err := h.queries.GetJobStats(ctx, repo.GetJobStatsParams{
+		ClusterID:   clusterID,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+	})
+	if err != nil {
+		log.Printf("failed to load job stats: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	tags, err := h.queries.ListJobStatsByTag(ctx, repo.ListJobStatsByTagParams{
+		ClusterID:   clusterID,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+		MaxGroups:   int32(tagLimit),
+	})
+	if err != nil {
+		log.Printf("failed to load job stats by tag: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	nodes, err := h.queries.ListJobStatsByNode(ctx, repo.ListJobStatsByNodeParams{
+		ClusterID:   clusterID,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+	})
+	if err != nil {
+		log.Printf("failed to load job stats by node: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	days, err := h.queries.ListDailyJobStats(ctx, repo.ListDailyJobStatsParams{
+		WindowStart: windowStart,
+		TimeZone:    timeZone,
+		WindowEnd:   windowEnd,
+		ClusterID:   clusterID,
+	})
+	if err != nil {
+		log.Printf("failed to load daily job stats: %v", err)
+		apierror.Write(c, apierror.Internal)
+		return
+	}
+
+	resp := statsResponse{
+		From:     from,
+		To:       to,
+		TimeZone: timeZone,
+		Overall:  newJobStats(overall.Total, overall.Running, overall.Completed, overall.Failed, overall.TimedOut, overall.Cancelled, overall.Lost, overall.DurationPercentiles),
+		ByTag:    make([]tagStats, 0, len(tags)),
+		ByNode:   make([]nodeStats, 0, len(nodes)),
+		Daily:    make([]dailyJobStats, 0, len(days)),
+	}
+	for _, row := range tags {
+		resp.ByTag = append(resp.ByTag, tagStats{
+			Tag:      row.Tag,
+			jobStats: newJobStats(row.Total, row.Running, row.Completed, row.Failed, row.TimedOut, row.Cancelled, row.Lost, row.DurationPercentiles),
+		})
+	}
+	for _, row := range nodes {
+		resp.ByNode = append(resp.ByNode, nodeStats{
+			NodeID:   row.NodeID,
+			NodeName: row.NodeName,
+			jobStats: newJobStats(row.Total, row.Running, row.Completed, row.Failed, row.TimedOut, row.Cancelled, row.Lost, row.DurationPercentiles),
+		})
+	}
+	for _, row := range days {
+		resp.Daily = append(resp.Daily, dailyJobStats{
+			Date:     row.Day.Time.Format(time.DateOnly),
+			jobStats: newJobStats(row.Total, row.Running, row.Completed, row.Failed, row.TimedOut, row.Cancelled, row.Lost, row.DurationPercentiles),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// newJobStats は集計クエリの 1 行から jobStats を作る。percentiles は p50 / p90 / p99 の順で、
+// completed のジョブがなければ空。
+func newJobStats(total, running, completed, failed, timedOut, cancelled, lost int64, percentiles []float64) jobStats {
+	stats := jobStats{
+		Total:     total,
+		Running:   running,
+		Completed: completed,
+		Failed:    failed,
+		TimedOut:  timedOut,
+		Cancelled: cancelled,
+		Lost:      lost,
+	}
+
+	failures := failed + timedOut + lost
+	if finished := completed + failures; finished > 0 {
+		rate := float64(failures) / float64(finished)
+		stats.FailureRate = &rate
+	}
+
+	if len(percentiles) == 3 {
+		stats.DurationHours = durationPercentiles{P50: &percentiles[0], P90: &percentiles[1], P99: &percentiles[2]}
+	}
+	return stats
+}
@@ -0,0 +1,240 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanaya/jobboard-hub/internal/config"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-smtpmail"
+)
+
+const (
+	errorTailLines = 50
+	errorTailBytes = 8 << 10
+)
+
+// Mailer はジョブの終了を SMTP でメール通知する。
+// CLI の通知と違い、lost のように CLI が報告できない終了も Hub から知らせられる。
+// 宛先はジョブのクラスターに設定されたもの（clusters.notify_emails）で、設定がなければ送らない。
+type Mailer struct {
+	config   config.MailConfig
+	queries  repo.Querier
+	from     *mail.Address
+	statuses map[string]bool
+}
+
+// New は SMTP サーバが設定されていなければ nil を返す。nil の Mailer は何も送らない。
+func New(cfg config.MailConfig, queries repo.Querier) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	switch cfg.TLS {
+	case smtpmail.TLSStartTLS, smtpmail.TLSImplicit, smtpmail.TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", cfg.TLS)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+	statuses := make(map[string]bool, len(cfg.Statuses))
+	for _, status := range cfg.Statuses {
+		statuses[status] = true
+	}
+
+	return &Mailer{
+		config:   cfg,
+		queries:  queries,
+		from:     from,
+		statuses: statuses,
+	}, nil
+}
+
+// JobFinished は通知対象のステータスで終わったジョブのサマリを非同期に送る。
+// 送信に失敗してもログに残すだけで、呼び出し側の処理には影響させない。
+func (m *Mailer) JobFinished(job repo.Job, nodeName string) {
+	if m == nil || !m.statuses[job.Status] {
+		return
+	}
+	m.send(job, nodeName, strings.ToUpper(job.Status))
+}
+
+// DurationAnomaly は所要時間がベースラインから外れたジョブを知らせる。
+// 実行中のジョブでは、開始からの経過時間で判定した結果を送る。
+func (m *Mailer) DurationAnomaly(job repo.Job, nodeName string) {
+	if m == nil || !m.config.Anomalies || job.DurationAnomaly == nil {
+		return
+	}
+	m.send(job, nodeName, strings.ToUpper(*job.DurationAnomaly))
+}
+
+// send はジョブのクラスターの宛先を引いてから送る。
+func (m *Mailer) send(job repo.Job, nodeName, headline string) {
+	now := time.Now()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+		defer cancel()
+
+		cluster, err := m.queries.GetCluster(ctx, job.ClusterID)
+		if err != nil {
+			log.Printf("failed to load email recipients for job %d: %v", job.ID, err)
+			return
+		}
+		to := make([]*mail.Address, 0, len(cluster.NotifyEmails))
+		for _, raw := range cluster.NotifyEmails {
+			addr, err := mail.ParseAddress(raw)
+			if err != nil {
+				log.Printf("skipping invalid email recipient %q of cluster %s: %v", raw, cluster.ID, err)
+				continue
+			}
+			to = append(to, addr)
+		}
+		if len(to) == 0 {
+			return
+		}
+
+		msg, err := m.message(job, nodeName, headline, to, now)
+		if err != nil {
+			log.Printf("failed to build email for job %d: %v", job.ID, err)
+			return
+		}
+		recipients := make([]string, 0, len(to))
+		for _, addr := range to {
+			recipients = append(recipients, addr.Address)
+		}
+		if err := smtpmail.Send(ctx, smtpmail.Config{
+			Host:     m.config.Host,
+			Port:     m.config.Port,
+			Username: m.config.Username,
+			Password: m.config.Password,
+			TLS:      m.config.TLS,
+		}, m.from.Address, recipients, msg); err != nil {
+			log.Printf("failed to send email for job %d: %v", job.ID, err)
+		}
+	}()
+}
+
+type field struct {
+	Name  string
+	Value string
+}
+
+type view struct {
+	Title     string
+	Command   string
+	Fields    []field
+	ErrorTail string
+}
+
+var htmlBody = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.Title}}</h2>
+<pre style="background: #f4f4f4; padding: 8px;">{{.Command}}</pre>
+<table cellpadding="4" style="border-collapse: collapse;">
+{{- range .Fields}}
+<tr><th align="left">{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .ErrorTail}}
+<h3>Error</h3>
+<pre style="background: #fdf2f2; padding: 8px; white-space: pre-wrap;">{{.ErrorTail}}</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+// message は headline を件名とタイトルに使ったメールを作る。通常はジョブのステータスで、
+// 所要時間の通知では SLOW / FAST になる。
+func (m *Mailer) message(job repo.Job, nodeName, headline string, recipients []*mail.Address, now time.Time) ([]byte, error) {
+	command := strings.Join(job.Command, " ")
+
+	v := view{
+		Title:   "jobboard: " + headline,
+		Command: command,
+		Fields: []field{
+			{Name: "Job", Value: "#" + strconv.FormatInt(job.ID, 10)},
+			{Name: "Status", Value: strings.ToUpper(job.Status)},
+			{Name: "Node", Value: nodeName},
+		},
+	}
+	if job.ExitCode != nil {
+		v.Fields = append(v.Fields, field{Name: "Exit code", Value: strconv.Itoa(int(*job.ExitCode))})
+	}
+	if job.Tag != nil && *job.Tag != "" {
+		v.Fields = append(v.Fields, field{Name: "Tag", Value: *job.Tag})
+	}
+	if labels := sortedLabels(job.Labels); len(labels) > 0 {
+		v.Fields = append(v.Fields, field{Name: "Labels", Value: strings.Join(labels, " ")})
+	}
+	if job.StartedAt.Valid {
+		v.Fields = append(v.Fields, field{Name: "Started", Value: job.StartedAt.Time.UTC().Format(time.RFC3339)})
+	}
+	if job.FinishedAt.Valid {
+		v.Fields = append(v.Fields, field{Name: "Finished", Value: job.FinishedAt.Time.UTC().Format(time.RFC3339)})
+	}
+	if job.StartedAt.Valid && job.FinishedAt.Valid {
+		duration := job.FinishedAt.Time.Sub(job.StartedAt.Time).Round(time.Second)
+		v.Fields = append(v.Fields, field{Name: "Duration", Value: duration.String()})
+	}
+	if job.DurationBaselineSeconds != nil {
+		baseline := time.Duration(*job.DurationBaselineSeconds * float64(time.Second)).Round(time.Second)
+		v.Fields = append(v.Fields, field{Name: "Baseline", Value: baseline.String() + " (median)"})
+	}
+	if job.DurationAnomalyScore != nil {
+		v.Fields = append(v.Fields, field{Name: "Anomaly score", Value: strconv.FormatFloat(*job.DurationAnomalyScore, 'f', 1, 64)})
+	}
+	if job.ErrorText != nil {
+		v.ErrorTail = smtpmail.ErrorTail(*job.ErrorText, errorTailLines, errorTailBytes)
+	}
+
+	var text strings.Builder
+	text.WriteString(v.Title + "\n\n")
+	text.WriteString(v.Command + "\n\n")
+	for _, f := range v.Fields {
+		text.WriteString(fmt.Sprintf("%s: %s\n", f.Name, f.Value))
+	}
+	if v.ErrorTail != "" {
+		text.WriteString(fmt.Sprintf("\nError (last %d lines):\n%s\n", errorTailLines, v.ErrorTail))
+	}
+
+	var html bytes.Buffer
+	if err := htmlBody.Execute(&html, v); err != nil {
+		return nil, err
+	}
+
+	if runes := []rune(command); len(runes) > 80 {
+		command = string(runes[:77]) + "..."
+	}
+	subject := fmt.Sprintf("[jobboard] %s: %s on %s", headline, command, nodeName)
+
+	to := make([]string, 0, len(recipients))
+	for _, addr := range recipients {
+		to = append(to, addr.String())
+	}
+	return smtpmail.BuildMessage(m.from.String(), to, subject, text.String(), html.String(), now)
+}
+
+func sortedLabels(raw []byte) []string {
+	var labels map[string]string
+	if len(raw) == 0 || json.Unmarshal(raw, &labels) != nil {
+		return nil
+	}
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
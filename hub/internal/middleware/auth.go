@@ -9,7 +9,11 @@ import (
 	"github.com/kanaya/jobboard-hub/internal/database/repo"
 )
 
-const ClusterIDContextKey = "cluster_id"
+const (
+	ClusterIDContextKey = "cluster_id"
+	// TokenExpiresAtContextKey はトークンの有効期限（time.Time）。期限のないトークンでは設定しない
+	TokenExpiresAtContextKey = "token_expires_at"
+)
 
 type AuthMiddleware struct {
 	queries   repo.Querier
@@ -48,6 +52,9 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		}
 
 		c.Set(ClusterIDContextKey, claims.ClusterID)
+		if claims.ExpiresAt != nil {
+			c.Set(TokenExpiresAtContextKey, claims.ExpiresAt.Time)
+		}
 		c.Next()
 	}
 }
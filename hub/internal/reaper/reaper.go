@@ -0,0 +1,134 @@
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/anomaly"
+	"github.com/kanaya/jobboard-hub/internal/config"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
+	"github.com/kanaya/jobboard-hub/internal/mailer"
+)
+
+// Reaper はハートビートが途絶えた実行中ジョブを lost に遷移させ、ノードのスロットを空ける。
+// あわせて期限切れのリースと lost になったキューのジョブをキューに戻し、
+// 保持期間を過ぎた Idempotency-Key の記録の削除と、実行時間が長すぎるジョブの検知も行う。
+type Reaper struct {
+	queries  *repo.Queries
+	config   config.ReaperConfig
+	mailer   *mailer.Mailer
+	detector *anomaly.Detector
+	broker   events.Broker
+}
+
+func New(queries *repo.Queries, cfg config.ReaperConfig, mailer *mailer.Mailer, detector *anomaly.Detector, broker events.Broker) *Reaper {
+	return &Reaper{
+		queries:  queries,
+		config:   cfg,
+		mailer:   mailer,
+		detector: detector,
+		broker:   broker,
+	}
+}
+
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reap(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("failed to reap lost jobs: %v", err)
+			}
+			if err := r.requeue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("failed to requeue queued jobs: %v", err)
+			}
+			if err := r.expireTriggerRequests(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("failed to expire trigger requests: %v", err)
+			}
+			if err := r.checkDurations(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("failed to check running job durations: %v", err)
+			}
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context) error {
+	staleBefore := pgtype.Timestamptz{
+		Time:  time.Now().Add(-r.config.HeartbeatTimeout).UTC(),
+		Valid: true,
+	}
+
+	jobs, err := r.queries.MarkStaleJobsLost(ctx, staleBefore)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		log.Printf("marked job %d on node %d as lost", job.ID, job.NodeID)
+
+		nodeName := fmt.Sprintf("node %d", job.NodeID)
+		if node, err := r.queries.GetNodeByCluster(ctx, repo.GetNodeByClusterParams{ID: job.NodeID, ClusterID: job.ClusterID}); err == nil {
+			nodeName = node.NodeName
+		}
+		r.mailer.JobFinished(job, nodeName)
+		events.PublishJob(r.broker, events.JobFinished, job)
+		events.PublishNode(ctx, r.broker, r.queries, job.ClusterID, job.NodeID)
+	}
+	return nil
+}
+
+func (r *Reaper) checkDurations(ctx context.Context) error {
+	jobs, err := r.detector.CheckRunning(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		events.PublishJob(r.broker, events.JobUpdated, job)
+	}
+	return nil
+}
+
+func (r *Reaper) requeue(ctx context.Context) error {
+	expired, err := r.queries.ExpireQueuedJobLeases(ctx)
+	if err != nil {
+		return err
+	}
+	lostBefore := pgtype.Timestamptz{
+		Time:  time.Now().Add(-r.config.LostRequeueGrace).UTC(),
+		Valid: true,
+	}
+	lost, err := r.queries.RequeueLostQueuedJobs(ctx, lostBefore)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range append(expired, lost...) {
+		log.Printf("queued job %d is now %s (attempt %d of %d)", item.ID, item.Status, item.Attempts, item.MaxAttempts)
+		events.PublishQueuedJob(r.broker, events.QueueUpdated, item)
+	}
+	return nil
+}
+
+func (r *Reaper) expireTriggerRequests(ctx context.Context) error {
+	createdBefore := pgtype.Timestamptz{
+		Time:  time.Now().Add(-r.config.IdempotencyRetention).UTC(),
+		Valid: true,
+	}
+
+	deleted, err := r.queries.DeleteTriggerRequestsBefore(ctx, createdBefore)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("expired %d trigger requests", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package response は API が返すジョブ・ノード・キューのジョブの形。ハンドラーのレスポンスと、
+// events で配るイベントの data の両方に使う。
+package response
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kanaya/jobboard-hub/internal/database/repo"
+)
+
+type Job struct {
+	ID                int64             `json:"id"`
+	ClusterID         string            `json:"cluster_id"`
+	NodeID            int64             `json:"node_id"`
+	Status            string            `json:"status"`
+	StartedAt         *time.Time        `json:"started_at,omitempty"`
+	FinishedAt        *time.Time        `json:"finished_at,omitempty"`
+	DurationHours     *float64          `json:"duration_hours,omitempty"`
+	Tag               *string           `json:"tag,omitempty"`
+	ErrorText         *string           `json:"error_text,omitempty"`
+	LastHeartbeatAt   *time.Time        `json:"last_heartbeat_at,omitempty"`
+	ExitCode          *int32            `json:"exit_code,omitempty"`
+	Command           []string          `json:"command,omitempty"`
+	WorkingDir        *string           `json:"working_dir,omitempty"`
+	TerminationSignal *string           `json:"termination_signal,omitempty"`
+	Resources         *Resources        `json:"resources,omitempty"`
+	Labels            map[string]string `json:"labels"`
+	Progress          *Progress         `json:"progress,omitempty"`
+	CancelRequest     *CancelRequest    `json:"cancel_request,omitempty"`
+	DurationBaseline  *DurationBaseline `json:"duration_baseline,omitempty"`
+}
+
+// DurationBaseline は同じタグの直近の completed のジョブと所要時間を比べた結果。
+type DurationBaseline struct {
+	MedianHours float64 `json:"median_hours"`
+	// Score は robust z-score。実行中に経過時間で slow とした場合は null
+	Score *float64 `json:"score"`
+	// Anomaly は slow / fast。外れていなければ null
+	Anomaly   *string    `json:"anomaly"`
+	FlaggedAt *time.Time `json:"flagged_at"`
+}
+
+type CancelRequest struct {
+	RequestedAt time.Time `json:"requested_at"`
+	RequestedBy string    `json:"requested_by"`
+}
+
+type Progress struct {
+	Percent   *float64  `json:"percent"`
+	Step      *int64    `json:"step"`
+	Total     *int64    `json:"total"`
+	Phase     *string   `json:"phase"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Resources struct {
+	CPUUserSeconds   *float64 `json:"cpu_user_seconds"`
+	CPUSystemSeconds *float64 `json:"cpu_system_seconds"`
+	MaxRSSBytes      *int64   `json:"max_rss_bytes"`
+	IOReadBytes      *int64   `json:"io_read_bytes"`
+	IOWriteBytes     *int64   `json:"io_write_bytes"`
+}
+
+func NewJob(job repo.Job) Job {
+	return Job{
+		ID:                job.ID,
+		ClusterID:         job.ClusterID,
+		NodeID:            job.NodeID,
+		Status:            job.Status,
+		StartedAt:         Time(job.StartedAt),
+		FinishedAt:        Time(job.FinishedAt),
+		DurationHours:     hours(job.DurationHours),
+		Tag:               job.Tag,
+		ErrorText:         job.ErrorText,
+		LastHeartbeatAt:   Time(job.LastHeartbeatAt),
+		ExitCode:          job.ExitCode,
+		Command:           job.Command,
+		WorkingDir:        job.WorkingDir,
+		TerminationSignal: job.TerminationSignal,
+		Resources:         newResources(job),
+		Labels:            Labels(job.Labels),
+		Progress:          newProgress(job),
+		CancelRequest:     newCancelRequest(job),
+		DurationBaseline:  newDurationBaseline(job),
+	}
+}
+
+func newDurationBaseline(job repo.Job) *DurationBaseline {
+	if job.DurationBaselineSeconds == nil {
+		return nil
+	}
+	return &DurationBaseline{
+		MedianHours: *job.DurationBaselineSeconds / 3600,
+		Score:       job.DurationAnomalyScore,
+		Anomaly:     job.DurationAnomaly,
+		FlaggedAt:   Time(job.DurationAnomalyAt),
+	}
+}
+
+func newCancelRequest(job repo.Job) *CancelRequest {
+	if !job.CancelRequestedAt.Valid {
+		return nil
+	}
+	resp := &CancelRequest{RequestedAt: job.CancelRequestedAt.Time}
+	if job.CancelRequestedBy != nil {
+		resp.RequestedBy = *job.CancelRequestedBy
+	}
+	return resp
+}
+
+func newProgress(job repo.Job) *Progress {
+	if !job.ProgressUpdatedAt.Valid {
+		return nil
+	}
+	return &Progress{
+		Percent:   job.ProgressPercent,
+		Step:      job.ProgressStep,
+		Total:     job.ProgressTotal,
+		Phase:     job.ProgressPhase,
+		UpdatedAt: job.ProgressUpdatedAt.Time,
+	}
+}
+
+func newResources(job repo.Job) *Resources {
+	if job.CpuUserSeconds == nil && job.CpuSystemSeconds == nil && job.MaxRssBytes == nil &&
+		job.IoReadBytes == nil && job.IoWriteBytes == nil {
+		return nil
+	}
+	return &Resources{
+		CPUUserSeconds:   job.CpuUserSeconds,
+		CPUSystemSeconds: job.CpuSystemSeconds,
+		MaxRSSBytes:      job.MaxRssBytes,
+		IOReadBytes:      job.IoReadBytes,
+		IOWriteBytes:     job.IoWriteBytes,
+	}
+}
+
+type Node struct {
+	ID            int64             `json:"id"`
+	NodeName      string            `json:"node_name"`
+	Slots         int32             `json:"slots"`
+	Labels        map[string]string `json:"labels"`
+	RunningJobIDs []int64           `json:"running_job_ids"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+func NewNode(node repo.Node, runningJobIDs []int64) Node {
+	var createdAt time.Time
+	if node.CreatedAt.Valid {
+		createdAt = node.CreatedAt.Time
+	}
+	if runningJobIDs == nil {
+		runningJobIDs = []int64{}
+	}
+	return Node{
+		ID:            node.ID,
+		NodeName:      node.NodeName,
+		Slots:         node.Slots,
+		Labels:        Labels(node.Labels),
+		RunningJobIDs: runningJobIDs,
+		CreatedAt:     createdAt,
+	}
+}
+
+type QueuedJob struct {
+	ID             int64             `json:"id"`
+	Command        []string          `json:"command"`
+	WorkingDir     *string           `json:"working_dir"`
+	Tag            *string           `json:"tag"`
+	Labels         map[string]string `json:"labels"`
+	NodeID         *int64            `json:"node_id"`
+	NodeSelector   map[string]string `json:"node_selector"`
+	Priority       int32             `json:"priority"`
+	Status         string            `json:"status"`
+	Attempts       int32             `json:"attempts"`
+	MaxAttempts    int32             `json:"max_attempts"`
+	LeasedNodeID   *int64            `json:"leased_node_id"`
+	LeaseExpiresAt *time.Time        `json:"lease_expires_at"`
+	JobID          *int64            `json:"job_id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+func NewQueuedJob(item repo.JobQueue) QueuedJob {
+	command := item.Command
+	if command == nil {
+		command = []string{}
+	}
+	return QueuedJob{
+		ID:             item.ID,
+		Command:        command,
+		WorkingDir:     item.WorkingDir,
+		Tag:            item.Tag,
+		Labels:         Labels(item.Labels),
+		NodeID:         item.NodeID,
+		NodeSelector:   Labels(item.NodeSelector),
+		Priority:       item.Priority,
+		Status:         item.Status,
+		Attempts:       item.Attempts,
+		MaxAttempts:    item.MaxAttempts,
+		LeasedNodeID:   item.LeasedNodeID,
+		LeaseExpiresAt: Time(item.LeaseExpiresAt),
+		JobID:          item.JobID,
+		CreatedAt:      item.CreatedAt.Time,
+		UpdatedAt:      item.UpdatedAt.Time,
+	}
+}
+
+// Labels は jsonb のラベルを map にする。読めなければ空の map を返す。
+func Labels(raw []byte) map[string]string {
+	labels := map[string]string{}
+	if len(raw) == 0 {
+		return labels
+	}
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+func Time(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	return &ts.Time
+}
+
+func hours(iv pgtype.Interval) *float64 {
+	if !iv.Valid {
+		return nil
+	}
+	hours := float64(iv.Microseconds) / float64(time.Hour/time.Microsecond)
+	return &hours
+}
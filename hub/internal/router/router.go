@@ -7,13 +7,18 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/kanaya/jobboard-hub/internal/anomaly"
+	"github.com/kanaya/jobboard-hub/internal/blobstore"
 	"github.com/kanaya/jobboard-hub/internal/database"
+	"github.com/kanaya/jobboard-hub/internal/database/joblist"
 	"github.com/kanaya/jobboard-hub/internal/database/repo"
+	"github.com/kanaya/jobboard-hub/internal/events"
 	"github.com/kanaya/jobboard-hub/internal/handler"
+	"github.com/kanaya/jobboard-hub/internal/mailer"
 	"github.com/kanaya/jobboard-hub/internal/middleware"
 )
 
-func New(ctx context.Context, db *database.Database, allowedOrigins string, jwtSecret []byte, tokenTTL time.Duration) *gin.Engine {
+func New(ctx context.Context, db *database.Database, store blobstore.Store, maxArtifactSize int64, leaseTTL time.Duration, mailer *mailer.Mailer, detector *anomaly.Detector, broker events.Broker, eventsKeepAlive time.Duration, allowedOrigins string, jwtSecret []byte, tokenTTL time.Duration) *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
 
@@ -22,7 +27,7 @@ func New(ctx context.Context, db *database.Database, allowedOrigins string, jwtS
 		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{
 			"Origin", "Content-Type", "Accept",
-			"Authorization", "X-Requested-With",
+			"Authorization", "X-Requested-With", "Last-Event-ID",
 		},
 		AllowCredentials: true,
 	}))
@@ -34,9 +39,12 @@ func New(ctx context.Context, db *database.Database, allowedOrigins string, jwtS
 	authMiddleware := middleware.NewAuthMiddleware(queries, jwtSecret)
 
 	clusterHandler := handler.NewClusterHandler(queries)
-	nodeHandler := handler.NewNodeHandler(queries)
-	jobHandler := handler.NewJobHandler(queries)
-	jobTriggerHandler := handler.NewJobTriggerHandler(queries)
+	nodeHandler := handler.NewNodeHandler(queries, broker)
+	jobHandler := handler.NewJobHandler(queries, joblist.New(db.Pool), store, broker)
+	queueHandler := handler.NewQueueHandler(queries, broker)
+	statsHandler := handler.NewStatsHandler(queries)
+	eventHandler := handler.NewEventHandler(broker, eventsKeepAlive)
+	jobTriggerHandler := handler.NewJobTriggerHandler(db, queries, store, maxArtifactSize, leaseTTL, mailer, detector, broker)
 
 	router.GET("/health", healthHandler.Check)
 	router.GET("/", healthHandler.Info)
@@ -55,16 +63,36 @@ func New(ctx context.Context, db *database.Database, allowedOrigins string, jwtS
 		{
 			// クラスタ
 			protected.GET("/clusters/me", clusterHandler.Me)
+			protected.PATCH("/clusters/me", clusterHandler.UpdateMe)
 
 			// ノード
 			protected.GET("/nodes", nodeHandler.List)
 			protected.POST("/nodes", nodeHandler.Create)
+			protected.PATCH("/nodes/:node_id", nodeHandler.Update)
 			protected.DELETE("/nodes/:node_id", nodeHandler.Delete)
 
 			// ジョブ
 			protected.GET("/jobs", jobHandler.List)
+			protected.GET("/jobs/search", jobHandler.Search)
 			protected.GET("/jobs/:job_id", jobHandler.Get)
+			protected.POST("/jobs/:job_id/cancel", jobHandler.Cancel)
+			protected.GET("/jobs/:job_id/logs", jobHandler.Logs)
+			protected.GET("/jobs/:job_id/metrics", jobHandler.Metrics)
+			protected.GET("/jobs/:job_id/artifacts", jobHandler.Artifacts)
+			protected.GET("/jobs/:job_id/artifacts/:artifact_id/download", jobHandler.DownloadArtifact)
 			protected.GET("/nodes/:node_id/jobs", jobHandler.ListByNode)
+
+			// 集計
+			protected.GET("/stats", statsHandler.Get)
+
+			// 変更通知
+			protected.GET("/events", eventHandler.Stream)
+
+			// ジョブキュー
+			protected.GET("/queue", queueHandler.List)
+			protected.POST("/queue", queueHandler.Create)
+			protected.GET("/queue/:queue_id", queueHandler.Get)
+			protected.DELETE("/queue/:queue_id", queueHandler.Cancel)
 		}
 
 		jobTrigger := api.Group("/job-trigger")
@@ -72,6 +100,13 @@ func New(ctx context.Context, db *database.Database, allowedOrigins string, jwtS
 			// ジョブトリガー
 			jobTrigger.POST("/start", jobTriggerHandler.StartJob)
 			jobTrigger.POST("/finish", jobTriggerHandler.FinishJob)
+			jobTrigger.POST("/heartbeat", jobTriggerHandler.Heartbeat)
+			jobTrigger.POST("/logs", jobTriggerHandler.AppendLogs)
+			jobTrigger.POST("/progress", jobTriggerHandler.ReportProgress)
+			jobTrigger.POST("/metrics", jobTriggerHandler.IngestMetrics)
+			jobTrigger.POST("/artifacts", jobTriggerHandler.UploadArtifact)
+			jobTrigger.POST("/lease", jobTriggerHandler.LeaseJob)
+			jobTrigger.POST("/previous-run", jobTriggerHandler.PreviousRun)
 		}
 	}
 
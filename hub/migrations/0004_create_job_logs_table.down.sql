@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS job_logs_job_id_stream_offset_idx;
+
+DROP TABLE IF EXISTS job_logs;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS job_logs (
+    job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    stream VARCHAR(16) NOT NULL,
+    seq BIGINT NOT NULL,
+    byte_offset BIGINT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, stream, seq),
+    CONSTRAINT job_logs_stream_check CHECK (stream IN ('stdout', 'stderr'))
+);
+
+CREATE INDEX job_logs_job_id_stream_offset_idx ON job_logs (job_id, stream, byte_offset);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS jobs_active_heartbeat_idx;
+
+UPDATE jobs SET status = 'failed' WHERE status = 'lost';
+
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN ('running', 'completed', 'failed'));
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS last_heartbeat_at;
@@ -0,0 +1,11 @@
+ALTER TABLE jobs ADD COLUMN last_heartbeat_at TIMESTAMPTZ;
+
+-- 既に実行中のジョブには猶予を与えるため、移行時点を最終ハートビートとみなす
+UPDATE jobs SET last_heartbeat_at = NOW() WHERE finished_at IS NULL;
+
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN ('running', 'completed', 'failed', 'lost'));
+
+CREATE INDEX jobs_active_heartbeat_idx
+ON jobs (last_heartbeat_at)
+WHERE finished_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS working_dir,
+    DROP COLUMN IF EXISTS command,
+    DROP COLUMN IF EXISTS exit_code;
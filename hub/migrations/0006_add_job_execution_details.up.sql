@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+    ADD COLUMN exit_code INTEGER,
+    ADD COLUMN command TEXT[],
+    ADD COLUMN working_dir TEXT;
@@ -0,0 +1,4 @@
+UPDATE jobs SET status = 'failed' WHERE status = 'timed_out';
+
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN ('running', 'completed', 'failed', 'lost'));
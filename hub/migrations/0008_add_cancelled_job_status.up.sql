@@ -0,0 +1,4 @@
+ALTER TABLE jobs ADD COLUMN termination_signal VARCHAR(16);
+
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN ('running', 'completed', 'failed', 'lost', 'timed_out', 'cancelled'));
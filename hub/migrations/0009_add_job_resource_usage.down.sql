@@ -0,0 +1,6 @@
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS io_write_bytes,
+    DROP COLUMN IF EXISTS io_read_bytes,
+    DROP COLUMN IF EXISTS max_rss_bytes,
+    DROP COLUMN IF EXISTS cpu_system_seconds,
+    DROP COLUMN IF EXISTS cpu_user_seconds;
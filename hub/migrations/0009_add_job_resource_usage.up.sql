@@ -0,0 +1,6 @@
+ALTER TABLE jobs
+    ADD COLUMN cpu_user_seconds DOUBLE PRECISION,
+    ADD COLUMN cpu_system_seconds DOUBLE PRECISION,
+    ADD COLUMN max_rss_bytes BIGINT,
+    ADD COLUMN io_read_bytes BIGINT,
+    ADD COLUMN io_write_bytes BIGINT;
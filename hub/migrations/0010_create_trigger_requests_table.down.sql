@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS trigger_requests_created_at_idx;
+
+DROP TABLE IF EXISTS trigger_requests;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS trigger_requests (
+    node_id BIGINT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(128) NOT NULL,
+    status_code INTEGER NOT NULL,
+    response JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (node_id, idempotency_key)
+);
+
+CREATE INDEX trigger_requests_created_at_idx ON trigger_requests (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS jobs_node_id_run_id_key;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS run_id;
@@ -0,0 +1,6 @@
+-- CLI が実行ごとに生成する UUID。start の再送で同じジョブを二重に作らないために使う
+ALTER TABLE jobs ADD COLUMN run_id UUID;
+
+CREATE UNIQUE INDEX jobs_node_id_run_id_key
+ON jobs (node_id, run_id)
+WHERE run_id IS NOT NULL;
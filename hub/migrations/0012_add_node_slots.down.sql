@@ -0,0 +1,34 @@
+DROP INDEX IF EXISTS jobs_active_node_id_idx;
+
+-- 1 ノード 1 ジョブに戻すため、最新以外の実行中ジョブは lost にする
+UPDATE jobs
+SET status = 'lost',
+    finished_at = NOW(),
+    duration_hours = NOW() - started_at,
+    error_text = 'lost: node slots were reduced to one'
+WHERE finished_at IS NULL
+  AND id NOT IN (
+    SELECT MAX(id) FROM jobs WHERE finished_at IS NULL GROUP BY node_id
+  );
+
+CREATE UNIQUE INDEX jobs_one_active_per_node_idx
+ON jobs (node_id)
+WHERE finished_at IS NULL;
+
+ALTER TABLE nodes ADD COLUMN current_job_id BIGINT;
+
+UPDATE nodes
+SET current_job_id = jobs.id
+FROM jobs
+WHERE jobs.node_id = nodes.id AND jobs.finished_at IS NULL;
+
+ALTER TABLE nodes
+    ADD CONSTRAINT nodes_current_job_id_fk
+    FOREIGN KEY (current_job_id)
+    REFERENCES jobs(id)
+    ON DELETE SET NULL
+    DEFERRABLE INITIALLY IMMEDIATE;
+
+ALTER TABLE nodes
+    DROP CONSTRAINT IF EXISTS nodes_slots_check,
+    DROP COLUMN IF EXISTS slots;
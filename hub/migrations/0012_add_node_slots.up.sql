@@ -0,0 +1,13 @@
+-- ノードごとに同時実行できるジョブ数を持たせ、単一の current_job_id をやめる
+ALTER TABLE nodes
+    ADD COLUMN slots INTEGER NOT NULL DEFAULT 1,
+    ADD CONSTRAINT nodes_slots_check CHECK (slots BETWEEN 1 AND 256);
+
+ALTER TABLE nodes DROP CONSTRAINT IF EXISTS nodes_current_job_id_fk;
+ALTER TABLE nodes DROP COLUMN IF EXISTS current_job_id;
+
+DROP INDEX IF EXISTS jobs_one_active_per_node_idx;
+
+CREATE INDEX jobs_active_node_id_idx
+ON jobs (node_id)
+WHERE finished_at IS NULL;
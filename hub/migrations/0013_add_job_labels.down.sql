@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS jobs_labels_idx;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS labels;
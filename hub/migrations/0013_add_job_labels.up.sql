@@ -0,0 +1,3 @@
+ALTER TABLE jobs ADD COLUMN labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX jobs_labels_idx ON jobs USING GIN (labels jsonb_path_ops);
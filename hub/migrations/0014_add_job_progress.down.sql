@@ -0,0 +1,6 @@
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS progress_updated_at,
+    DROP COLUMN IF EXISTS progress_phase,
+    DROP COLUMN IF EXISTS progress_total,
+    DROP COLUMN IF EXISTS progress_step,
+    DROP COLUMN IF EXISTS progress_percent;
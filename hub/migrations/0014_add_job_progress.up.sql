@@ -0,0 +1,6 @@
+ALTER TABLE jobs
+    ADD COLUMN progress_percent DOUBLE PRECISION,
+    ADD COLUMN progress_step BIGINT,
+    ADD COLUMN progress_total BIGINT,
+    ADD COLUMN progress_phase TEXT,
+    ADD COLUMN progress_updated_at TIMESTAMPTZ;
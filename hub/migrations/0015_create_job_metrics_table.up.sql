@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS job_metrics (
+    job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    key VARCHAR(128) NOT NULL,
+    step BIGINT NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (job_id, key, step),
+    CONSTRAINT job_metrics_step_check CHECK (step >= 0)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS job_artifacts (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT job_artifacts_job_id_name_key UNIQUE (job_id, name),
+    CONSTRAINT job_artifacts_size_bytes_check CHECK (size_bytes >= 0)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS job_queue;
+
+ALTER TABLE nodes DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,39 @@
+-- キューに積んだジョブの振り分け先をノードのラベルで指定できるようにする
+ALTER TABLE nodes ADD COLUMN labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Hub に積まれ、jobboard agent に貸し出されるのを待つジョブ。
+-- agent が start した時点で jobs の行ができ、job_id で結び付く
+CREATE TABLE IF NOT EXISTS job_queue (
+    id BIGSERIAL PRIMARY KEY,
+    cluster_id VARCHAR(64) NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    command TEXT[] NOT NULL,
+    working_dir TEXT,
+    tag VARCHAR(128),
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    node_id BIGINT REFERENCES nodes(id) ON DELETE CASCADE,
+    node_selector JSONB NOT NULL DEFAULT '{}'::jsonb,
+    priority INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    leased_node_id BIGINT REFERENCES nodes(id) ON DELETE SET NULL,
+    lease_expires_at TIMESTAMPTZ,
+    job_id BIGINT REFERENCES jobs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT job_queue_status_check CHECK (status IN ('queued', 'leased', 'started', 'failed', 'cancelled')),
+    CONSTRAINT job_queue_priority_check CHECK (priority BETWEEN -1000 AND 1000),
+    CONSTRAINT job_queue_max_attempts_check CHECK (max_attempts BETWEEN 1 AND 100)
+);
+
+CREATE INDEX job_queue_cluster_id_idx ON job_queue (cluster_id, id);
+
+CREATE INDEX job_queue_pending_idx
+ON job_queue (cluster_id, priority DESC, id)
+WHERE status IN ('queued', 'leased');
+
+CREATE INDEX job_queue_leased_node_id_idx
+ON job_queue (leased_node_id)
+WHERE status = 'leased';
+
+CREATE INDEX job_queue_job_id_idx ON job_queue (job_id);
@@ -0,0 +1,3 @@
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS cancel_requested_by,
+    DROP COLUMN IF EXISTS cancel_requested_at;
@@ -0,0 +1,3 @@
+ALTER TABLE jobs
+    ADD COLUMN cancel_requested_at TIMESTAMPTZ,
+    ADD COLUMN cancel_requested_by TEXT;
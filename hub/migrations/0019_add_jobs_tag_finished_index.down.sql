@@ -0,0 +1 @@
+DROP INDEX IF EXISTS jobs_cluster_tag_finished_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX jobs_cluster_tag_finished_idx
+    ON jobs (cluster_id, tag, finished_at DESC)
+    WHERE finished_at IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS jobs_node_started_idx;
+DROP INDEX IF EXISTS jobs_cluster_started_idx;
+CREATE INDEX jobs_node_id_idx ON jobs (node_id);
+CREATE INDEX jobs_cluster_id_idx ON jobs (cluster_id);
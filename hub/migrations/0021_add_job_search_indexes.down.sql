@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS job_logs_data_search_idx;
+DROP INDEX IF EXISTS jobs_error_text_search_idx;
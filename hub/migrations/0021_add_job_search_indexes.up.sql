@@ -0,0 +1,6 @@
+-- スタックトレースやログは言語が混ざり、語幹処理すると識別子が崩れるため simple 構成を使う。
+-- 検索クエリは同じ式を使わないとインデックスが効かない。
+CREATE INDEX jobs_error_text_search_idx
+    ON jobs USING GIN (to_tsvector('simple', COALESCE(error_text, '')));
+CREATE INDEX job_logs_data_search_idx
+    ON job_logs USING GIN (to_tsvector('simple', data));
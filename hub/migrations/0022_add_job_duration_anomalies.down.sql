@@ -0,0 +1,8 @@
+ALTER TABLE jobs
+    DROP CONSTRAINT IF EXISTS jobs_duration_anomaly_check,
+    DROP COLUMN IF EXISTS duration_anomaly_at,
+    DROP COLUMN IF EXISTS duration_baseline_seconds,
+    DROP COLUMN IF EXISTS duration_anomaly_score,
+    DROP COLUMN IF EXISTS duration_anomaly;
+
+DROP TABLE IF EXISTS job_duration_baselines;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS job_duration_baselines (
+    cluster_id VARCHAR(64) NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    sample_count INTEGER NOT NULL,
+    median_seconds DOUBLE PRECISION NOT NULL,
+    mad_seconds DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cluster_id, tag)
+);
+
+ALTER TABLE jobs
+    ADD COLUMN duration_anomaly TEXT,
+    ADD COLUMN duration_anomaly_score DOUBLE PRECISION,
+    ADD COLUMN duration_baseline_seconds DOUBLE PRECISION,
+    ADD COLUMN duration_anomaly_at TIMESTAMPTZ,
+    ADD CONSTRAINT jobs_duration_anomaly_check CHECK (duration_anomaly IN ('slow', 'fast'));
@@ -0,0 +1,2 @@
+ALTER TABLE clusters
+    ADD COLUMN notify_emails TEXT[] NOT NULL DEFAULT '{}';
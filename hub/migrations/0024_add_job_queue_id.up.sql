@@ -0,0 +1,4 @@
+-- キューから借りて start したジョブは、どのキューのジョブの試行かを残す。
+-- lost の後にキューへ戻して再び貸し出した場合、古い試行の finish で上書きしないために使う
+ALTER TABLE jobs
+    ADD COLUMN queue_id BIGINT REFERENCES job_queue(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS jobs_node_active_idx;
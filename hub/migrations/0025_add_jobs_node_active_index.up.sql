@@ -0,0 +1,2 @@
+-- ジョブの開始・終了のたびにノードの実行中ジョブを読むので、終了していないジョブだけの索引を持つ
+CREATE INDEX jobs_node_active_idx ON jobs (node_id, id) WHERE finished_at IS NULL;
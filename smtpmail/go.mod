@@ -0,0 +1,3 @@
+module github.com/kanaya/jobboard-smtpmail
+
+go 1.25.2
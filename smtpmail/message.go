@@ -0,0 +1,96 @@
+package smtpmail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// BuildMessage は text/plain と text/html の multipart/alternative のメッセージを作る。
+func BuildMessage(from string, to []string, subject, text, html string, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var id [12]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"From":         from,
+		"To":           strings.Join(to, ", "),
+		"Subject":      mime.QEncoding.Encode("utf-8", subject),
+		"Date":         now.Format(time.RFC1123Z),
+		"Message-ID":   "<" + hex.EncodeToString(id[:]) + "@jobboard>",
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var msg bytes.Buffer
+	for _, key := range keys {
+		msg.WriteString(key + ": " + headers[key] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// ErrorTail は長いエラー出力の末尾 maxLines 行（最大 maxBytes バイト）だけを残す。原因は末尾に出ることが多いため。
+func ErrorTail(s string, maxLines, maxBytes int) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxBytes {
+		// 文字の途中で切らないよう、次の文字の始まりまで進める
+		cut := len(tail) - maxBytes
+		for cut < len(tail) && !utf8.RuneStart(tail[cut]) {
+			cut++
+		}
+		tail = "..." + tail[cut:]
+	}
+	return tail
+}
@@ -0,0 +1,91 @@
+// Package smtpmail は Hub と CLI のメール通知で共有する、SMTP での送信とメッセージの組み立て。
+package smtpmail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+)
+
+// TLS の扱い
+const (
+	// TLSStartTLS は STARTTLS を必須とする
+	TLSStartTLS = "starttls"
+	// TLSImplicit は接続直後から TLS で話す（いわゆる SMTPS）
+	TLSImplicit = "tls"
+	// TLSNone は平文で送る
+	TLSNone = "none"
+)
+
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	TLS      string
+}
+
+// Send は net/smtp.SendMail と同じ手順で送るが、ctx の期限を接続に設定し、TLS の扱いを選べる。
+func Send(ctx context.Context, cfg Config, from string, to []string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, cfg.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	if cfg.TLS == TLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		// PlainAuth は localhost 以外への平文接続では認証情報を送らない
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
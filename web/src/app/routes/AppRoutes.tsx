@@ -6,6 +6,7 @@ import LoginPage from "../../features/auth/pages/LoginPage";
 import RegisterPage from "../../features/auth/pages/RegisterPage";
 import NodesPage from "../../features/nodes/pages/NodesPage";
 import JobsPage from "../../features/jobs/pages/JobsPage";
+import QueuePage from "../../features/queue/pages/QueuePage";
 import ProtectedRoute from "../../features/auth/components/ProtectedRoute";
 import RedirectIfAuthenticated from "../../features/auth/components/RedirectIfAuthenticated";
 
@@ -37,6 +38,7 @@ export default function AppRoutes() {
           <Route index element={<Navigate to="nodes" replace />} />
           <Route path="nodes" element={<NodesPage />} />
           <Route path="jobs" element={<JobsPage />} />
+          <Route path="queue" element={<QueuePage />} />
         </Route>
 
         <Route path="*" element={<Navigate to="/" replace />} />
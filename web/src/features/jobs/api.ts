@@ -1,7 +1,27 @@
 import { apiRequest } from "../../lib/apiCient";
 import type { StoredAuth } from "../../lib/storage";
 import type { JobDto } from "./schemas";
-import { jobsArraySchema } from "./schemas";
+import { jobPageSchema, jobSchema } from "./schemas";
+
+export type JobProgress = {
+  percent: number | null;
+  step: number | null;
+  total: number | null;
+  phase: string | null;
+  updatedAt: Date;
+};
+
+export type JobCancelRequest = {
+  requestedAt: Date;
+  requestedBy: string;
+};
+
+export type JobDurationBaseline = {
+  medianHours: number;
+  score: number | null;
+  anomaly: "slow" | "fast" | null;
+  flaggedAt: Date | null;
+};
 
 export type Job = {
   id: number;
@@ -13,6 +33,10 @@ export type Job = {
   durationHours: number | null;
   tag: string | null;
   errorText: string | null;
+  labels: Record<string, string>;
+  progress: JobProgress | null;
+  cancelRequest: JobCancelRequest | null;
+  durationBaseline: JobDurationBaseline | null;
 };
 
 function parseDuration(value: JobDto["duration_hours"]): number | null {
@@ -44,16 +68,64 @@ function mapJob(dto: JobDto): Job {
     durationHours: parseDuration(dto.duration_hours),
     tag: dto.tag ?? null,
     errorText: dto.error_text ?? null,
+    labels: dto.labels ?? {},
+    progress: dto.progress
+      ? {
+          percent: dto.progress.percent ?? null,
+          step: dto.progress.step ?? null,
+          total: dto.progress.total ?? null,
+          phase: dto.progress.phase ?? null,
+          updatedAt: dto.progress.updated_at,
+        }
+      : null,
+    cancelRequest: dto.cancel_request
+      ? {
+          requestedAt: dto.cancel_request.requested_at,
+          requestedBy: dto.cancel_request.requested_by,
+        }
+      : null,
+    durationBaseline: dto.duration_baseline
+      ? {
+          medianHours: dto.duration_baseline.median_hours,
+          score: dto.duration_baseline.score ?? null,
+          anomaly: dto.duration_baseline.anomaly ?? null,
+          flaggedAt: dto.duration_baseline.flagged_at ?? null,
+        }
+      : null,
   };
 }
 
-export async function fetchJobs(auth: StoredAuth): Promise<Job[]> {
-  const dto = await apiRequest(`/api/jobs`, {
+export type JobPage = {
+  items: Job[];
+  nextCursor: string | null;
+  totalCount: number | null;
+};
+
+export const JOB_PAGE_SIZE = 50;
+
+export async function fetchJobs(auth: StoredAuth, cursor?: string | null): Promise<JobPage> {
+  const params = new URLSearchParams({ limit: String(JOB_PAGE_SIZE) });
+  if (cursor) {
+    params.set("cursor", cursor);
+  } else {
+    params.set("include_total", "true");
+  }
+  const dto = await apiRequest(`/api/jobs?${params.toString()}`, {
     method: "GET",
     token: auth.token,
   });
-  console.log("Raw job DTOs:", dto);
-  const jobs = jobsArraySchema.parse(dto);
-  console.log("Fetched jobs:", jobs);
-  return jobs.map(mapJob);
+  const page = jobPageSchema.parse(dto);
+  return {
+    items: page.items.map(mapJob),
+    nextCursor: page.next_cursor,
+    totalCount: page.total_count ?? null,
+  };
+}
+
+export async function cancelJob(auth: StoredAuth, jobId: number): Promise<Job> {
+  const dto = await apiRequest(`/api/jobs/${jobId}/cancel`, {
+    method: "POST",
+    token: auth.token,
+  });
+  return mapJob(jobSchema.parse(dto));
 }
@@ -1,4 +1,5 @@
 import RefreshIcon from "@mui/icons-material/Refresh";
+import StopCircleOutlinedIcon from "@mui/icons-material/StopCircleOutlined";
 import {
   Alert,
   Box,
@@ -10,6 +11,7 @@ import {
   DialogContent,
   DialogTitle,
   IconButton,
+  LinearProgress,
   Paper,
   Stack,
   Table,
@@ -21,10 +23,11 @@ import {
   Tooltip,
   Typography,
 } from "@mui/material";
-import { useQuery } from "@tanstack/react-query";
+import { useInfiniteQuery, useMutation, useQueryClient } from "@tanstack/react-query";
 import { useState } from "react";
 import { useAuth } from "../../auth/AuthContext";
-import { fetchJobs, type Job } from "../api";
+import { cancelJob, fetchJobs, type Job, type JobProgress } from "../api";
+import { resolveErrorMessage } from "../../../lib/errorCatalog";
 
 type StatusKey = "running" | "completed" | "failed" | "other";
 
@@ -79,10 +82,36 @@ function StatusChip({ status, onClick }: { status: string; onClick?: () => void
   );
 }
 
+function ProgressSummary({ progress }: { progress: JobProgress }) {
+  const parts: string[] = [];
+  if (progress.percent != null) {
+    parts.push(`${progress.percent.toFixed(1)}%`);
+  }
+  if (progress.step != null && progress.total != null) {
+    parts.push(`${progress.step}/${progress.total}`);
+  }
+  if (progress.phase) {
+    parts.push(progress.phase);
+  }
+
+  return (
+    <Box sx={{ mt: 1, minWidth: 160 }}>
+      {progress.percent != null && (
+        <LinearProgress variant="determinate" value={progress.percent} sx={{ mb: 0.5 }} />
+      )}
+      <Typography variant="caption" color="text.secondary">
+        {parts.join(" · ")}
+      </Typography>
+    </Box>
+  );
+}
+
 export default function JobsPage() {
   const { auth } = useAuth();
   const [selectedJob, setSelectedJob] = useState<Job | null>(null);
   const [isErrorDialogOpen, setIsErrorDialogOpen] = useState(false);
+  const [cancelError, setCancelError] = useState<string | null>(null);
+  const queryClient = useQueryClient();
 
   const requireAuth = () => {
     if (!auth) {
@@ -92,11 +121,31 @@ export default function JobsPage() {
     return auth;
   };
 
-  const jobsQuery = useQuery({
+  const jobsQuery = useInfiniteQuery({
     queryKey: ["jobs"],
-    queryFn: () => fetchJobs(requireAuth()),
+    queryFn: ({ pageParam }) => fetchJobs(requireAuth(), pageParam),
+    initialPageParam: null as string | null,
+    getNextPageParam: (lastPage) => lastPage.nextCursor,
     enabled: Boolean(auth?.token),
   });
+  const jobs = jobsQuery.data?.pages.flatMap((page) => page.items) ?? [];
+  const totalCount = jobsQuery.data?.pages[0]?.totalCount ?? null;
+
+  const cancelMutation = useMutation({
+    mutationFn: (jobId: number) => cancelJob(requireAuth(), jobId),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ["jobs"] });
+    },
+    onError: (error: unknown) => {
+      setCancelError(resolveErrorMessage(error, "ジョブの取り消しに失敗しました"));
+      queryClient.invalidateQueries({ queryKey: ["jobs"] });
+    },
+  });
+
+  const handleCancel = (job: Job) => {
+    setCancelError(null);
+    cancelMutation.mutate(job.id);
+  };
 
   const handleOpenErrorDialog = (job: Job) => {
     setSelectedJob(job);
@@ -116,6 +165,12 @@ export default function JobsPage() {
         </IconButton>
       </Toolbar>
 
+      {cancelError ? (
+        <Alert severity="error" sx={{ mb: 2 }} onClose={() => setCancelError(null)}>
+          {cancelError}
+        </Alert>
+      ) : null}
+
       {jobsQuery.isLoading ? (
         <Box sx={{ py: 8, textAlign: "center" }}>
           <CircularProgress />
@@ -135,11 +190,13 @@ export default function JobsPage() {
               <TableCell>終了</TableCell>
               <TableCell>所要時間 (h)</TableCell>
               <TableCell>タグ</TableCell>
+              <TableCell>ラベル</TableCell>
+              <TableCell align="right">操作</TableCell>
             </TableRow>
           </TableHead>
           <TableBody>
-            {jobsQuery.data && jobsQuery.data.length > 0 ? (
-              jobsQuery.data.map((job) => {
+            {jobs.length > 0 ? (
+              jobs.map((job) => {
                 const isFailed = job.status.toLowerCase() === "failed";
                 const statusChip = (
                   <StatusChip status={job.status} onClick={isFailed ? () => handleOpenErrorDialog(job) : undefined} />
@@ -157,17 +214,70 @@ export default function JobsPage() {
                       ) : (
                         statusChip
                       )}
+                      {job.status.toLowerCase() === "running" && job.progress && (
+                        <ProgressSummary progress={job.progress} />
+                      )}
                     </TableCell>
                     <TableCell>{job.startedAt ? job.startedAt.toLocaleString() : "-"}</TableCell>
                     <TableCell>{job.finishedAt ? job.finishedAt.toLocaleString() : "-"}</TableCell>
-                    <TableCell>{job.durationHours != null ? job.durationHours.toFixed(2) : "-"}</TableCell>
+                    <TableCell>
+                      {job.durationHours != null ? job.durationHours.toFixed(2) : "-"}
+                      {job.durationBaseline?.anomaly && (
+                        <Tooltip title={`通常は ${job.durationBaseline.medianHours.toFixed(2)} h（直近の中央値）`} arrow>
+                          <Chip
+                            label={job.durationBaseline.anomaly === "slow" ? "遅い" : "速い"}
+                            size="small"
+                            color={job.durationBaseline.anomaly === "slow" ? "warning" : "info"}
+                            variant="outlined"
+                            sx={{ ml: 1 }}
+                          />
+                        </Tooltip>
+                      )}
+                    </TableCell>
                     <TableCell>{job.tag ?? "-"}</TableCell>
+                    <TableCell>
+                      {Object.keys(job.labels).length > 0 ? (
+                        <Stack direction="row" spacing={0.5} useFlexGap flexWrap="wrap">
+                          {Object.entries(job.labels)
+                            .sort(([a], [b]) => a.localeCompare(b))
+                            .map(([key, value]) => (
+                              <Chip key={key} label={`${key}=${value}`} size="small" variant="outlined" />
+                            ))}
+                        </Stack>
+                      ) : (
+                        "-"
+                      )}
+                    </TableCell>
+                    <TableCell align="right">
+                      {job.status.toLowerCase() === "running" ? (
+                        <Tooltip
+                          title={
+                            job.cancelRequest
+                              ? `${job.cancelRequest.requestedBy} が ${job.cancelRequest.requestedAt.toLocaleString()} に取り消しを要求済み`
+                              : "ジョブを取り消す"
+                          }
+                        >
+                          <span>
+                            <IconButton
+                              color="error"
+                              onClick={() => handleCancel(job)}
+                              disabled={
+                                Boolean(job.cancelRequest) ||
+                                (cancelMutation.isPending && cancelMutation.variables === job.id)
+                              }
+                            >
+                              <StopCircleOutlinedIcon />
+                            </IconButton>
+                          </span>
+                        </Tooltip>
+                      ) : null}
+                    </TableCell>
                   </TableRow>
                 );
               })
             ) : (
               <TableRow>
-                <TableCell colSpan={7}>
+                <TableCell colSpan={9}>
                   <Stack sx={{ py: 6, textAlign: "center", color: "text.secondary" }}>ジョブ履歴がありません</Stack>
                 </TableCell>
               </TableRow>
@@ -176,6 +286,19 @@ export default function JobsPage() {
         </Table>
       )}
 
+      {jobs.length > 0 ? (
+        <Stack direction="row" spacing={2} sx={{ mt: 2, alignItems: "center", justifyContent: "center" }}>
+          <Typography variant="body2" color="text.secondary">
+            {totalCount != null ? `${totalCount} 件中 ${jobs.length} 件を表示` : `${jobs.length} 件を表示`}
+          </Typography>
+          {jobsQuery.hasNextPage ? (
+            <Button onClick={() => jobsQuery.fetchNextPage()} disabled={jobsQuery.isFetchingNextPage}>
+              {jobsQuery.isFetchingNextPage ? <CircularProgress size={20} /> : "さらに読み込む"}
+            </Button>
+          ) : null}
+        </Stack>
+      ) : null}
+
       <Dialog
         open={isErrorDialogOpen}
         onClose={handleCloseErrorDialog}
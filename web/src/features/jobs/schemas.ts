@@ -8,6 +8,26 @@ const intervalObjectSchema = z
 
 const durationSchema = z.union([z.number(), z.string(), intervalObjectSchema, z.null(), z.undefined()]);
 
+const progressSchema = z.object({
+  percent: z.number().nullable().optional(),
+  step: z.number().nullable().optional(),
+  total: z.number().nullable().optional(),
+  phase: z.string().nullable().optional(),
+  updated_at: z.coerce.date(),
+});
+
+const cancelRequestSchema = z.object({
+  requested_at: z.coerce.date(),
+  requested_by: z.string(),
+});
+
+const durationBaselineSchema = z.object({
+  median_hours: z.number(),
+  score: z.number().nullable().optional(),
+  anomaly: z.enum(["slow", "fast"]).nullable().optional(),
+  flagged_at: z.coerce.date().nullable().optional(),
+});
+
 export const jobSchema = z.object({
   id: z.number(),
   cluster_id: z.string(),
@@ -18,9 +38,20 @@ export const jobSchema = z.object({
   duration_hours: durationSchema.optional(),
   tag: z.string().nullable().optional(),
   error_text: z.string().nullable().optional(),
+  labels: z.record(z.string()).optional(),
+  progress: progressSchema.nullable().optional(),
+  cancel_request: cancelRequestSchema.nullable().optional(),
+  duration_baseline: durationBaselineSchema.nullable().optional(),
 });
 
 export type JobDto = z.infer<typeof jobSchema>;
 
 export const jobsArraySchema = z.array(jobSchema);
 export type JobsArrayDto = z.infer<typeof jobsArraySchema>;
+
+export const jobPageSchema = z.object({
+  items: jobsArraySchema,
+  next_cursor: z.string().nullable(),
+  total_count: z.number().optional(),
+});
+export type JobPageDto = z.infer<typeof jobPageSchema>;
@@ -7,7 +7,9 @@ import { nodeSchema } from "./schemas";
 export type Node = {
   id: number;
   nodeName: string;
-  currentJobId: number | null;
+  slots: number;
+  runningJobIds: number[];
+  labels: Record<string, string>;
   createdAt: Date;
 };
 
@@ -17,7 +19,9 @@ function mapNode(dto: NodeDto): Node {
   return {
     id: dto.id,
     nodeName: dto.node_name,
-    currentJobId: dto.current_job_id ?? null,
+    slots: dto.slots,
+    runningJobIds: dto.running_job_ids,
+    labels: dto.labels ?? {},
     createdAt: dto.created_at,
   };
 }
@@ -42,6 +46,7 @@ export async function createNode(auth: StoredAuth, request: CreateNodeRequest):
     token: auth.token,
     body: {
       node_name: request.nodeName,
+      slots: request.slots,
     },
   });
   const parsed = nodeSchema.parse(dto);
@@ -14,17 +14,19 @@ type FormErrors = Partial<Record<keyof CreateNodeRequest, string>>;
 
 export default function NodeCreateDialog({ open, onClose, onSubmit, loading, apiError }: NodeCreateDialogProps) {
   const [nodeName, setNodeName] = useState("");
+  const [slots, setSlots] = useState("1");
   const [errors, setErrors] = useState<FormErrors>({});
 
   const handleClose = () => {
     setErrors({});
     setNodeName("");
+    setSlots("1");
     onClose();
   };
 
   const handleSubmit = async (event: React.FormEvent<HTMLFormElement>) => {
     event.preventDefault();
-    const parseResult = createNodeRequestSchema.safeParse({ nodeName });
+    const parseResult = createNodeRequestSchema.safeParse({ nodeName, slots });
     if (!parseResult.success) {
       const fieldErrors: FormErrors = {};
       parseResult.error.issues.forEach((issue) => {
@@ -39,6 +41,7 @@ export default function NodeCreateDialog({ open, onClose, onSubmit, loading, api
     setErrors({});
     await onSubmit(parseResult.data);
     setNodeName("");
+    setSlots("1");
   };
 
   return (
@@ -57,6 +60,17 @@ export default function NodeCreateDialog({ open, onClose, onSubmit, loading, api
               required
               fullWidth
             />
+            <TextField
+              label="同時実行スロット数"
+              type="number"
+              value={slots}
+              onChange={(event) => setSlots(event.target.value)}
+              error={Boolean(errors.slots)}
+              helperText={errors.slots}
+              disabled={loading}
+              inputProps={{ min: 1, max: 256 }}
+              fullWidth
+            />
           </Stack>
         </DialogContent>
         <DialogActions sx={{ px: 3, pb: 2 }}>
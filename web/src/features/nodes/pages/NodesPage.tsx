@@ -4,6 +4,7 @@ import RefreshIcon from "@mui/icons-material/Refresh";
 import {
   Alert,
   Box,
+  Chip,
   Button,
   CircularProgress,
   IconButton,
@@ -119,7 +120,9 @@ export default function NodesPage() {
               <TableRow>
                 <TableCell>ID</TableCell>
                 <TableCell>ノード名</TableCell>
-                <TableCell>現在のジョブID</TableCell>
+                <TableCell>スロット</TableCell>
+                <TableCell>ラベル</TableCell>
+                <TableCell>実行中のジョブID</TableCell>
                 <TableCell>作成日時</TableCell>
                 <TableCell align="right">操作</TableCell>
               </TableRow>
@@ -130,7 +133,23 @@ export default function NodesPage() {
                   <TableRow key={node.id} hover>
                     <TableCell>{node.id}</TableCell>
                     <TableCell>{node.nodeName}</TableCell>
-                    <TableCell>{node.currentJobId ?? "なし"}</TableCell>
+                    <TableCell>
+                      {node.runningJobIds.length} / {node.slots}
+                    </TableCell>
+                    <TableCell>
+                      {Object.keys(node.labels).length > 0 ? (
+                        <Stack direction="row" spacing={0.5} flexWrap="wrap" useFlexGap>
+                          {Object.entries(node.labels)
+                            .sort(([a], [b]) => a.localeCompare(b))
+                            .map(([key, value]) => (
+                              <Chip key={key} label={`${key}=${value}`} size="small" variant="outlined" />
+                            ))}
+                        </Stack>
+                      ) : (
+                        "-"
+                      )}
+                    </TableCell>
+                    <TableCell>{node.runningJobIds.length > 0 ? node.runningJobIds.join(", ") : "なし"}</TableCell>
                     <TableCell>{node.createdAt.toLocaleString()}</TableCell>
                     <TableCell align="right">
                       <Tooltip title="ノードを削除">
@@ -152,7 +171,7 @@ export default function NodesPage() {
                 ))
               ) : (
                 <TableRow>
-                  <TableCell colSpan={5}>
+                  <TableCell colSpan={7}>
                     <Box sx={{ py: 6, textAlign: "center", color: "text.secondary" }}>ノードがありません</Box>
                   </TableCell>
                 </TableRow>
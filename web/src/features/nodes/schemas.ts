@@ -3,7 +3,9 @@ import { z } from "zod";
 export const nodeSchema = z.object({
   id: z.number(),
   node_name: z.string(),
-  current_job_id: z.number().nullable().optional(),
+  slots: z.number(),
+  running_job_ids: z.array(z.number()),
+  labels: z.record(z.string()).optional(),
   created_at: z.coerce.date(),
   node_token: z.string().optional(),
 });
@@ -12,6 +14,11 @@ export type NodeDto = z.infer<typeof nodeSchema>;
 
 export const createNodeRequestSchema = z.object({
   nodeName: z.string().min(1, "ノード名を入力してください").max(255, "ノード名は255文字以内で入力してください"),
+  slots: z.coerce
+    .number()
+    .int("スロット数は整数で入力してください")
+    .min(1, "スロット数は1以上で入力してください")
+    .max(256, "スロット数は256以下で入力してください"),
 });
 
 export type CreateNodeRequest = z.infer<typeof createNodeRequestSchema>;
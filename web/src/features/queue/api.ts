@@ -0,0 +1,98 @@
+import { z } from "zod";
+import { apiRequest } from "../../lib/apiCient";
+import type { StoredAuth } from "../../lib/storage";
+import type { EnqueueJobRequest, QueuedJobDto } from "./schemas";
+import { queuedJobSchema } from "./schemas";
+
+export type QueuedJob = {
+  id: number;
+  command: string[];
+  workingDir: string | null;
+  tag: string | null;
+  labels: Record<string, string>;
+  nodeId: number | null;
+  nodeSelector: Record<string, string>;
+  priority: number;
+  status: string;
+  attempts: number;
+  maxAttempts: number;
+  leasedNodeId: number | null;
+  leaseExpiresAt: Date | null;
+  jobId: number | null;
+  createdAt: Date;
+  updatedAt: Date;
+};
+
+const queuedJobsArraySchema = z.array(queuedJobSchema);
+
+function mapQueuedJob(dto: QueuedJobDto): QueuedJob {
+  return {
+    id: dto.id,
+    command: dto.command,
+    workingDir: dto.working_dir,
+    tag: dto.tag,
+    labels: dto.labels,
+    nodeId: dto.node_id,
+    nodeSelector: dto.node_selector,
+    priority: dto.priority,
+    status: dto.status,
+    attempts: dto.attempts,
+    maxAttempts: dto.max_attempts,
+    leasedNodeId: dto.leased_node_id,
+    leaseExpiresAt: dto.lease_expires_at,
+    jobId: dto.job_id,
+    createdAt: dto.created_at,
+    updatedAt: dto.updated_at,
+  };
+}
+
+function parseLabels(input: string): Record<string, string> {
+  const labels: Record<string, string> = {};
+  input
+    .split(",")
+    .map((item) => item.trim())
+    .filter((item) => item !== "")
+    .forEach((item) => {
+      const index = item.indexOf("=");
+      labels[item.slice(0, index)] = item.slice(index + 1);
+    });
+  return labels;
+}
+
+// コマンドは空白区切りで argv に分割する。引用符によるエスケープは扱わない
+function splitCommand(input: string): string[] {
+  return input.split(/\s+/).filter((item) => item !== "");
+}
+
+export async function fetchQueuedJobs(auth: StoredAuth): Promise<QueuedJob[]> {
+  const dto = await apiRequest(`/api/queue`, {
+    method: "GET",
+    token: auth.token,
+  });
+  const items = queuedJobsArraySchema.parse(dto);
+  return items.map(mapQueuedJob);
+}
+
+export async function enqueueJob(auth: StoredAuth, request: EnqueueJobRequest): Promise<QueuedJob> {
+  const dto = await apiRequest(`/api/queue`, {
+    method: "POST",
+    token: auth.token,
+    body: {
+      command: splitCommand(request.command),
+      working_dir: request.workingDir === "" ? null : request.workingDir,
+      tag: request.tag === "" ? null : request.tag,
+      labels: parseLabels(request.labels),
+      node_selector: parseLabels(request.nodeSelector),
+      priority: request.priority,
+      max_attempts: request.maxAttempts,
+    },
+  });
+  return mapQueuedJob(queuedJobSchema.parse(dto));
+}
+
+export async function cancelQueuedJob(auth: StoredAuth, queueId: number): Promise<void> {
+  await apiRequest(`/api/queue/${queueId}`, {
+    method: "DELETE",
+    token: auth.token,
+  });
+}
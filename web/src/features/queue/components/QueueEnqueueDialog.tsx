@@ -0,0 +1,146 @@
+import { Dialog, DialogTitle, DialogContent, DialogActions, Button, TextField, Stack } from "@mui/material";
+import { useState } from "react";
+import { enqueueJobRequestSchema, type EnqueueJobRequest } from "../schemas";
+
+type QueueEnqueueDialogProps = {
+  open: boolean;
+  onClose: () => void;
+  onSubmit: (values: EnqueueJobRequest) => Promise<void>;
+  loading?: boolean;
+  apiError?: string | null;
+};
+
+type FormErrors = Partial<Record<keyof EnqueueJobRequest, string>>;
+
+const initialValues = {
+  command: "",
+  workingDir: "",
+  tag: "",
+  labels: "",
+  nodeSelector: "",
+  priority: "0",
+  maxAttempts: "3",
+};
+
+export default function QueueEnqueueDialog({ open, onClose, onSubmit, loading, apiError }: QueueEnqueueDialogProps) {
+  const [values, setValues] = useState(initialValues);
+  const [errors, setErrors] = useState<FormErrors>({});
+
+  const setField = (field: keyof typeof initialValues) => (event: React.ChangeEvent<HTMLInputElement>) => {
+    setValues((prev) => ({ ...prev, [field]: event.target.value }));
+  };
+
+  const handleClose = () => {
+    setErrors({});
+    setValues(initialValues);
+    onClose();
+  };
+
+  const handleSubmit = async (event: React.FormEvent<HTMLFormElement>) => {
+    event.preventDefault();
+    const parseResult = enqueueJobRequestSchema.safeParse(values);
+    if (!parseResult.success) {
+      const fieldErrors: FormErrors = {};
+      parseResult.error.issues.forEach((issue) => {
+        const field = issue.path[0];
+        if (typeof field === "string") {
+          fieldErrors[field as keyof EnqueueJobRequest] = issue.message;
+        }
+      });
+      setErrors(fieldErrors);
+      return;
+    }
+    setErrors({});
+    await onSubmit(parseResult.data);
+    setValues(initialValues);
+  };
+
+  return (
+    <Dialog open={open} onClose={handleClose} fullWidth maxWidth="sm">
+      <form onSubmit={handleSubmit} noValidate>
+        <DialogTitle>ジョブをキューに追加</DialogTitle>
+        <DialogContent>
+          <Stack spacing={3} sx={{ mt: 1 }}>
+            <TextField
+              label="コマンド"
+              value={values.command}
+              onChange={setField("command")}
+              error={Boolean(errors.command)}
+              helperText={errors.command || apiError || "空白区切りで引数に分割されます"}
+              disabled={loading}
+              required
+              fullWidth
+            />
+            <TextField
+              label="作業ディレクトリ"
+              value={values.workingDir}
+              onChange={setField("workingDir")}
+              helperText="省略すると agent を起動したディレクトリで実行します"
+              disabled={loading}
+              fullWidth
+            />
+            <TextField
+              label="タグ"
+              value={values.tag}
+              onChange={setField("tag")}
+              error={Boolean(errors.tag)}
+              helperText={errors.tag}
+              disabled={loading}
+              fullWidth
+            />
+            <TextField
+              label="ラベル"
+              value={values.labels}
+              onChange={setField("labels")}
+              error={Boolean(errors.labels)}
+              helperText={errors.labels || "例: team=vision,exp=baseline"}
+              disabled={loading}
+              fullWidth
+            />
+            <TextField
+              label="ノードセレクタ"
+              value={values.nodeSelector}
+              onChange={setField("nodeSelector")}
+              error={Boolean(errors.nodeSelector)}
+              helperText={errors.nodeSelector || "例: gpu=a100 (すべてのラベルを持つノードだけが実行します)"}
+              disabled={loading}
+              fullWidth
+            />
+            <Stack direction="row" spacing={2}>
+              <TextField
+                label="優先度"
+                type="number"
+                value={values.priority}
+                onChange={setField("priority")}
+                error={Boolean(errors.priority)}
+                helperText={errors.priority || "大きいほど先に実行されます"}
+                disabled={loading}
+                inputProps={{ min: -1000, max: 1000 }}
+                fullWidth
+              />
+              <TextField
+                label="最大試行回数"
+                type="number"
+                value={values.maxAttempts}
+                onChange={setField("maxAttempts")}
+                error={Boolean(errors.maxAttempts)}
+                helperText={errors.maxAttempts}
+                disabled={loading}
+                inputProps={{ min: 1, max: 100 }}
+                fullWidth
+              />
+            </Stack>
+          </Stack>
+        </DialogContent>
+        <DialogActions sx={{ px: 3, pb: 2 }}>
+          <Button onClick={handleClose} disabled={loading}>
+            キャンセル
+          </Button>
+          <Button type="submit" variant="contained" disabled={loading}>
+            追加
+          </Button>
+        </DialogActions>
+      </form>
+    </Dialog>
+  );
+}
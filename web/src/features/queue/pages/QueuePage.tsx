@@ -0,0 +1,212 @@
+import AddIcon from "@mui/icons-material/Add";
+import CancelOutlinedIcon from "@mui/icons-material/CancelOutlined";
+import RefreshIcon from "@mui/icons-material/Refresh";
+import {
+  Alert,
+  Box,
+  Button,
+  Chip,
+  CircularProgress,
+  IconButton,
+  Paper,
+  Stack,
+  Table,
+  TableBody,
+  TableCell,
+  TableHead,
+  TableRow,
+  Toolbar,
+  Tooltip,
+  Typography,
+} from "@mui/material";
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { useState } from "react";
+import { useAuth } from "../../auth/AuthContext";
+import QueueEnqueueDialog from "../components/QueueEnqueueDialog";
+import { cancelQueuedJob, enqueueJob, fetchQueuedJobs, type QueuedJob } from "../api";
+import type { EnqueueJobRequest } from "../schemas";
+import { resolveErrorMessage } from "../../../lib/errorCatalog";
+
+const CANCELLABLE_STATUSES = new Set(["queued", "leased"]);
+
+const STATUS_COLORS: Record<string, "default" | "info" | "warning" | "success" | "error"> = {
+  queued: "default",
+  leased: "info",
+  started: "success",
+  failed: "error",
+  cancelled: "warning",
+};
+
+function LabelChips({ labels }: { labels: Record<string, string> }) {
+  if (Object.keys(labels).length === 0) {
+    return <>-</>;
+  }
+  return (
+    <Stack direction="row" spacing={0.5} useFlexGap flexWrap="wrap">
+      {Object.entries(labels)
+        .sort(([a], [b]) => a.localeCompare(b))
+        .map(([key, value]) => (
+          <Chip key={key} label={`${key}=${value}`} size="small" variant="outlined" />
+        ))}
+    </Stack>
+  );
+}
+
+export default function QueuePage() {
+  const { auth } = useAuth();
+  const queryClient = useQueryClient();
+  const [isEnqueueOpen, setIsEnqueueOpen] = useState(false);
+  const [enqueueError, setEnqueueError] = useState<string | null>(null);
+  const [cancelError, setCancelError] = useState<string | null>(null);
+
+  const requireAuth = () => {
+    if (!auth) {
+      throw new Error("Authentication is required to manage the queue");
+    }
+    return auth;
+  };
+
+  const queueQuery = useQuery({
+    queryKey: ["queue"],
+    queryFn: () => fetchQueuedJobs(requireAuth()),
+    enabled: Boolean(auth?.token),
+  });
+
+  const enqueueMutation = useMutation({
+    mutationFn: (input: EnqueueJobRequest) => enqueueJob(requireAuth(), input),
+    onSuccess: () => {
+      setIsEnqueueOpen(false);
+      queryClient.invalidateQueries({ queryKey: ["queue"] });
+    },
+    onError: (error: unknown) => {
+      setEnqueueError(resolveErrorMessage(error, "ジョブの追加に失敗しました"));
+    },
+  });
+
+  const cancelMutation = useMutation({
+    mutationFn: (queueId: number) => cancelQueuedJob(requireAuth(), queueId),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ["queue"] });
+    },
+    onError: (error: unknown) => {
+      setCancelError(resolveErrorMessage(error, "ジョブの取り消しに失敗しました"));
+      queryClient.invalidateQueries({ queryKey: ["queue"] });
+    },
+  });
+
+  const handleEnqueue = async (input: EnqueueJobRequest) => {
+    setEnqueueError(null);
+    await enqueueMutation.mutateAsync(input);
+  };
+
+  const handleCancel = (item: QueuedJob) => {
+    setCancelError(null);
+    cancelMutation.mutate(item.id);
+  };
+
+  return (
+    <Stack spacing={3}>
+      <Paper elevation={0} sx={{ p: 3 }}>
+        <Toolbar disableGutters sx={{ justifyContent: "space-between", mb: 2 }}>
+          <Typography variant="h5">ジョブキュー</Typography>
+          <Stack direction="row" spacing={2}>
+            <IconButton onClick={() => queueQuery.refetch()} disabled={queueQuery.isFetching}>
+              {queueQuery.isFetching ? <CircularProgress size={20} /> : <RefreshIcon />}
+            </IconButton>
+            <Button variant="contained" startIcon={<AddIcon />} onClick={() => setIsEnqueueOpen(true)}>
+              ジョブを追加
+            </Button>
+          </Stack>
+        </Toolbar>
+
+        {cancelError ? (
+          <Alert severity="error" sx={{ mb: 2 }} onClose={() => setCancelError(null)}>
+            {cancelError}
+          </Alert>
+        ) : null}
+
+        {queueQuery.isLoading ? (
+          <Box sx={{ py: 8, textAlign: "center" }}>
+            <CircularProgress />
+          </Box>
+        ) : queueQuery.isError ? (
+          <Alert severity="error" sx={{ my: 4 }}>
+            キューの取得に失敗しました
+          </Alert>
+        ) : (
+          <Table>
+            <TableHead>
+              <TableRow>
+                <TableCell>ID</TableCell>
+                <TableCell>コマンド</TableCell>
+                <TableCell>ステータス</TableCell>
+                <TableCell>優先度</TableCell>
+                <TableCell>試行</TableCell>
+                <TableCell>ノード</TableCell>
+                <TableCell>ノードセレクタ</TableCell>
+                <TableCell>ジョブID</TableCell>
+                <TableCell>作成日時</TableCell>
+                <TableCell align="right">操作</TableCell>
+              </TableRow>
+            </TableHead>
+            <TableBody>
+              {queueQuery.data && queueQuery.data.length > 0 ? (
+                queueQuery.data.map((item) => (
+                  <TableRow key={item.id} hover>
+                    <TableCell>{item.id}</TableCell>
+                    <TableCell sx={{ fontFamily: "monospace" }}>{item.command.join(" ")}</TableCell>
+                    <TableCell>
+                      <Chip label={item.status} size="small" color={STATUS_COLORS[item.status] ?? "default"} />
+                    </TableCell>
+                    <TableCell>{item.priority}</TableCell>
+                    <TableCell>
+                      {item.attempts} / {item.maxAttempts}
+                    </TableCell>
+                    <TableCell>{item.leasedNodeId ?? item.nodeId ?? "-"}</TableCell>
+                    <TableCell>
+                      <LabelChips labels={item.nodeSelector} />
+                    </TableCell>
+                    <TableCell>{item.jobId ?? "-"}</TableCell>
+                    <TableCell>{item.createdAt.toLocaleString()}</TableCell>
+                    <TableCell align="right">
+                      {CANCELLABLE_STATUSES.has(item.status) ? (
+                        <Tooltip title="キューから取り消す">
+                          <span>
+                            <IconButton
+                              color="error"
+                              onClick={() => handleCancel(item)}
+                              disabled={cancelMutation.isPending && cancelMutation.variables === item.id}
+                            >
+                              <CancelOutlinedIcon />
+                            </IconButton>
+                          </span>
+                        </Tooltip>
+                      ) : null}
+                    </TableCell>
+                  </TableRow>
+                ))
+              ) : (
+                <TableRow>
+                  <TableCell colSpan={10}>
+                    <Box sx={{ py: 6, textAlign: "center", color: "text.secondary" }}>キューは空です</Box>
+                  </TableCell>
+                </TableRow>
+              )}
+            </TableBody>
+          </Table>
+        )}
+      </Paper>
+
+      <QueueEnqueueDialog
+        open={isEnqueueOpen}
+        onClose={() => {
+          setIsEnqueueOpen(false);
+          setEnqueueError(null);
+        }}
+        onSubmit={handleEnqueue}
+        loading={enqueueMutation.isPending}
+        apiError={enqueueError}
+      />
+    </Stack>
+  );
+}
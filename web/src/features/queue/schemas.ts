@@ -0,0 +1,58 @@
+import { z } from "zod";
+
+export const queuedJobSchema = z.object({
+  id: z.number(),
+  command: z.array(z.string()),
+  working_dir: z.string().nullable(),
+  tag: z.string().nullable(),
+  labels: z.record(z.string()),
+  node_id: z.number().nullable(),
+  node_selector: z.record(z.string()),
+  priority: z.number(),
+  status: z.string(),
+  attempts: z.number(),
+  max_attempts: z.number(),
+  leased_node_id: z.number().nullable(),
+  lease_expires_at: z.coerce.date().nullable(),
+  job_id: z.number().nullable(),
+  created_at: z.coerce.date(),
+  updated_at: z.coerce.date(),
+});
+
+export type QueuedJobDto = z.infer<typeof queuedJobSchema>;
+
+const labelLinePattern = /^[A-Za-z0-9_.\-/]+=.*$/;
+
+// "key=value" をカンマ区切りで並べた入力を受け付ける
+const labelsInputSchema = z
+  .string()
+  .trim()
+  .refine(
+    (value) =>
+      value === "" ||
+      value
+        .split(",")
+        .map((item) => item.trim())
+        .every((item) => labelLinePattern.test(item)),
+    "key=value をカンマ区切りで入力してください",
+  );
+
+export const enqueueJobRequestSchema = z.object({
+  command: z.string().trim().min(1, "コマンドを入力してください"),
+  workingDir: z.string().trim(),
+  tag: z.string().trim().max(128, "タグは128文字以内で入力してください"),
+  labels: labelsInputSchema,
+  nodeSelector: labelsInputSchema,
+  priority: z.coerce
+    .number()
+    .int("優先度は整数で入力してください")
+    .min(-1000, "優先度は-1000以上で入力してください")
+    .max(1000, "優先度は1000以下で入力してください"),
+  maxAttempts: z.coerce
+    .number()
+    .int("試行回数は整数で入力してください")
+    .min(1, "試行回数は1以上で入力してください")
+    .max(100, "試行回数は100以下で入力してください"),
+});
+
+export type EnqueueJobRequest = z.infer<typeof enqueueJobRequestSchema>;
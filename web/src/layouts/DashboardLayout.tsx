@@ -1,6 +1,7 @@
 import MenuIcon from "@mui/icons-material/Menu";
 import WorkspacesIcon from "@mui/icons-material/Workspaces";
 import ListAltIcon from "@mui/icons-material/ListAlt";
+import QueueIcon from "@mui/icons-material/Queue";
 import {
   AppBar,
   Box,
@@ -17,9 +18,11 @@ import {
   Button,
   Stack,
 } from "@mui/material";
-import { useState } from "react";
+import { useQueryClient } from "@tanstack/react-query";
+import { useEffect, useState } from "react";
 import { Outlet, useLocation, useNavigate } from "react-router-dom";
 import { useAuth } from "../features/auth/AuthContext";
+import { subscribeEvents } from "../lib/eventStream";
 
 const drawerWidth = 240;
 
@@ -32,6 +35,7 @@ type NavItem = {
 const navItems: NavItem[] = [
   { label: "ノード", path: "/nodes", icon: <WorkspacesIcon /> },
   { label: "ジョブ", path: "/jobs", icon: <ListAltIcon /> },
+  { label: "キュー", path: "/queue", icon: <QueueIcon /> },
 ];
 
 export default function DashboardLayout() {
@@ -39,6 +43,30 @@ export default function DashboardLayout() {
   const navigate = useNavigate();
   const location = useLocation();
   const [mobileOpen, setMobileOpen] = useState(false);
+  const queryClient = useQueryClient();
+  const token = auth?.token;
+
+  // Hub からの変更通知を受けて、表示中の一覧を取り直す
+  useEffect(() => {
+    if (!token) return;
+    const controller = new AbortController();
+    void subscribeEvents(
+      token,
+      (event) => {
+        if (event.type.startsWith("job.") || event.type === "reset") {
+          queryClient.invalidateQueries({ queryKey: ["jobs"] });
+        }
+        if (event.type.startsWith("node.") || event.type === "reset") {
+          queryClient.invalidateQueries({ queryKey: ["nodes"] });
+        }
+        if (event.type.startsWith("queue.") || event.type === "reset") {
+          queryClient.invalidateQueries({ queryKey: ["queue"] });
+        }
+      },
+      controller.signal,
+    );
+    return () => controller.abort();
+  }, [token, queryClient]);
 
   const handleDrawerToggle = () => {
     setMobileOpen((prev) => !prev);
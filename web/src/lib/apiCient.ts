@@ -1,4 +1,4 @@
-const API_BASE_URL = import.meta.env.VITE_API_BASE_URL ?? "http://localhost:8080";
+export const API_BASE_URL = import.meta.env.VITE_API_BASE_URL ?? "http://localhost:8080";
 
 export const AUTH_INVALID_EVENT = "jobboard:auth-invalid";
 export const FORCED_LOGOUT_MESSAGE_KEY = "jobboard:forced-logout-message";
@@ -2,14 +2,20 @@ import { ApiError } from "./apiCient";
 
 const ERROR_MESSAGES: Record<string, string> = {
   INVALID_REQUEST: "入力内容を確認してください。",
+  INVALID_CURSOR: "一覧の続きを読み込めませんでした。最初から読み込み直してください。",
   INVALID_CREDENTIALS: "クラスタIDまたはパスワードが正しくありません。",
   AUTH_MISSING_TOKEN: "認証情報が見つかりません。再度ログインしてください。",
   AUTH_INVALID_TOKEN: "セッションの有効期限が切れたか認証情報が不正です。",
   CLUSTER_ALREADY_EXISTS: "指定したクラスタIDは既に使用されています。",
   NODE_NOT_FOUND: "対象のノードが見つかりません。",
   JOB_NOT_FOUND: "対象のジョブが見つかりません。",
-  JOB_ALREADY_RUNNING: "このノードではすでにジョブが実行中です。",
+  NODE_SLOTS_FULL: "このノードの実行スロットはすべて使用中です。",
   JOB_NOT_RUNNING: "実行中のジョブはありません。",
+  ARTIFACT_NOT_FOUND: "対象のアーティファクトが見つかりません。",
+  ARTIFACT_TOO_LARGE: "アーティファクトのサイズが上限を超えています。",
+  QUEUED_JOB_NOT_FOUND: "対象のキュー項目が見つかりません。",
+  QUEUED_JOB_NOT_PENDING: "このジョブは既に開始済みか終了しているため取り消せません。",
+  QUEUE_LEASE_LOST: "キューの貸し出し期限が切れたか、取り消されています。",
   INTERNAL_ERROR: "サーバーで問題が発生しました。時間をおいて再度お試しください。",
 };
 
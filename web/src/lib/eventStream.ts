@@ -0,0 +1,84 @@
+import { API_BASE_URL, AUTH_INVALID_EVENT, FORCED_LOGOUT_MESSAGE_KEY } from "./apiCient";
+
+export type HubEvent = {
+  id: string | null;
+  type: string;
+  data: unknown;
+};
+
+const DEFAULT_RETRY_MS = 3000;
+
+// EventSource は Authorization ヘッダーを付けられないため、fetch で text/event-stream を読む。
+// 切断されたら最後に受け取った ID を Last-Event-ID に付けて再接続する。signal を abort すると止まる。
+export async function subscribeEvents(token: string, onEvent: (event: HubEvent) => void, signal: AbortSignal) {
+  let lastEventId: string | null = null;
+  let retryMs = DEFAULT_RETRY_MS;
+
+  while (!signal.aborted) {
+    try {
+      const headers: Record<string, string> = {
+        Accept: "text/event-stream",
+        Authorization: `Bearer ${token}`,
+      };
+      if (lastEventId) {
+        headers["Last-Event-ID"] = lastEventId;
+      }
+
+      const response = await fetch(`${API_BASE_URL}/api/events`, { headers, signal });
+      if (response.status === 401) {
+        const message = "セッションの有効期限が切れました。再ログインしてください。";
+        window.sessionStorage.setItem(FORCED_LOGOUT_MESSAGE_KEY, message);
+        window.dispatchEvent(new CustomEvent(AUTH_INVALID_EVENT, { detail: message }));
+        return;
+      }
+      if (!response.ok || !response.body) {
+        throw new Error(`event stream failed: ${response.status}`);
+      }
+
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buffer += value.replace(/\r\n?/g, "\n");
+
+        let end: number;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          const block = buffer.slice(0, end);
+          buffer = buffer.slice(end + 2);
+
+          let type = "message";
+          let id: string | null = null;
+          const data: string[] = [];
+          for (const line of block.split("\n")) {
+            if (line === "" || line.startsWith(":")) continue;
+            const colon = line.indexOf(":");
+            const field = colon >= 0 ? line.slice(0, colon) : line;
+            const content = colon >= 0 ? line.slice(colon + 1).replace(/^ /, "") : "";
+            if (field === "event") type = content;
+            else if (field === "data") data.push(content);
+            else if (field === "id") id = content;
+            else if (field === "retry" && /^\d+$/.test(content)) retryMs = Number(content);
+          }
+          if (id !== null) {
+            lastEventId = id;
+          }
+          if (data.length === 0) continue;
+
+          let parsed: unknown = null;
+          try {
+            parsed = JSON.parse(data.join("\n"));
+          } catch {
+            parsed = null;
+          }
+          onEvent({ id, type, data: parsed });
+        }
+      }
+    } catch (error) {
+      if (signal.aborted) return;
+      console.warn("event stream disconnected", error);
+    }
+
+    await new Promise((resolve) => setTimeout(resolve, retryMs));
+  }
+}